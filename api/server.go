@@ -0,0 +1,524 @@
+// Package api serves REST/JSON API to list ECHONET Lite nodes, read and write properties of devices,
+// and stream INF notifications by Server-Sent Events
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+const (
+	// requestTimeout is timeout of Get and SetC requested through API
+	requestTimeout = 10 * time.Second
+	// keepAliveInterval is interval of comments sent to event stream to keep the connection
+	keepAliveInterval = 30 * time.Second
+)
+
+var logger = log.New(os.Stdout, "[API]", log.LstdFlags)
+
+// Server is http.Handler of API mounted at /api/:
+//
+//	GET /api/nodes                      nodes and their objects
+//	GET /api/nodes/<node>/<eoj>         cached properties, ?live=true reads them from the device (?epc=80,b3 to select)
+//	PUT /api/nodes/<node>/<eoj>         set properties by SetC with body {"properties":{"80":"ON","b3":24}}
+//	GET /api/events                     INF and INFC notifications as Server-Sent Events (?eoj=013001 to select)
+//
+// <node> is IP address or DeviceID of the object.
+// PUT requires token of Options if it is set, and set to EV charger/discharger is refused unless it is permitted.
+type Server struct {
+	elc  echonetlite.NodeController
+	dict echonetlite.ClassDictionary
+	opts Options
+
+	mu    sync.Mutex
+	cache map[objectKey]*objectCache
+}
+
+type objectKey struct {
+	addr string
+	obj  echonetlite.Object
+}
+
+type objectCache struct {
+	values  map[echonetlite.PropertyCode]echonetlite.PropertyValue
+	updated time.Time
+}
+
+// Options is options of Server
+type Options struct {
+	// Token is required as "Authorization: Bearer <token>" to set properties.
+	// PUT is not served (404) if it is empty, because API may be served with /metrics to the network.
+	Token string
+	// EVPermission permits set to EV charger/discharger, which is refused with 403 by default
+	EVPermission echonetlite.EVSetPermission
+}
+
+// NodeInfo is node in list of nodes
+type NodeInfo struct {
+	Address  string       `json:"address"`
+	ID       string       `json:"id"`
+	MAC      string       `json:"mac,omitempty"`
+	LastSeen *time.Time   `json:"last_seen,omitempty"`
+	Stale    bool         `json:"stale"`
+	Objects  []ObjectInfo `json:"objects"`
+}
+
+// ObjectInfo is object of node
+type ObjectInfo struct {
+	EOJ    string `json:"eoj"`
+	Class  string `json:"class"`
+	Device string `json:"device"`
+}
+
+// ObjectState is properties of object
+type ObjectState struct {
+	Address    string                      `json:"address"`
+	Device     string                      `json:"device"`
+	EOJ        string                      `json:"eoj"`
+	Class      string                      `json:"class"`
+	Updated    *time.Time                  `json:"updated,omitempty"`
+	Properties []echonetlite.PropertyValue `json:"properties"`
+}
+
+// SetRequest is body of PUT, whose properties are keyed by EPC in hex.
+// Values are names of enumerated values, numbers, or raw data in hex with 0x prefix.
+type SetRequest struct {
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Event is INF or INFC notification sent to event stream
+type Event struct {
+	Address    string                      `json:"address"`
+	Device     string                      `json:"device"`
+	EOJ        string                      `json:"eoj"`
+	ESV        string                      `json:"esv"`
+	Properties []echonetlite.PropertyValue `json:"properties"`
+	Time       time.Time                   `json:"time"`
+}
+
+// Error is body of error response
+type Error struct {
+	Error    string   `json:"error"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// NewServer returns Server of elc with opts, whose properties are decoded by dict
+func NewServer(elc echonetlite.NodeController, dict echonetlite.ClassDictionary, opts Options) *Server {
+	return &Server{elc: elc, dict: dict, opts: opts, cache: map[objectKey]*objectCache{}}
+}
+
+// Run caches properties in responses and notifications received by the controller until ctx is done
+func (s *Server) Run(ctx context.Context) {
+	messages, stop := s.elc.Subscribe()
+	defer stop()
+	for {
+		select {
+		case m := <-messages:
+			switch m.Frame.ESV {
+			case echonetlite.GetRes, echonetlite.GetSNA, echonetlite.Inf, echonetlite.InfC, echonetlite.SetGetRes, echonetlite.SetGetSNA:
+				s.store(m.Host(), m.Frame.SrcObj(), m.Frame.Properties, time.Now())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// store caches props of obj at addr and returns them decoded
+func (s *Server) store(addr string, obj echonetlite.Object, props []echonetlite.Property, now time.Time) []echonetlite.PropertyValue {
+	values := []echonetlite.PropertyValue{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := objectKey{addr: addr, obj: obj}
+	c, ok := s.cache[key]
+	if !ok {
+		c = &objectCache{values: map[echonetlite.PropertyCode]echonetlite.PropertyValue{}}
+		s.cache[key] = c
+	}
+	for _, p := range props {
+		// 読み出せなかったプロパティはデータが空
+		if p.Len == 0 {
+			continue
+		}
+		v := s.dict.DecodeProperty(obj, p)
+		c.values[v.Code] = v
+		c.updated = now
+		values = append(values, v)
+	}
+	return values
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/")
+	levels := strings.Split(path, "/")
+	switch {
+	case path == "nodes":
+		s.allow(w, r, http.MethodGet, s.handleNodes)
+	case path == "events":
+		s.allow(w, r, http.MethodGet, s.handleEvents)
+	case len(levels) == 3 && levels[0] == "nodes":
+		switch r.Method {
+		case http.MethodGet:
+			s.handleGet(w, r, levels[1], levels[2])
+		case http.MethodPut:
+			// トークンがなければ設定は受け付けない
+			if s.opts.Token == "" {
+				writeError(w, http.StatusNotFound, "set is disabled, token is not configured")
+				return
+			}
+			s.handleSet(w, r, levels[1], levels[2])
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, h http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	h(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Printf("[Error] %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, format string, a ...interface{}) {
+	writeJSON(w, status, Error{Error: fmt.Sprintf(format, a...)})
+}
+
+func (s *Server) className(obj echonetlite.Object) string {
+	return s.dict.Get(obj.ClassGroup, obj.Class).Desc
+}
+
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	nodes := []NodeInfo{}
+	for _, n := range s.elc.Nodes() {
+		info := NodeInfo{Address: n.Address, ID: n.ID(), Stale: n.Stale, Objects: []ObjectInfo{}}
+		if len(n.MAC) > 0 {
+			info.MAC = n.MAC.String()
+		}
+		if !n.LastSeen.IsZero() {
+			seen := n.LastSeen
+			info.LastSeen = &seen
+		}
+		for _, obj := range n.Devices {
+			info.Objects = append(info.Objects, ObjectInfo{
+				EOJ:    hex.EncodeToString(obj.Data()),
+				Class:  s.className(obj),
+				Device: s.elc.DeviceID(n.Address, obj),
+			})
+		}
+		nodes = append(nodes, info)
+	}
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+// resolve returns address and object of <node>/<eoj> in path, or writes error response
+func (s *Server) resolve(w http.ResponseWriter, node, eoj string) (string, echonetlite.Object, bool) {
+	obj, err := echonetlite.ParseObject(eoj)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return "", obj, false
+	}
+	addr, ok := s.elc.LookupDevice(node, obj)
+	if !ok {
+		writeError(w, http.StatusNotFound, "object %s of node %s is not found", eoj, node)
+		return "", obj, false
+	}
+	return addr, obj, true
+}
+
+func (s *Server) state(addr string, obj echonetlite.Object, values []echonetlite.PropertyValue, updated time.Time) ObjectState {
+	sort.Slice(values, func(i, j int) bool { return values[i].Code < values[j].Code })
+	st := ObjectState{
+		Address:    addr,
+		Device:     s.elc.DeviceID(addr, obj),
+		EOJ:        hex.EncodeToString(obj.Data()),
+		Class:      s.className(obj),
+		Properties: values,
+	}
+	if !updated.IsZero() {
+		st.Updated = &updated
+	}
+	return st
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request, node, eoj string) {
+	addr, obj, ok := s.resolve(w, node, eoj)
+	if !ok {
+		return
+	}
+	params := r.URL.Query()
+	codes, err := echonetlite.ParsePropertyCodes(params.Get("epc"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	live := false
+	if v := params.Get("live"); v != "" {
+		live, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid live parameter %q", v)
+			return
+		}
+	}
+
+	if !live {
+		selected := map[echonetlite.PropertyCode]bool{}
+		for _, c := range codes {
+			selected[c] = true
+		}
+		values := []echonetlite.PropertyValue{}
+		var updated time.Time
+		s.mu.Lock()
+		if c, ok := s.cache[objectKey{addr: addr, obj: obj}]; ok {
+			for code, v := range c.values {
+				if len(selected) == 0 || selected[code] {
+					values = append(values, v)
+				}
+			}
+			updated = c.updated
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, s.state(addr, obj, values, updated))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	values, err := s.read(ctx, addr, obj, codes)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%s", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.state(addr, obj, values, time.Now()))
+}
+
+// read gets properties of codes from obj at addr, or properties in Get property map if codes is empty
+func (s *Server) read(ctx context.Context, addr string, obj echonetlite.Object, codes []echonetlite.PropertyCode) ([]echonetlite.PropertyValue, error) {
+	if len(codes) == 0 {
+		props, err := s.elc.Get(ctx, addr, obj, echonetlite.GetPropertyMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Get property map: %w", err)
+		}
+		p, ok := echonetlite.Frame{Properties: props}.Property(echonetlite.GetPropertyMap)
+		if !ok {
+			return nil, fmt.Errorf("Get property map not found")
+		}
+		codes, err = echonetlite.ParsePropertyMap(p.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	values := []echonetlite.PropertyValue{}
+	for start := 0; start < len(codes); start += echonetlite.MaxPropertiesPerGet {
+		end := start + echonetlite.MaxPropertiesPerGet
+		if end > len(codes) {
+			end = len(codes)
+		}
+		props, err := s.elc.Get(ctx, addr, obj, codes[start:end]...)
+		if _, ok := err.(*echonetlite.ServiceError); err != nil && !ok {
+			return nil, err
+		}
+		values = append(values, s.store(addr, obj, props, time.Now())...)
+	}
+	return values, nil
+}
+
+// authorized returns true if r has the token
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1
+}
+
+func (s *Server) handleSet(w http.ResponseWriter, r *http.Request, node, eoj string) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="elexporter"`)
+		writeError(w, http.StatusUnauthorized, "token is required to set properties")
+		return
+	}
+	addr, obj, ok := s.resolve(w, node, eoj)
+	if !ok {
+		return
+	}
+	var req SetRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: %s", err)
+		return
+	}
+	if len(req.Properties) == 0 {
+		writeError(w, http.StatusBadRequest, "no properties to set")
+		return
+	}
+
+	epcs := make([]string, 0, len(req.Properties))
+	for epc := range req.Properties {
+		epcs = append(epcs, epc)
+	}
+	sort.Strings(epcs)
+	props := []echonetlite.Property{}
+	codes := []echonetlite.PropertyCode{}
+	problems := []string{}
+	for _, epc := range epcs {
+		p, err := s.encode(obj, epc, req.Properties[epc])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", epc, err))
+			continue
+		}
+		props = append(props, p)
+		codes = append(codes, echonetlite.PropertyCode(p.Code))
+	}
+	if len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, Error{Error: "invalid properties", Problems: problems})
+		return
+	}
+	if obj.ClassGroup == echonetlite.HomeEquipmentGroup && obj.Class == echonetlite.EVChargerDischarger {
+		if err := echonetlite.CheckEVChargerSet(s.opts.EVPermission, props...); err != nil {
+			writeError(w, http.StatusForbidden, "%s", err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	if err := s.elc.Set(ctx, addr, obj, props...); err != nil {
+		if _, ok := err.(*echonetlite.EVSetNotPermittedError); ok {
+			writeError(w, http.StatusForbidden, "%s", err)
+			return
+		}
+		if serr, ok := err.(*echonetlite.ServiceError); ok {
+			rejected := []string{}
+			for _, c := range serr.Rejected() {
+				rejected = append(rejected, fmt.Sprintf("%02x", byte(c)))
+			}
+			writeJSON(w, http.StatusConflict, Error{Error: "rejected by the device", Problems: rejected})
+			return
+		}
+		writeError(w, http.StatusBadGateway, "%s", err)
+		return
+	}
+	logger.Printf("set %s [%s]: %s", addr, eoj, strings.Join(epcs, ","))
+
+	// 設定後の値を読み出して返す
+	values, err := s.read(ctx, addr, obj, codes)
+	if err != nil {
+		logger.Printf("[Error] failed to read %s [%s] after set: %s", addr, eoj, err)
+	}
+	writeJSON(w, http.StatusOK, s.state(addr, obj, values, time.Now()))
+}
+
+// encode validates value of epc by the class dictionary
+func (s *Server) encode(obj echonetlite.Object, epc string, value interface{}) (echonetlite.Property, error) {
+	code, err := echonetlite.ParsePropertyCode(epc)
+	if err != nil {
+		return echonetlite.Property{}, err
+	}
+	var v string
+	switch value := value.(type) {
+	case string:
+		v = value
+	case float64:
+		v = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return echonetlite.Property{}, fmt.Errorf("string or number is expected: %v", value)
+	}
+	return s.dict.EncodeProperty(obj, code, v)
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	var filter *echonetlite.Object
+	if eoj := r.URL.Query().Get("eoj"); eoj != "" {
+		obj, err := echonetlite.ParseObject(eoj)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		filter = &obj
+	}
+
+	messages, stop := s.elc.Subscribe()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	t := time.NewTicker(keepAliveInterval)
+	defer t.Stop()
+	for {
+		select {
+		case m := <-messages:
+			f := m.Frame
+			if f.ESV != echonetlite.Inf && f.ESV != echonetlite.InfC {
+				continue
+			}
+			obj := f.SrcObj()
+			if filter != nil && *filter != obj {
+				continue
+			}
+			addr := m.Host()
+			e := Event{
+				Address:    addr,
+				Device:     s.elc.DeviceID(addr, obj),
+				EOJ:        hex.EncodeToString(obj.Data()),
+				ESV:        f.ESV.String(),
+				Properties: []echonetlite.PropertyValue{},
+				Time:       time.Now(),
+			}
+			for _, p := range f.Properties {
+				if p.Len > 0 {
+					e.Properties = append(e.Properties, s.dict.DecodeProperty(obj, p))
+				}
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				logger.Printf("[Error] %s", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: inf\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-t.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/echonetlite/eltest"
+)
+
+// request sends request with optional bearer token, and decodes the response into v
+func request(t *testing.T, method, url, body string, v interface{}, token ...string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tk := range token {
+		req.Header.Set("Authorization", "Bearer "+tk)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode
+}
+
+// objectJSON is ObjectState decoded in tests
+type objectJSON struct {
+	Address    string `json:"address"`
+	Device     string `json:"device"`
+	EOJ        string `json:"eoj"`
+	Properties []struct {
+		Name  string   `json:"name"`
+		Value *float64 `json:"value"`
+		State string   `json:"state"`
+	} `json:"properties"`
+}
+
+// values returns values of properties keyed by name, state for enumeration
+func (o objectJSON) values() map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, p := range o.Properties {
+		if p.Value != nil {
+			m[p.Name] = *p.Value
+		} else {
+			m[p.Name] = p.State
+		}
+	}
+	return m
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	elc := eltest.NewController()
+	s := NewServer(elc, eltest.Dictionary(), Options{Token: "secret"})
+	go s.Run(ctx)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var nodes []NodeInfo
+	if code := request(t, http.MethodGet, ts.URL+"/api/nodes", "", &nodes); code != http.StatusOK {
+		t.Fatalf("nodes: %d", code)
+	}
+	if len(nodes) != 1 || nodes[0].MAC != "00:11:22:33:44:55" || nodes[0].ID != "mac:00:11:22:33:44:55" ||
+		len(nodes[0].Objects) != 1 || nodes[0].Objects[0] != (ObjectInfo{EOJ: "013001", Class: "家庭用エアコン", Device: "dev1"}) {
+		t.Errorf("nodes: %+v", nodes)
+	}
+
+	// 受信した値がキャッシュされる
+	elc.Receive(echonetlite.GetRes, echonetlite.Property{Code: 0x80, Len: 1, Data: echonetlite.Data{0x31}})
+	var st objectJSON
+	for i := 0; i < 100 && len(st.Properties) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		st = objectJSON{}
+		if code := request(t, http.MethodGet, ts.URL+"/api/nodes/dev1/013001", "", &st); code != http.StatusOK {
+			t.Fatalf("cached: %d", code)
+		}
+	}
+	if got := st.values(); len(got) != 1 || got["動作状態"] != "OFF" {
+		t.Errorf("cached: %v", got)
+	}
+
+	st = objectJSON{}
+	if code := request(t, http.MethodGet, ts.URL+"/api/nodes/192.168.0.10/013001?live=true", "", &st); code != http.StatusOK {
+		t.Fatalf("live: %d", code)
+	}
+	if got := st.values(); len(got) != 4 || got["動作状態"] != "ON" || got["運転モード設定"] != "冷房" || got["温度設定値"] != 26.0 || got["室内温度計測値"] != -2.0 {
+		t.Errorf("live: %v", got)
+	}
+	if st.Address != "192.168.0.10" || st.Device != "dev1" || st.EOJ != "013001" {
+		t.Errorf("live: %+v", st)
+	}
+
+	st = objectJSON{}
+	if code := request(t, http.MethodGet, ts.URL+"/api/nodes/dev1/013001?live=1&epc=b3", "", &st); code != http.StatusOK {
+		t.Fatalf("live with epc: %d", code)
+	}
+	if got := st.values(); len(got) != 1 || got["温度設定値"] != 26.0 {
+		t.Errorf("live with epc: %v", got)
+	}
+
+	st = objectJSON{}
+	if code := request(t, http.MethodPut, ts.URL+"/api/nodes/dev1/013001", `{"properties":{"80":"off","b3":24}}`, &st, "secret"); code != http.StatusOK {
+		t.Fatalf("set: %d", code)
+	}
+	if got := st.values(); len(got) != 2 || got["動作状態"] != "OFF" || got["温度設定値"] != 24.0 {
+		t.Errorf("set: %v", got)
+	}
+
+	testcases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		code     int
+		problems []string
+	}{
+		{name: "invalid values", method: http.MethodPut, path: "/api/nodes/dev1/013001", body: `{"properties":{"80":"STANDBY","b3":300,"bb":"0x01","zz":1}}`, code: http.StatusBadRequest,
+			problems: []string{"80: invalid value for 動作状態", "b3: invalid value for 温度設定値", "zz: invalid EPC"}},
+		{name: "rejected", method: http.MethodPut, path: "/api/nodes/dev1/013001", body: `{"properties":{"b3":"0x3c"}}`, code: http.StatusConflict, problems: []string{"b3"}},
+		{name: "unknown field", method: http.MethodPut, path: "/api/nodes/dev1/013001", body: `{"props":{}}`, code: http.StatusBadRequest},
+		{name: "unknown device", method: http.MethodGet, path: "/api/nodes/dev2/013001", code: http.StatusNotFound},
+		{name: "invalid EOJ", method: http.MethodGet, path: "/api/nodes/dev1/0130", code: http.StatusBadRequest},
+		{name: "invalid EPC", method: http.MethodGet, path: "/api/nodes/dev1/013001?epc=8", code: http.StatusBadRequest},
+		{name: "invalid EOJ of events", method: http.MethodGet, path: "/api/events?eoj=0130", code: http.StatusBadRequest},
+		{name: "method", method: http.MethodPost, path: "/api/nodes", code: http.StatusMethodNotAllowed},
+		{name: "not found", method: http.MethodGet, path: "/api/devices", code: http.StatusNotFound},
+	}
+	for _, tc := range testcases {
+		var e Error
+		if code := request(t, tc.method, ts.URL+tc.path, tc.body, &e, "secret"); code != tc.code {
+			t.Errorf("%s: status %d, want %d: %+v", tc.name, code, tc.code, e)
+		}
+		if len(e.Problems) != len(tc.problems) {
+			t.Errorf("%s: problems %q", tc.name, e.Problems)
+			continue
+		}
+		for i, p := range tc.problems {
+			if !strings.HasPrefix(e.Problems[i], p) {
+				t.Errorf("%s: problem %q, want %q", tc.name, e.Problems[i], p)
+			}
+		}
+	}
+}
+
+func TestServer_Permission(t *testing.T) {
+	t.Parallel()
+
+	elc := eltest.NewController()
+	ts := httptest.NewServer(NewServer(elc, eltest.Dictionary(), Options{Token: "secret", EVPermission: echonetlite.EVAllowCharge}))
+	defer ts.Close()
+
+	testcases := []struct {
+		name  string
+		path  string
+		body  string
+		token []string
+		code  int
+	}{
+		{name: "no token", path: "/api/nodes/dev1/013001", body: `{"properties":{"80":"ON"}}`, code: http.StatusUnauthorized},
+		{name: "wrong token", path: "/api/nodes/dev1/013001", body: `{"properties":{"80":"ON"}}`, token: []string{"wrong"}, code: http.StatusUnauthorized},
+		{name: "aircon", path: "/api/nodes/dev1/013001", body: `{"properties":{"80":"ON"}}`, token: []string{"secret"}, code: http.StatusOK},
+		{name: "charging", path: "/api/nodes/dev1/027e01", body: `{"properties":{"da":"充電"}}`, token: []string{"secret"}, code: http.StatusOK},
+		{name: "discharging", path: "/api/nodes/dev1/027e01", body: `{"properties":{"da":"放電"}}`, token: []string{"secret"}, code: http.StatusForbidden},
+	}
+	for _, tc := range testcases {
+		var v map[string]interface{}
+		if code := request(t, http.MethodPut, ts.URL+tc.path, tc.body, &v, tc.token...); code != tc.code {
+			t.Errorf("%s: status %d, want %d: %v", tc.name, code, tc.code, v)
+		}
+	}
+	// 読み出しにはトークンは不要
+	var nodes []NodeInfo
+	if code := request(t, http.MethodGet, ts.URL+"/api/nodes", "", &nodes); code != http.StatusOK {
+		t.Errorf("nodes: %d", code)
+	}
+	if p := elc.Value(echonetlite.EVOperationMode); len(p.Data) != 1 || p.Data[0] != 0x42 {
+		t.Errorf("discharging must not be sent: %v", p)
+	}
+}
+
+func TestServer_NoToken(t *testing.T) {
+	t.Parallel()
+
+	elc := eltest.NewController()
+	ts := httptest.NewServer(NewServer(elc, eltest.Dictionary(), Options{EVPermission: echonetlite.EVAllowCharge}))
+	defer ts.Close()
+
+	// トークンが設定されていなければ何を送っても設定できない
+	for _, token := range [][]string{nil, {""}, {"secret"}} {
+		var e Error
+		if code := request(t, http.MethodPut, ts.URL+"/api/nodes/dev1/013001", `{"properties":{"b3":24}}`, &e, token...); code != http.StatusNotFound {
+			t.Errorf("token %q: status %d, want %d: %+v", token, code, http.StatusNotFound, e)
+		}
+	}
+	if p := elc.Value(echonetlite.TemperatureSetting); len(p.Data) != 1 || p.Data[0] != 0x1a {
+		t.Errorf("temperature must not be set: %v", p)
+	}
+	var nodes []NodeInfo
+	if code := request(t, http.MethodGet, ts.URL+"/api/nodes", "", &nodes); code != http.StatusOK {
+		t.Errorf("nodes: %d", code)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	t.Parallel()
+
+	elc := eltest.NewController()
+	ts := httptest.NewServer(NewServer(elc, eltest.Dictionary(), Options{}))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/events?eoj=0x013001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type: %s", ct)
+	}
+
+	elc.Receive(echonetlite.GetRes, echonetlite.Property{Code: 0x80, Len: 1, Data: echonetlite.Data{0x30}})
+	elc.Receive(echonetlite.Inf, echonetlite.Property{Code: 0x80, Len: 1, Data: echonetlite.Data{0x31}})
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(res.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	read := func() string {
+		select {
+		case l := <-lines:
+			return l
+		case <-time.After(3 * time.Second):
+			t.Fatal("event is not received")
+		}
+		return ""
+	}
+	if l := read(); l != "event: inf" {
+		t.Fatalf("event: %s", l)
+	}
+	l := read()
+	var e struct {
+		objectJSON
+		ESV string `json:"esv"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(l, "data: ")), &e); err != nil {
+		t.Fatalf("%s: %s", err, l)
+	}
+	if e.Address != "192.168.0.10" || e.Device != "dev1" || e.EOJ != "013001" || e.ESV != echonetlite.Inf.String() {
+		t.Errorf("event: %s", l)
+	}
+	if got := e.values(); len(got) != 1 || got["動作状態"] != "OFF" {
+		t.Errorf("properties of event: %v", got)
+	}
+}
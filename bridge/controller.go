@@ -0,0 +1,344 @@
+package bridge
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+const (
+	// setTimeout is timeout of SetC requested through MQTT
+	setTimeout = 10 * time.Second
+	// propertyMapTimeout is timeout to read property maps for discovery
+	propertyMapTimeout = 5 * time.Second
+)
+
+// ControllerBridge publishes properties received by the controller, and sets properties requested through MQTT.
+//
+//	<prefix>/<device>/<eoj>/<epc>             decoded property value (JSON)
+//	<prefix>/<device>/<eoj>/state             the last values of all properties keyed by EPC (JSON)
+//	<prefix>/<device>/<eoj>/inf               INF and INFC notifications (JSON)
+//	<prefix>/<device>/<eoj>/<epc>/set         value to set by SetC
+//	<prefix>/<device>/<eoj>/<epc>/set/result  acknowledgement of set (JSON)
+//	<prefix>/<device>/<eoj>/mode/set          HVAC mode of Home Assistant to set to home air conditioner
+//	<prefix>/<device>/<eoj>/mode/set/result   acknowledgement of set (JSON)
+//
+// <device> is DeviceID of the object, and <eoj> and <epc> are in hex such as 013001 and 80.
+// HVAC mode "off" sets operation status (0x80) to OFF, and other modes set it to ON with operation mode (0xB0).
+// Set to EV charger/discharger is refused unless it is permitted by EVPermission of Options.
+type ControllerBridge struct {
+	elc     echonetlite.NodeController
+	dict    echonetlite.ClassDictionary
+	session *Session
+
+	states     map[string]map[string]echonetlite.PropertyValue // base topic -> EPC -> value
+	discovered map[string]bool                                 // base topic of objects announced to Home Assistant
+}
+
+// Notification is payload of INF and INFC notification
+type Notification struct {
+	ESV        string                      `json:"esv"`
+	Properties []echonetlite.PropertyValue `json:"properties"`
+	Time       time.Time                   `json:"time"`
+}
+
+// SetResult is payload of acknowledgement of set
+type SetResult struct {
+	OK    bool   `json:"ok"`
+	Value string `json:"value"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewControllerBridge returns ControllerBridge of elc, whose properties are decoded by dict
+func NewControllerBridge(elc echonetlite.NodeController, dict echonetlite.ClassDictionary, s *Session) *ControllerBridge {
+	b := &ControllerBridge{
+		elc:        elc,
+		dict:       dict,
+		session:    s,
+		states:     map[string]map[string]echonetlite.PropertyValue{},
+		discovered: map[string]bool{},
+	}
+	s.OnConnect(func(ctx context.Context, c *mqtt.Client) error {
+		return s.Subscribe(ctx, c, s.Prefix()+"/+/+/+/set", b.handleSet)
+	})
+	return b
+}
+
+// Run publishes frames received by the controller until ctx is done
+func (b *ControllerBridge) Run(ctx context.Context) {
+	messages, stop := b.elc.Subscribe()
+	defer stop()
+	for {
+		select {
+		case m := <-messages:
+			b.publish(ctx, m)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *ControllerBridge) topic(device string, obj echonetlite.Object, rest ...string) string {
+	levels := append([]string{b.session.Prefix(), device, hex.EncodeToString(obj.Data())}, rest...)
+	return strings.Join(levels, "/")
+}
+
+// publish publishes properties in responses and notifications
+func (b *ControllerBridge) publish(ctx context.Context, m echonetlite.Message) {
+	f := m.Frame
+	switch f.ESV {
+	case echonetlite.GetRes, echonetlite.GetSNA, echonetlite.Inf, echonetlite.InfC, echonetlite.SetGetRes, echonetlite.SetGetSNA:
+	default:
+		return
+	}
+	obj := f.SrcObj()
+	device := b.elc.DeviceID(m.Address, obj)
+
+	values := []echonetlite.PropertyValue{}
+	for _, p := range f.Properties {
+		// Get_SNAで読み出せなかったプロパティはデータが空
+		if p.Len == 0 {
+			continue
+		}
+		values = append(values, b.dict.DecodeProperty(obj, p))
+	}
+
+	for _, v := range values {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			logger.Printf("[Error] %s", err)
+			continue
+		}
+		topic := b.topic(device, obj, fmt.Sprintf("%02x", byte(v.Code)))
+		if err := b.session.PublishState(ctx, topic, payload); err != nil {
+			logger.Printf("[Error] failed to publish %s: %s", topic, err)
+		}
+	}
+	b.publishState(ctx, device, obj, values)
+
+	if f.ESV != echonetlite.Inf && f.ESV != echonetlite.InfC {
+		return
+	}
+	payload, err := json.Marshal(Notification{ESV: f.ESV.String(), Properties: values, Time: time.Now()})
+	if err != nil {
+		logger.Printf("[Error] %s", err)
+		return
+	}
+	topic := b.topic(device, obj, "inf")
+	if err := b.session.Publish(ctx, topic, false, payload); err != nil {
+		logger.Printf("[Error] failed to publish %s: %s", topic, err)
+	}
+}
+
+// publishState merges values into the last values of the object and publishes them
+func (b *ControllerBridge) publishState(ctx context.Context, device string, obj echonetlite.Object, values []echonetlite.PropertyValue) {
+	if len(values) == 0 {
+		return
+	}
+	base := b.topic(device, obj)
+	state, ok := b.states[base]
+	if !ok {
+		state = map[string]echonetlite.PropertyValue{}
+		b.states[base] = state
+	}
+	for _, v := range values {
+		state[fmt.Sprintf("%02x", byte(v.Code))] = v
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		logger.Printf("[Error] %s", err)
+		return
+	}
+	if err := b.session.PublishState(ctx, base+"/state", payload); err != nil {
+		logger.Printf("[Error] failed to publish %s/state: %s", base, err)
+	}
+}
+
+// RunDiscovery announces devices found by the controller to Home Assistant through d every interval until ctx is done.
+// Entities are built from class and property maps of each device, which are read once.
+func (b *ControllerBridge) RunDiscovery(ctx context.Context, d *Discovery, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		b.discover(ctx, d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *ControllerBridge) discover(ctx context.Context, d *Discovery) {
+	for _, n := range b.elc.Nodes() {
+		for _, obj := range n.Devices {
+			device := b.elc.DeviceID(n.Address, obj)
+			base := b.topic(device, obj)
+			if b.discovered[base] {
+				continue
+			}
+			get, set, err := b.propertyMaps(ctx, n.Address, obj)
+			if err != nil {
+				logger.Printf("[Error] failed to read property maps of %s %s: %s", n.Address, obj, err)
+				continue
+			}
+			eoj := hex.EncodeToString(obj.Data())
+			nodeID := sanitizeID(b.session.Prefix() + "_" + device + "_" + eoj)
+			info := map[string]interface{}{
+				"identifiers": []string{nodeID},
+				"name":        b.dict.Get(obj.ClassGroup, obj.Class).Desc + " " + eoj,
+			}
+			if id, ok := n.Identities[obj]; ok {
+				info["manufacturer"] = id.Manufacturer.String()
+			}
+			eb := entityBuilder{
+				dict:   b.dict,
+				obj:    obj,
+				base:   base,
+				nodeID: nodeID,
+				common: map[string]interface{}{"availability_topic": b.session.StatusTopic(), "device": info},
+				get:    get,
+				set:    set,
+			}
+			d.Add(ctx, eb.entities()...)
+			b.discovered[base] = true
+		}
+	}
+}
+
+// propertyMaps reads Get and Set property maps of obj at addr
+func (b *ControllerBridge) propertyMaps(ctx context.Context, addr string, obj echonetlite.Object) (map[echonetlite.PropertyCode]bool, map[echonetlite.PropertyCode]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, propertyMapTimeout)
+	defer cancel()
+	props, err := b.elc.Get(ctx, addr, obj, echonetlite.GetPropertyMap, echonetlite.SetPropertyMap)
+	if _, ok := err.(*echonetlite.ServiceError); err != nil && !ok {
+		return nil, nil, err
+	}
+	f := echonetlite.Frame{Properties: props}
+	maps := []map[echonetlite.PropertyCode]bool{}
+	for _, code := range []echonetlite.PropertyCode{echonetlite.GetPropertyMap, echonetlite.SetPropertyMap} {
+		m := map[echonetlite.PropertyCode]bool{}
+		// Setプロパティマップがない機器は設定できるプロパティがない
+		if p, ok := f.Property(code); ok && p.Len > 0 {
+			codes, err := echonetlite.ParsePropertyMap(p.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, c := range codes {
+				m[c] = true
+			}
+		} else if code == echonetlite.GetPropertyMap {
+			return nil, nil, fmt.Errorf("Get property map not found")
+		}
+		maps = append(maps, m)
+	}
+	return maps[0], maps[1], nil
+}
+
+// handleSet sets property requested by message to <prefix>/<device>/<eoj>/<epc>/set, and publishes the result
+func (b *ControllerBridge) handleSet(m mqtt.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), setTimeout)
+	defer cancel()
+
+	value := string(m.Payload)
+	result := SetResult{OK: true, Value: value}
+	if err := b.set(ctx, m.Topic, value); err != nil {
+		logger.Printf("[Error] %s: %s", m.Topic, err)
+		result = SetResult{Value: value, Error: err.Error()}
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		logger.Printf("[Error] %s", err)
+		return
+	}
+	topic := m.Topic + "/result"
+	if err := b.session.Publish(ctx, topic, false, payload); err != nil {
+		logger.Printf("[Error] failed to publish %s: %s", topic, err)
+	}
+}
+
+func (b *ControllerBridge) set(ctx context.Context, topic, value string) error {
+	levels := strings.Split(strings.TrimPrefix(topic, b.session.Prefix()+"/"), "/")
+	if len(levels) != 4 || levels[3] != "set" {
+		return fmt.Errorf("unexpected topic")
+	}
+	device := levels[0]
+	obj, err := echonetlite.ParseObject(levels[1])
+	if err != nil {
+		return err
+	}
+	if levels[2] == "mode" {
+		return b.setMode(ctx, device, obj, value)
+	}
+	code, err := echonetlite.ParsePropertyCode(levels[2])
+	if err != nil {
+		return err
+	}
+
+	addr, ok := b.elc.LookupDevice(device, obj)
+	if !ok {
+		return fmt.Errorf("unknown device %s %s", device, levels[1])
+	}
+	p, err := b.dict.EncodeProperty(obj, code, value)
+	if err != nil {
+		return err
+	}
+	if obj.ClassGroup == echonetlite.HomeEquipmentGroup && obj.Class == echonetlite.EVChargerDischarger {
+		if err := echonetlite.CheckEVChargerSet(b.session.opts.EVPermission, p); err != nil {
+			return err
+		}
+	}
+	if err := b.elc.Set(ctx, addr, obj, p); err != nil {
+		return err
+	}
+	// 設定後の値を読み出して状態のトピックを更新する
+	if _, err := b.elc.Get(ctx, addr, obj, code); err != nil {
+		logger.Printf("[Error] failed to read %s after set: %s", topic, err)
+	}
+	return nil
+}
+
+// setMode sets HVAC mode of Home Assistant to home air conditioner by operation status and operation mode in a SetC
+func (b *ControllerBridge) setMode(ctx context.Context, device string, obj echonetlite.Object, mode string) error {
+	if obj.ClassGroup != echonetlite.AirConditionerGroup || obj.Class != echonetlite.HomeAirConditioner {
+		return fmt.Errorf("mode is not supported for %s", obj)
+	}
+	addr, ok := b.elc.LookupDevice(device, obj)
+	if !ok {
+		return fmt.Errorf("unknown device %s %s", device, hex.EncodeToString(obj.Data()))
+	}
+	props := []echonetlite.Property{{Code: byte(echonetlite.OperationStatus), Len: 1, Data: echonetlite.Data{0x31}}}
+	codes := []echonetlite.PropertyCode{echonetlite.OperationStatus}
+	if mode != "off" {
+		found := false
+		for _, m := range airconModes {
+			if m.mode == mode {
+				props = []echonetlite.Property{
+					{Code: byte(echonetlite.OperationStatus), Len: 1, Data: echonetlite.Data{0x30}},
+					{Code: byte(echonetlite.OperationModeSetting), Len: 1, Data: echonetlite.Data{m.raw}},
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown mode: %q", mode)
+		}
+		codes = append(codes, echonetlite.OperationModeSetting)
+	}
+	if err := b.elc.Set(ctx, addr, obj, props...); err != nil {
+		return err
+	}
+	// 設定後の値を読み出して状態のトピックを更新する
+	if _, err := b.elc.Get(ctx, addr, obj, codes...); err != nil {
+		logger.Printf("[Error] failed to read mode of %s %s after set: %s", device, obj, err)
+	}
+	return nil
+}
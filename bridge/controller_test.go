@@ -0,0 +1,267 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/echonetlite/eltest"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+func TestControllerBridge(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	obs, ch := observe(t, ctx, srv, "echonetlite/#")
+	defer obs.Close()
+
+	elc := eltest.NewController()
+	s := newTestSession(t, srv, "echonetlite")
+	b := NewControllerBridge(elc, eltest.Dictionary(), s)
+	go s.Run(ctx)
+	go b.Run(ctx)
+	waitConnected(t, s)
+
+	elc.Receive(echonetlite.GetRes,
+		echonetlite.Property{Code: 0x80, Len: 1, Data: echonetlite.Data{0x30}},
+		echonetlite.Property{Code: 0xb3, Len: 1, Data: echonetlite.Data{0x1a}},
+	)
+	m := receive(t, ch, "echonetlite/dev1/013001/80")
+	if string(m.Payload) != `{"epc":"80","name":"動作状態","state":"ON","raw":"30"}` {
+		t.Errorf("payload of 80: %s", m.Payload)
+	}
+	m = receive(t, ch, "echonetlite/dev1/013001/b3")
+	if string(m.Payload) != `{"epc":"b3","name":"温度設定値","unit":"℃","value":26,"raw":"1a"}` {
+		t.Errorf("payload of b3: %s", m.Payload)
+	}
+	if _, ok := srv.Retained("echonetlite/dev1/013001/b3"); !ok {
+		t.Error("state is not retained")
+	}
+	m = receive(t, ch, "echonetlite/dev1/013001/state")
+	if string(m.Payload) != `{"80":{"epc":"80","name":"動作状態","state":"ON","raw":"30"},"b3":{"epc":"b3","name":"温度設定値","unit":"℃","value":26,"raw":"1a"}}` {
+		t.Errorf("state: %s", m.Payload)
+	}
+
+	elc.Receive(echonetlite.Inf, echonetlite.Property{Code: 0x80, Len: 1, Data: echonetlite.Data{0x31}})
+	m = receive(t, ch, "echonetlite/dev1/013001/inf")
+	var n struct {
+		ESV        string                   `json:"esv"`
+		Properties []map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(m.Payload, &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ESV != echonetlite.Inf.String() || len(n.Properties) != 1 || n.Properties[0]["state"] != "OFF" {
+		t.Errorf("notification: %s", m.Payload)
+	}
+	if m, ok := srv.Retained("echonetlite/dev1/013001/80"); !ok || string(m.Payload) != `{"epc":"80","name":"動作状態","state":"OFF","raw":"31"}` {
+		t.Errorf("state after INF: %s", m.Payload)
+	}
+
+	tests := []struct {
+		topic   string
+		payload string
+		ok      bool
+		state   string
+	}{
+		{topic: "echonetlite/dev1/013001/b3/set", payload: "24", ok: true, state: `{"epc":"b3","name":"温度設定値","unit":"℃","value":24,"raw":"18"}`},
+		{topic: "echonetlite/dev1/013001/80/set", payload: "on", ok: true, state: `{"epc":"80","name":"動作状態","state":"ON","raw":"30"}`},
+		{topic: "echonetlite/dev1/013001/80/set", payload: "STANDBY"},
+		{topic: "echonetlite/dev1/013001/b3/set", payload: "60"},
+		{topic: "echonetlite/dev2/013001/80/set", payload: "ON"},
+		{topic: "echonetlite/dev1/0130/80/set", payload: "ON"},
+	}
+	for _, tt := range tests {
+		if err := obs.Publish(ctx, tt.topic, 1, false, []byte(tt.payload)); err != nil {
+			t.Fatal(err)
+		}
+		m := receive(t, ch, tt.topic+"/result")
+		var r SetResult
+		if err := json.Unmarshal(m.Payload, &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.OK != tt.ok || r.Value != tt.payload || (r.Error == "") != tt.ok {
+			t.Errorf("%s %s: result %s", tt.topic, tt.payload, m.Payload)
+		}
+		if !tt.ok {
+			continue
+		}
+		state := tt.topic[:len(tt.topic)-len("/set")]
+		if m := receive(t, ch, state); string(m.Payload) != tt.state {
+			t.Errorf("%s %s: state %s", tt.topic, tt.payload, m.Payload)
+		}
+	}
+}
+
+func TestControllerBridge_EVPermission(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	obs, ch := observe(t, ctx, srv, "echonetlite/#")
+	defer obs.Close()
+
+	elc := eltest.NewController()
+	s := newTestSession(t, srv, "echonetlite")
+	s.opts.EVPermission = echonetlite.EVAllowCharge
+	b := NewControllerBridge(elc, eltest.Dictionary(), s)
+	go s.Run(ctx)
+	go b.Run(ctx)
+	waitConnected(t, s)
+
+	tests := []struct {
+		payload string
+		ok      bool
+	}{
+		{payload: "充電", ok: true},
+		{payload: "放電"},
+	}
+	for _, tt := range tests {
+		topic := "echonetlite/dev1/027e01/da/set"
+		if err := obs.Publish(ctx, topic, 1, false, []byte(tt.payload)); err != nil {
+			t.Fatal(err)
+		}
+		m := receive(t, ch, topic+"/result")
+		var r SetResult
+		if err := json.Unmarshal(m.Payload, &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.OK != tt.ok || (!tt.ok && !strings.Contains(r.Error, "permission")) {
+			t.Errorf("%s: result %s", tt.payload, m.Payload)
+		}
+	}
+	if p := elc.Value(echonetlite.EVOperationMode); len(p.Data) != 1 || p.Data[0] != 0x42 {
+		t.Errorf("discharging must not be sent: %v", p)
+	}
+}
+
+func TestControllerBridge_Mode(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	obs, ch := observe(t, ctx, srv, "echonetlite/#")
+	defer obs.Close()
+
+	elc := eltest.NewController()
+	s := newTestSession(t, srv, "echonetlite")
+	b := NewControllerBridge(elc, eltest.Dictionary(), s)
+	go s.Run(ctx)
+	go b.Run(ctx)
+	waitConnected(t, s)
+
+	tests := []struct {
+		mode   string
+		ok     bool
+		status byte
+		opmode byte
+	}{
+		{mode: "cool", ok: true, status: 0x30, opmode: 0x42},
+		// OFFは運転モードを変えない
+		{mode: "off", ok: true, status: 0x31, opmode: 0x42},
+		{mode: "auto", ok: true, status: 0x30, opmode: 0x41},
+		{mode: "warm", status: 0x30, opmode: 0x41},
+	}
+	topic := "echonetlite/dev1/013001/mode/set"
+	for _, tt := range tests {
+		if err := obs.Publish(ctx, topic, 1, false, []byte(tt.mode)); err != nil {
+			t.Fatal(err)
+		}
+		m := receive(t, ch, topic+"/result")
+		var r SetResult
+		if err := json.Unmarshal(m.Payload, &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.OK != tt.ok || r.Value != tt.mode {
+			t.Errorf("%s: result %s", tt.mode, m.Payload)
+		}
+		status, opmode := elc.Value(echonetlite.OperationStatus), elc.Value(echonetlite.OperationModeSetting)
+		if len(status.Data) != 1 || status.Data[0] != tt.status || len(opmode.Data) != 1 || opmode.Data[0] != tt.opmode {
+			t.Errorf("%s: 80=%v b0=%v", tt.mode, status.Data, opmode.Data)
+		}
+	}
+}
+
+func TestControllerBridge_RunDiscovery(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	obs, ch := observe(t, ctx, srv, "homeassistant/#")
+	defer obs.Close()
+
+	s := newTestSession(t, srv, "echonetlite")
+	b := NewControllerBridge(eltest.NewController(), eltest.Dictionary(), s)
+	d := NewDiscovery(s, DefaultDiscoveryPrefix)
+	go s.Run(ctx)
+	waitConnected(t, s)
+	go b.RunDiscovery(ctx, d, time.Minute)
+
+	m := receive(t, ch, "homeassistant/climate/echonetlite_dev1_013001/climate/config")
+	var config map[string]interface{}
+	if err := json.Unmarshal(m.Payload, &config); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"unique_id":                 "echonetlite_dev1_013001_climate",
+		"availability_topic":        "echonetlite/status",
+		"mode_state_topic":          "echonetlite/dev1/013001/state",
+		"mode_command_topic":        "echonetlite/dev1/013001/mode/set",
+		"power_command_topic":       "echonetlite/dev1/013001/80/set",
+		"temperature_command_topic": "echonetlite/dev1/013001/b3/set",
+		"temperature_state_topic":   "echonetlite/dev1/013001/b3",
+		"current_temperature_topic": "echonetlite/dev1/013001/bb",
+	}
+	for k, v := range want {
+		if config[k] != v {
+			t.Errorf("%s: got %v, want %v", k, config[k], v)
+		}
+	}
+	if _, ok := config["fan_mode_command_topic"]; ok {
+		t.Error("fan mode should not be configured without 0xA0 in Set property map")
+	}
+
+	m = receive(t, ch, "homeassistant/sensor/echonetlite_dev1_013001/bb/config")
+	if err := json.Unmarshal(m.Payload, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["device_class"] != "temperature" || config["unit_of_measurement"] != "°C" || config["state_topic"] != "echonetlite/dev1/013001/bb" {
+		t.Errorf("sensor config: %s", m.Payload)
+	}
+	if topics := srv.RetainedTopics("homeassistant/#"); len(topics) != 2 {
+		t.Errorf("settable properties should not be sensors: %v", topics)
+	}
+}
@@ -0,0 +1,119 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+// DefaultDiscoveryPrefix is discovery prefix of Home Assistant
+const DefaultDiscoveryPrefix = "homeassistant"
+
+// Entity is Home Assistant entity announced by MQTT discovery
+type Entity struct {
+	Component string // sensor, climate, light, switch
+	NodeID    string
+	ObjectID  string
+	Config    map[string]interface{}
+}
+
+// topic returns config topic <prefix>/<component>/<node_id>/<object_id>/config
+func (e Entity) topic(prefix string) string {
+	return strings.Join([]string{prefix, e.Component, e.NodeID, e.ObjectID, "config"}, "/")
+}
+
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// sanitizeID replaces characters which are not allowed in node_id and object_id
+func sanitizeID(s string) string {
+	return invalidIDChars.ReplaceAllString(s, "_")
+}
+
+// Discovery publishes Home Assistant MQTT discovery configs as retained messages.
+// Configs are published again on every connection and when Home Assistant publishes "online" to <prefix>/status.
+type Discovery struct {
+	session *Session
+	prefix  string
+
+	mu      sync.Mutex
+	configs map[string][]byte // config topic -> payload
+}
+
+// NewDiscovery returns Discovery publishing configs under prefix
+func NewDiscovery(s *Session, prefix string) *Discovery {
+	d := &Discovery{session: s, prefix: prefix, configs: map[string][]byte{}}
+	s.OnConnect(func(ctx context.Context, c *mqtt.Client) error {
+		if err := s.Subscribe(ctx, c, prefix+"/status", d.handleStatus); err != nil {
+			return err
+		}
+		for _, topic := range d.topics() {
+			if err := c.Publish(ctx, topic, s.opts.QoS, true, d.config(topic)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return d
+}
+
+// Add publishes configs of entities which are new or changed
+func (d *Discovery) Add(ctx context.Context, entities ...Entity) {
+	for _, e := range entities {
+		topic := e.topic(d.prefix)
+		payload, err := json.Marshal(e.Config)
+		if err != nil {
+			logger.Printf("[Error] %s", err)
+			continue
+		}
+		d.mu.Lock()
+		prev, ok := d.configs[topic]
+		d.configs[topic] = payload
+		d.mu.Unlock()
+		if ok && string(prev) == string(payload) {
+			continue
+		}
+		if err := d.session.Publish(ctx, topic, true, payload); err != nil && err != ErrNotConnected {
+			logger.Printf("[Error] failed to publish %s: %s", topic, err)
+		}
+	}
+}
+
+// topics returns config topics added so far in sorted order
+func (d *Discovery) topics() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	topics := make([]string, 0, len(d.configs))
+	for t := range d.configs {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (d *Discovery) config(topic string) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.configs[topic]
+}
+
+// handleStatus publishes all configs again when Home Assistant is restarted
+func (d *Discovery) handleStatus(m mqtt.Message) {
+	if string(m.Payload) != "online" {
+		return
+	}
+	ctx := context.Background()
+	for _, topic := range d.topics() {
+		if err := d.session.Publish(ctx, topic, true, d.config(topic)); err != nil {
+			// 接続処理中は OnConnect で発行される
+			if err != ErrNotConnected {
+				logger.Printf("[Error] failed to publish %s: %s", topic, err)
+			}
+			return
+		}
+	}
+}
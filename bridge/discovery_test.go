@@ -0,0 +1,57 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+func TestDiscovery(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	obs, ch := observe(t, ctx, srv, "ha/#")
+	defer obs.Close()
+
+	s := newTestSession(t, srv, "echonetlite")
+	d := NewDiscovery(s, "ha")
+	// 接続前に追加したものは接続時に発行される
+	d.Add(ctx, Entity{Component: "switch", NodeID: "node1", ObjectID: "switch", Config: map[string]interface{}{"name": "Power"}})
+	go s.Run(ctx)
+
+	m := receive(t, ch, "ha/switch/node1/switch/config")
+	if string(m.Payload) != `{"name":"Power"}` {
+		t.Errorf("config: %s", m.Payload)
+	}
+	waitConnected(t, s)
+
+	d.Add(ctx, Entity{Component: "sensor", NodeID: "node1", ObjectID: "e0", Config: map[string]interface{}{"name": "Temperature"}})
+	receive(t, ch, "ha/sensor/node1/e0/config")
+
+	// Home Assistant が起動したら全て発行しなおす
+	if err := obs.Publish(ctx, "ha/status", 1, false, []byte("online")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, ch, "ha/sensor/node1/e0/config")
+	receive(t, ch, "ha/switch/node1/switch/config")
+
+	if topics := srv.RetainedTopics("ha/+/+/+/config"); len(topics) != 2 {
+		t.Errorf("retained configs: %v", topics)
+	}
+}
+
+func TestSanitizeID(t *testing.T) {
+	if got := sanitizeID("echonetlite_mac:00:11:22:33:44:55_013001"); got != "echonetlite_mac_00_11_22_33_44_55_013001" {
+		t.Errorf("sanitizeID: %s", got)
+	}
+}
@@ -0,0 +1,220 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+// airconModes is operation modes (0xB0) of home air conditioner in HVAC modes of Home Assistant
+var airconModes = []struct {
+	mode string
+	raw  byte
+}{
+	{"auto", 0x41},
+	{"cool", 0x42},
+	{"heat", 0x43},
+	{"dry", 0x44},
+	{"fan_only", 0x45},
+}
+
+// sensorClass returns device_class and state_class of Home Assistant for unit of property
+func sensorClass(code echonetlite.PropertyCode, unit string) (string, string) {
+	switch unit {
+	case "℃", "°C":
+		return "temperature", "measurement"
+	case "W", "kW":
+		return "power", "measurement"
+	case "Wh", "kWh":
+		return "energy", "total_increasing"
+	case "V":
+		return "voltage", "measurement"
+	case "A":
+		return "current", "measurement"
+	case "ppm":
+		return "carbon_dioxide", "measurement"
+	case "lx", "lux":
+		return "illuminance", "measurement"
+	case "%":
+		if code == echonetlite.MeasuredRoomHumidity {
+			return "humidity", "measurement"
+		}
+	}
+	return "", "measurement"
+}
+
+// entityBuilder builds entities of an object whose properties are published under base topic
+type entityBuilder struct {
+	dict   echonetlite.ClassDictionary
+	obj    echonetlite.Object
+	base   string // <prefix>/<device>/<eoj>
+	nodeID string
+	common map[string]interface{} // availability and device
+	get    map[echonetlite.PropertyCode]bool
+	set    map[echonetlite.PropertyCode]bool
+}
+
+func (b entityBuilder) topic(code echonetlite.PropertyCode, rest ...string) string {
+	return strings.Join(append([]string{b.base, fmt.Sprintf("%02x", byte(code))}, rest...), "/")
+}
+
+func (b entityBuilder) entity(component, objectID, name string, config map[string]interface{}) Entity {
+	c := map[string]interface{}{
+		"name":      name,
+		"unique_id": b.nodeID + "_" + objectID,
+	}
+	for k, v := range b.common {
+		c[k] = v
+	}
+	for k, v := range config {
+		c[k] = v
+	}
+	return Entity{Component: component, NodeID: b.nodeID, ObjectID: objectID, Config: c}
+}
+
+// entities returns climate for home air conditioner, light for lighting, switch for other objects whose
+// operation status is settable, and sensors for numeric properties which are readable but not settable
+func (b entityBuilder) entities() []Entity {
+	entities := []Entity{}
+	lighting := b.obj.ClassGroup == echonetlite.HomeEquipmentGroup &&
+		(b.obj.Class == echonetlite.GeneralLighting || b.obj.Class == echonetlite.SingleFuncLighting)
+	switch {
+	case b.obj.ClassGroup == echonetlite.AirConditionerGroup && b.obj.Class == echonetlite.HomeAirConditioner:
+		entities = append(entities, b.climate())
+	case lighting && b.set[echonetlite.OperationStatus]:
+		entities = append(entities, b.light())
+	case b.set[echonetlite.OperationStatus]:
+		entities = append(entities, b.entity("switch", "switch", "Power", b.power(map[string]interface{}{
+			"state_topic":    b.topic(echonetlite.OperationStatus),
+			"value_template": "{{ '0x' ~ value_json.raw }}",
+		})))
+	}
+
+	codes := []echonetlite.PropertyCode{}
+	for c := range b.get {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	for _, c := range codes {
+		if e, ok := b.sensor(c); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// power returns config with command of operation status
+func (b entityBuilder) power(config map[string]interface{}) map[string]interface{} {
+	config["command_topic"] = b.topic(echonetlite.OperationStatus, "set")
+	config["payload_on"] = "0x30"
+	config["payload_off"] = "0x31"
+	return config
+}
+
+func (b entityBuilder) climate() Entity {
+	modes := []string{"off"}
+	stateMap := []string{}
+	for _, m := range airconModes {
+		modes = append(modes, m.mode)
+		stateMap = append(stateMap, fmt.Sprintf("'%02x':'%s'", m.raw, m.mode))
+	}
+	// 動作状態と運転モードを合わせてHVACモードにする
+	config := map[string]interface{}{
+		"modes":            modes,
+		"mode_state_topic": b.base + "/state",
+		"mode_state_template": "{% if value_json['80'] is defined and value_json['80'].raw == '31' %}off" +
+			"{% elif value_json['b0'] is defined %}{{ {" + strings.Join(stateMap, ",") + "}.get(value_json['b0'].raw, 'auto') }}" +
+			"{% else %}auto{% endif %}",
+		"power_command_topic": b.topic(echonetlite.OperationStatus, "set"),
+		"payload_on":          "0x30",
+		"payload_off":         "0x31",
+		"temperature_unit":    "C",
+		"precision":           1.0,
+	}
+	if b.set[echonetlite.OperationModeSetting] {
+		// 動作状態と運転モードはブリッジでまとめて設定する
+		config["mode_command_topic"] = b.base + "/mode/set"
+	}
+	if b.set[echonetlite.TemperatureSetting] {
+		config["temperature_command_topic"] = b.topic(echonetlite.TemperatureSetting, "set")
+		config["temperature_command_template"] = "{{ value | int }}"
+		config["temperature_state_topic"] = b.topic(echonetlite.TemperatureSetting)
+		config["temperature_state_template"] = "{{ value_json.value }}"
+		config["min_temp"] = 16
+		config["max_temp"] = 30
+		config["temp_step"] = 1
+	}
+	if b.get[echonetlite.MeasuredRoomTemperature] {
+		config["current_temperature_topic"] = b.topic(echonetlite.MeasuredRoomTemperature)
+		config["current_temperature_template"] = "{{ value_json.value }}"
+	}
+	if b.get[echonetlite.MeasuredRoomHumidity] {
+		config["current_humidity_topic"] = b.topic(echonetlite.MeasuredRoomHumidity)
+		config["current_humidity_template"] = "{{ value_json.value }}"
+	}
+	if b.set[echonetlite.AirFlowRateSetting] {
+		fanModes := []string{"auto"}
+		for l := 1; l <= 8; l++ {
+			fanModes = append(fanModes, fmt.Sprint(l))
+		}
+		config["fan_modes"] = fanModes
+		config["fan_mode_command_topic"] = b.topic(echonetlite.AirFlowRateSetting, "set")
+		config["fan_mode_command_template"] = "{{ '0x41' if value == 'auto' else '0x3' ~ value }}"
+		config["fan_mode_state_topic"] = b.topic(echonetlite.AirFlowRateSetting)
+		config["fan_mode_state_template"] = "{{ 'auto' if value_json.raw == '41' else value_json.raw[1] }}"
+	}
+	return b.entity("climate", "climate", "Air conditioner", config)
+}
+
+func (b entityBuilder) light() Entity {
+	config := b.power(map[string]interface{}{
+		"state_topic":          b.topic(echonetlite.OperationStatus),
+		"state_value_template": "{{ '0x' ~ value_json.raw }}",
+	})
+	if b.set[echonetlite.IlluminanceLevel] {
+		config["brightness_command_topic"] = b.topic(echonetlite.IlluminanceLevel, "set")
+		config["brightness_scale"] = 100
+		config["brightness_state_topic"] = b.topic(echonetlite.IlluminanceLevel)
+		config["brightness_value_template"] = "{{ value_json.value }}"
+	}
+	return b.entity("light", "light", "Light", config)
+}
+
+// sensor returns sensor of numeric property code.
+// Properties of super class other than power consumption are not sensors.
+func (b entityBuilder) sensor(code echonetlite.PropertyCode) (Entity, bool) {
+	if b.set[code] {
+		return Entity{}, false
+	}
+	if code < 0xA0 && code != echonetlite.MomentaryPowerConsumption && code != echonetlite.IntegratingPowerConsumption {
+		return Entity{}, false
+	}
+	info, ok := b.dict.Property(b.obj.ClassGroup, b.obj.Class, code)
+	if !ok || len(info.Values) > 0 {
+		return Entity{}, false
+	}
+	if _, ok := echonetlite.ParseNumberType(info.DataType); !ok {
+		return Entity{}, false
+	}
+
+	config := map[string]interface{}{
+		"state_topic":    b.topic(code),
+		"value_template": "{{ value_json.value }}",
+	}
+	unit := info.Unit
+	if unit == "℃" {
+		unit = "°C"
+	}
+	if unit != "" {
+		config["unit_of_measurement"] = unit
+	}
+	deviceClass, stateClass := sensorClass(code, info.Unit)
+	if deviceClass != "" {
+		config["device_class"] = deviceClass
+	}
+	config["state_class"] = stateClass
+	epc := fmt.Sprintf("%02x", byte(code))
+	return b.entity("sensor", epc, info.Detail, config), true
+}
@@ -0,0 +1,121 @@
+package bridge
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+func codeSet(codes ...echonetlite.PropertyCode) map[echonetlite.PropertyCode]bool {
+	m := map[echonetlite.PropertyCode]bool{}
+	for _, c := range codes {
+		m[c] = true
+	}
+	return m
+}
+
+func TestEntityBuilder(t *testing.T) {
+	dict := echonetlite.ClassDictionary{
+		echonetlite.HomeEquipmentGroup: {
+			echonetlite.GeneralLighting: {
+				Properties: echonetlite.PropertyDictionary{
+					0xb0: {Code: 0xb0, Detail: "照度レベル設定", Unit: "%", DataType: "unsigned char"},
+				},
+			},
+			echonetlite.StorageBattery: {
+				Properties: echonetlite.PropertyDictionary{
+					0x84: {Code: 0x84, Detail: "瞬時電力計測値", Unit: "W", DataType: "unsigned short"},
+					0x88: {Code: 0x88, Detail: "異常発生状態", DataType: "unsigned char", Values: map[byte]string{0x41: "異常有", 0x42: "異常無"}},
+					0xa8: {Code: 0xa8, Detail: "AC積算充電電力量計測値", Unit: "Wh", DataType: "unsigned long"},
+					0xe4: {Code: 0xe4, Detail: "蓄電残量3", Unit: "%", DataType: "unsigned char"},
+				},
+			},
+		},
+	}
+
+	aircon := echonetlite.NewObject(echonetlite.AirConditionerGroup, echonetlite.HomeAirConditioner, 1)
+	testcases := []struct {
+		name string
+		obj  echonetlite.Object
+		get  map[echonetlite.PropertyCode]bool
+		set  map[echonetlite.PropertyCode]bool
+		want map[string]map[string]interface{} // component/object_id -> expected config
+	}{
+		{
+			name: "light",
+			obj:  echonetlite.NewObject(echonetlite.HomeEquipmentGroup, echonetlite.GeneralLighting, 1),
+			get:  codeSet(0x80, 0xb0),
+			set:  codeSet(0x80, 0xb0),
+			want: map[string]map[string]interface{}{
+				"light/light": {
+					"command_topic":            "el/dev/029001/80/set",
+					"payload_on":               "0x30",
+					"state_topic":              "el/dev/029001/80",
+					"brightness_command_topic": "el/dev/029001/b0/set",
+					"brightness_scale":         100,
+				},
+			},
+		},
+		{
+			name: "climate",
+			obj:  aircon,
+			get:  codeSet(0x80, 0xb0, 0xb3),
+			set:  codeSet(0x80, 0xb0, 0xb3),
+			want: map[string]map[string]interface{}{
+				"climate/climate": {
+					"power_command_topic":       "el/dev/013001/80/set",
+					"mode_state_topic":          "el/dev/013001/state",
+					"mode_command_topic":        "el/dev/013001/mode/set",
+					"mode_command_template":     nil,
+					"temperature_command_topic": "el/dev/013001/b3/set",
+				},
+			},
+		},
+		{
+			name: "switch and sensors",
+			obj:  echonetlite.NewObject(echonetlite.HomeEquipmentGroup, echonetlite.StorageBattery, 1),
+			get:  codeSet(0x80, 0x84, 0x88, 0xa8, 0xe4),
+			set:  codeSet(0x80),
+			want: map[string]map[string]interface{}{
+				"switch/switch": {
+					"command_topic": "el/dev/027d01/80/set",
+					"state_topic":   "el/dev/027d01/80",
+				},
+				"sensor/84": {"device_class": "power", "state_class": "measurement", "unit_of_measurement": "W"},
+				"sensor/a8": {"device_class": "energy", "state_class": "total_increasing", "unit_of_measurement": "Wh"},
+				"sensor/e4": {"state_class": "measurement", "unit_of_measurement": "%", "state_topic": "el/dev/027d01/e4"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		b := entityBuilder{
+			dict:   dict,
+			obj:    tc.obj,
+			base:   "el/dev/" + hex.EncodeToString(tc.obj.Data()),
+			nodeID: "el_dev",
+			get:    tc.get,
+			set:    tc.set,
+		}
+
+		entities := b.entities()
+		if len(entities) != len(tc.want) {
+			t.Errorf("%s: %d entities, want %d: %v", tc.name, len(entities), len(tc.want), entities)
+		}
+		for _, e := range entities {
+			want, ok := tc.want[e.Component+"/"+e.ObjectID]
+			if !ok {
+				t.Errorf("%s: unexpected entity %s/%s", tc.name, e.Component, e.ObjectID)
+				continue
+			}
+			if e.Config["unique_id"] != "el_dev_"+e.ObjectID {
+				t.Errorf("%s: unique_id %v", tc.name, e.Config["unique_id"])
+			}
+			for k, v := range want {
+				if e.Config[k] != v {
+					t.Errorf("%s %s/%s: %s is %v, want %v", tc.name, e.Component, e.ObjectID, k, e.Config[k], v)
+				}
+			}
+		}
+	}
+}
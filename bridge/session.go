@@ -0,0 +1,163 @@
+// Package bridge publishes ECHONET Lite properties and smart-meter readings to MQTT,
+// and writes properties requested through MQTT
+package bridge
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+
+	// publishTimeout is timeout of a publish
+	publishTimeout = 10 * time.Second
+)
+
+// ErrNotConnected is returned when a message is published while the session is disconnected
+var ErrNotConnected = errors.New("not connected to MQTT broker")
+
+var logger = log.New(os.Stdout, "[MQTT]", log.LstdFlags)
+
+// Options is options of session
+type Options struct {
+	MQTT   mqtt.Options
+	Prefix string // prefix of all topics
+	QoS    byte   // QoS of published messages and subscriptions
+	Retain bool   // retain published states
+
+	// EVPermission permits set to EV charger/discharger requested through MQTT, which is refused by default
+	EVPermission echonetlite.EVSetPermission
+}
+
+// Session keeps connection to MQTT broker and connects again after it is lost.
+// "online" is published to <prefix>/status on connection, and "offline" is published by the broker as will.
+type Session struct {
+	opts Options
+
+	mu     sync.Mutex
+	client *mqtt.Client
+	hooks  []func(ctx context.Context, c *mqtt.Client) error
+}
+
+// NewSession returns Session for opts
+func NewSession(opts Options) *Session {
+	opts.MQTT.Will = &mqtt.Message{Topic: opts.Prefix + "/status", Payload: []byte("offline"), QoS: opts.QoS, Retain: true}
+	return &Session{opts: opts}
+}
+
+// Prefix returns prefix of topics
+func (s *Session) Prefix() string {
+	return s.opts.Prefix
+}
+
+// StatusTopic returns topic of availability
+func (s *Session) StatusTopic() string {
+	return s.opts.Prefix + "/status"
+}
+
+// OnConnect registers f called on every connection, to subscribe topics and publish initial messages.
+// The connection is made again if f returns error.
+func (s *Session) OnConnect(f func(ctx context.Context, c *mqtt.Client) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, f)
+}
+
+// Run connects to the broker until ctx is done
+func (s *Session) Run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Printf("[Error] %s, reconnecting in %s", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if err != errSessionLost {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		} else {
+			backoff = minBackoff
+		}
+	}
+}
+
+// errSessionLost is returned by session when established connection is lost
+var errSessionLost = errors.New("connection to MQTT broker is lost")
+
+func (s *Session) session(ctx context.Context) error {
+	c, err := mqtt.Connect(ctx, s.opts.MQTT)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Publish(ctx, s.StatusTopic(), s.opts.QoS, true, []byte("online")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	hooks := append([]func(context.Context, *mqtt.Client) error{}, s.hooks...)
+	s.mu.Unlock()
+	for _, f := range hooks {
+		if err := f(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	logger.Printf("connected to %s", s.opts.MQTT.Broker)
+	s.mu.Lock()
+	s.client = c
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.client = nil
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-c.Done():
+		return errSessionLost
+	case <-ctx.Done():
+		c.Publish(context.Background(), s.StatusTopic(), s.opts.QoS, true, []byte("offline"))
+		return ctx.Err()
+	}
+}
+
+// Publish publishes payload to topic in QoS of the session.
+// It returns ErrNotConnected while the connection is lost.
+func (s *Session) Publish(ctx context.Context, topic string, retain bool, payload []byte) error {
+	s.mu.Lock()
+	c := s.client
+	s.mu.Unlock()
+	if c == nil {
+		return ErrNotConnected
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return c.Publish(ctx, topic, s.opts.QoS, retain, payload)
+}
+
+// PublishState publishes payload to topic, which is retained if Options.Retain is true
+func (s *Session) PublishState(ctx context.Context, topic string, payload []byte) error {
+	return s.Publish(ctx, topic, s.opts.Retain, payload)
+}
+
+// Subscribe subscribes filter on c in QoS of the session
+func (s *Session) Subscribe(ctx context.Context, c *mqtt.Client, filter string, h mqtt.Handler) error {
+	return c.Subscribe(ctx, filter, s.opts.QoS, h)
+}
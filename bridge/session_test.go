@@ -0,0 +1,124 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+func newTestSession(t *testing.T, s *mqtt.Server, prefix string) *Session {
+	t.Helper()
+	return NewSession(Options{
+		MQTT:   mqtt.Options{Broker: "tcp://" + s.Addr(), ClientID: prefix},
+		Prefix: prefix,
+		QoS:    1,
+		Retain: true,
+	})
+}
+
+// waitConnected waits until session is connected and hooks are done
+func waitConnected(t *testing.T, s *Session) {
+	t.Helper()
+	for i := 0; i < 300; i++ {
+		s.mu.Lock()
+		c := s.client
+		s.mu.Unlock()
+		if c != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("session is not connected")
+}
+
+// observe subscribes filter and returns channel of messages
+func observe(t *testing.T, ctx context.Context, s *mqtt.Server, filter string) (*mqtt.Client, <-chan mqtt.Message) {
+	t.Helper()
+	c, err := mqtt.Connect(ctx, mqtt.Options{Broker: "tcp://" + s.Addr(), ClientID: "observer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan mqtt.Message, 64)
+	if err := c.Subscribe(ctx, filter, 1, func(m mqtt.Message) { ch <- m }); err != nil {
+		t.Fatal(err)
+	}
+	return c, ch
+}
+
+// receive returns the next message of topic, skipping other topics
+func receive(t *testing.T, ch <-chan mqtt.Message, topic string) mqtt.Message {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case m := <-ch:
+			if m.Topic == topic {
+				return m
+			}
+		case <-timeout:
+			t.Fatalf("message of %s is not received", topic)
+			return mqtt.Message{}
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	s := newTestSession(t, srv, "test")
+	if err := s.Publish(ctx, "test/x", false, []byte("x")); err != ErrNotConnected {
+		t.Errorf("Publish before connection: %v", err)
+	}
+
+	connected := make(chan struct{}, 4)
+	s.OnConnect(func(ctx context.Context, c *mqtt.Client) error {
+		connected <- struct{}{}
+		return nil
+	})
+	sctx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		s.Run(sctx)
+		close(done)
+	}()
+	<-connected
+	waitConnected(t, s)
+	if m, ok := srv.Retained("test/status"); !ok || string(m.Payload) != "online" {
+		t.Errorf("status: %q", m.Payload)
+	}
+
+	// 切断されたら will が発行され、再接続する
+	srv.Disconnect()
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session is not reconnected")
+	}
+	waitConnected(t, s)
+	if m, ok := srv.Retained("test/status"); !ok || string(m.Payload) != "online" {
+		t.Errorf("status after reconnection: %q", m.Payload)
+	}
+	if err := s.PublishState(ctx, "test/x", []byte("x")); err != nil {
+		t.Error(err)
+	}
+
+	stop()
+	<-done
+	if m, ok := srv.Retained("test/status"); !ok || string(m.Payload) != "offline" {
+		t.Errorf("status after stop: %q", m.Payload)
+	}
+	if m, ok := srv.Retained("test/x"); !ok || string(m.Payload) != "x" {
+		t.Errorf("retained state: %q", m.Payload)
+	}
+}
@@ -0,0 +1,122 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+// SmartMeter is source of smart-meter readings, which is implemented by *echonetlite.ElectricityControllerNode
+type SmartMeter interface {
+	Subscribe() (<-chan echonetlite.Reading, func())
+	Readings() []echonetlite.Reading
+}
+
+// SmartMeterBridge publishes readings of smart-meter to <prefix>/<reading> such as <prefix>/power
+type SmartMeterBridge struct {
+	node    SmartMeter
+	session *Session
+}
+
+// ReadingPayload is payload of reading
+type ReadingPayload struct {
+	Value float64   `json:"value"`
+	Unit  string    `json:"unit"`
+	Time  time.Time `json:"time"`
+}
+
+// NewSmartMeterBridge returns SmartMeterBridge of node.
+// The last readings are published again on every connection.
+func NewSmartMeterBridge(node SmartMeter, s *Session) *SmartMeterBridge {
+	b := &SmartMeterBridge{node: node, session: s}
+	s.OnConnect(func(ctx context.Context, c *mqtt.Client) error {
+		for _, r := range node.Readings() {
+			payload, err := marshalReading(r)
+			if err != nil {
+				return err
+			}
+			if err := c.Publish(ctx, b.topic(r), s.opts.QoS, s.opts.Retain, payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return b
+}
+
+// Entities returns Home Assistant sensors of readings, power in W and cumulative energy in kWh
+func (b *SmartMeterBridge) Entities() []Entity {
+	nodeID := sanitizeID(b.session.Prefix())
+	device := map[string]interface{}{
+		"identifiers": []string{nodeID},
+		"name":        "Smart meter",
+		"model":       "低圧スマート電力量メータ",
+	}
+	sensors := []struct {
+		reading     string
+		name        string
+		unit        string
+		deviceClass string
+		stateClass  string
+	}{
+		{echonetlite.ReadingPower, "Power", "W", "power", "measurement"},
+		{echonetlite.ReadingEnergyNormal, "Energy", "kWh", "energy", "total_increasing"},
+		{echonetlite.ReadingEnergyReverse, "Reverse energy", "kWh", "energy", "total_increasing"},
+	}
+	entities := []Entity{}
+	for _, s := range sensors {
+		entities = append(entities, Entity{
+			Component: "sensor",
+			NodeID:    nodeID,
+			ObjectID:  s.reading,
+			Config: map[string]interface{}{
+				"name":                s.name,
+				"unique_id":           nodeID + "_" + s.reading,
+				"state_topic":         b.topic(echonetlite.Reading{Name: s.reading}),
+				"value_template":      "{{ value_json.value }}",
+				"unit_of_measurement": s.unit,
+				"device_class":        s.deviceClass,
+				"state_class":         s.stateClass,
+				"availability_topic":  b.session.StatusTopic(),
+				"device":              device,
+			},
+		})
+	}
+	return entities
+}
+
+func marshalReading(r echonetlite.Reading) ([]byte, error) {
+	return json.Marshal(ReadingPayload{Value: r.Value, Unit: r.Unit, Time: r.Updated})
+}
+
+func (b *SmartMeterBridge) topic(r echonetlite.Reading) string {
+	return b.session.Prefix() + "/" + r.Name
+}
+
+// Run publishes readings until ctx is done
+func (b *SmartMeterBridge) Run(ctx context.Context) {
+	readings, stop := b.node.Subscribe()
+	defer stop()
+	for {
+		select {
+		case r := <-readings:
+			b.publish(ctx, r)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *SmartMeterBridge) publish(ctx context.Context, r echonetlite.Reading) {
+	payload, err := marshalReading(r)
+	if err != nil {
+		logger.Printf("[Error] %s", err)
+		return
+	}
+	if err := b.session.PublishState(ctx, b.topic(r), payload); err != nil {
+		logger.Printf("[Error] failed to publish %s: %s", b.topic(r), err)
+	}
+}
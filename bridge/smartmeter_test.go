@@ -0,0 +1,91 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+type fakeSmartMeter struct {
+	readings chan echonetlite.Reading
+	last     []echonetlite.Reading
+}
+
+func (m *fakeSmartMeter) Subscribe() (<-chan echonetlite.Reading, func()) {
+	return m.readings, func() {}
+}
+
+func (m *fakeSmartMeter) Readings() []echonetlite.Reading {
+	return m.last
+}
+
+func TestSmartMeterBridge(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv, err := mqtt.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	obs, ch := observe(t, ctx, srv, "smartmeter/#")
+	defer obs.Close()
+
+	updated := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	node := &fakeSmartMeter{
+		readings: make(chan echonetlite.Reading, 4),
+		last:     []echonetlite.Reading{{Name: echonetlite.ReadingEnergyNormal, Unit: "kWh", Value: 1234.5, Updated: updated}},
+	}
+	s := newTestSession(t, srv, "smartmeter")
+	b := NewSmartMeterBridge(node, s)
+	go s.Run(ctx)
+	go b.Run(ctx)
+
+	// 接続時に最後の値が発行される
+	m := receive(t, ch, "smartmeter/energy_normal")
+	var p ReadingPayload
+	if err := json.Unmarshal(m.Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Value != 1234.5 || p.Unit != "kWh" || !p.Time.Equal(updated) {
+		t.Errorf("energy_normal: %s", m.Payload)
+	}
+
+	waitConnected(t, s)
+	node.readings <- echonetlite.Reading{Name: echonetlite.ReadingPower, Unit: "W", Value: 480, Updated: updated}
+	m = receive(t, ch, "smartmeter/power")
+	if string(m.Payload) != `{"value":480,"unit":"W","time":"2021-03-01T12:00:00Z"}` {
+		t.Errorf("power: %s", m.Payload)
+	}
+	if _, ok := srv.Retained("smartmeter/power"); !ok {
+		t.Error("power is not retained")
+	}
+}
+
+func TestSmartMeterBridge_Entities(t *testing.T) {
+	s := NewSession(Options{Prefix: "smartmeter"})
+	b := NewSmartMeterBridge(&fakeSmartMeter{}, s)
+	entities := b.Entities()
+	if len(entities) != 3 {
+		t.Fatalf("entities: %v", entities)
+	}
+	for _, e := range entities[1:] {
+		c := e.Config
+		if e.Component != "sensor" || c["device_class"] != "energy" || c["state_class"] != "total_increasing" || c["unit_of_measurement"] != "kWh" {
+			t.Errorf("energy sensor: %+v", e)
+		}
+	}
+	if got := entities[0].topic(DefaultDiscoveryPrefix); got != "homeassistant/sensor/smartmeter/power/config" {
+		t.Errorf("topic: %s", got)
+	}
+	if got := entities[0].Config["state_topic"]; got != "smartmeter/power" {
+		t.Errorf("state topic: %s", got)
+	}
+}
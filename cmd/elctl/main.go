@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+var version string
+
+var (
+	jsonOutput = flag.Bool("json", false, "print results in JSON")
+	timeout    = flag.Duration("timeout", 3*time.Second, "time to wait for responses")
+	dictPath   = flag.String("dict", "", "directory of ECHONETLite-ObjectDatabase csv files")
+	makerPath  = flag.String("manufacturers", "", "CSV file of manufacturer codes and names")
+	verbose    = flag.Bool("v", false, "print logs")
+
+	allowEVCharge    = flag.Bool("allow-ev-charge", false, "permit set which starts charging of EV charger/discharger")
+	allowEVDischarge = flag.Bool("allow-ev-discharge", false, "permit set which starts discharging of EV charger/discharger")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: elctl [flags] <command> [args]
+
+Commands:
+  discover                     find nodes and their objects
+  get <ip> <eoj> <epc...>      read properties
+  set <ip> <eoj> <epc>=<value> write properties, value is hex (0x..), decimal or name such as on
+  watch                        print notifications (INF, INFC)
+  describe <ip>                print property maps of all objects in the node
+
+Example:
+  elctl get 192.168.1.10 013001 80 bb
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		echonetlite.SetLogOutput(ioutil.Discard)
+	}
+
+	err := run(flag.Arg(0), flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd string, args []string) error {
+	if *dictPath != "" {
+		err := echonetlite.LoadClassDictionary(*dictPath)
+		if err != nil {
+			return fmt.Errorf("failed to load class dictionary: %w", err)
+		}
+	} else {
+		// Names are not printed if the database is not found
+		echonetlite.PrepareClassDictionary()
+	}
+	if *makerPath != "" {
+		if err := echonetlite.LoadManufacturers(*makerPath); err != nil {
+			return fmt.Errorf("failed to load manufacturers: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	elc, err := echonetlite.NewControllerNode()
+	if err != nil {
+		return err
+	}
+	defer elc.Close()
+	elc.Listen(ctx)
+
+	switch cmd {
+	case "discover":
+		return discover(ctx, elc)
+	case "get":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: elctl get <ip> <eoj> <epc...>")
+		}
+		return get(ctx, elc, args[0], args[1], args[2:])
+	case "set":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: elctl set <ip> <eoj> <epc>=<value>...")
+		}
+		return set(ctx, elc, args[0], args[1], args[2:])
+	case "watch":
+		return watch(ctx, elc)
+	case "describe":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: elctl describe <ip>")
+		}
+		return describe(ctx, elc, args[0])
+	case "version":
+		fmt.Println(version)
+		return nil
+	}
+	return fmt.Errorf("unknown command: %s", cmd)
+}
+
+func discover(ctx context.Context, elc *echonetlite.ControllerNode) error {
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	nodeProfile := echonetlite.NewObject(echonetlite.ProfileGroup, echonetlite.Profile, 0x01)
+	f := echonetlite.NewFrame(0, controllerObject(), nodeProfile, echonetlite.Get, []echonetlite.Property{
+		{Code: byte(echonetlite.InstanceListS), Len: 0, Data: []byte{}},
+	})
+
+	nodes := []nodeOutput{}
+	for _, m := range elc.Broadcast(ctx, &f) {
+		n := nodeOutput{Address: m.Host()}
+		if p, ok := m.Frame.Property(echonetlite.InstanceListS); ok {
+			objs, err := echonetlite.ParseObjectList(p.Data)
+			if err != nil {
+				log.Println(err)
+			}
+			for _, o := range objs {
+				n.Objects = append(n.Objects, newObjectOutput(o))
+			}
+		}
+		nodes = append(nodes, n)
+	}
+
+	return output(nodes, func() {
+		for _, n := range nodes {
+			fmt.Println(n.Address)
+			for _, o := range n.Objects {
+				fmt.Printf("  %s %s\n", o.EOJ, o.Class)
+			}
+		}
+	})
+}
+
+func get(ctx context.Context, elc *echonetlite.ControllerNode, ip, eoj string, epcs []string) error {
+	obj, err := echonetlite.ParseObject(eoj)
+	if err != nil {
+		return err
+	}
+	codes := []echonetlite.PropertyCode{}
+	for _, s := range epcs {
+		c, err := echonetlite.ParsePropertyCode(s)
+		if err != nil {
+			return err
+		}
+		codes = append(codes, c)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	props, err := elc.Get(ctx, ip, obj, codes...)
+	if _, ok := err.(*echonetlite.ServiceError); err != nil && !ok {
+		return err
+	}
+
+	out := newObjectOutput(obj)
+	for _, p := range props {
+		out.Properties = append(out.Properties, newPropertyOutput(obj, p))
+	}
+	perr := output(out, func() {
+		for _, p := range out.Properties {
+			printProperty(p)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return perr
+}
+
+func set(ctx context.Context, elc *echonetlite.ControllerNode, ip, eoj string, assignments []string) error {
+	obj, err := echonetlite.ParseObject(eoj)
+	if err != nil {
+		return err
+	}
+	props := []echonetlite.Property{}
+	for _, a := range assignments {
+		p, err := parseAssignment(obj, a)
+		if err != nil {
+			return err
+		}
+		props = append(props, p)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	elc.EVPermission = evSetPermission()
+	err = elc.Set(ctx, ip, obj, props...)
+	if _, ok := err.(*echonetlite.EVSetNotPermittedError); ok {
+		return fmt.Errorf("%w (use -allow-ev-charge / -allow-ev-discharge)", err)
+	}
+	if err != nil {
+		return err
+	}
+	return output(map[string]bool{"ok": true}, func() {
+		fmt.Println("OK")
+	})
+}
+
+func watch(ctx context.Context, elc *echonetlite.ControllerNode) error {
+	ch, stop := elc.Subscribe()
+	defer stop()
+
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m := <-ch:
+			if m.Frame.ESV != echonetlite.Inf && m.Frame.ESV != echonetlite.InfC {
+				continue
+			}
+			n := notificationOutput{
+				Time:    time.Now().Format(time.RFC3339),
+				Address: m.Host(),
+				ESV:     m.Frame.ESV.String(),
+				Object:  newObjectOutput(m.Frame.SEOJ),
+			}
+			for _, p := range m.Frame.Properties {
+				n.Object.Properties = append(n.Object.Properties, newPropertyOutput(m.Frame.SEOJ, p))
+			}
+
+			if *jsonOutput {
+				if err := enc.Encode(n); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Printf("%s %s %s %s %s\n", n.Time, n.Address, n.ESV, n.Object.EOJ, n.Object.Class)
+			for _, p := range n.Object.Properties {
+				printProperty(p)
+			}
+		}
+	}
+}
+
+func describe(ctx context.Context, elc *echonetlite.ControllerNode, ip string) error {
+	nodeProfile := echonetlite.NewObject(echonetlite.ProfileGroup, echonetlite.Profile, 0x01)
+
+	getCtx, cancel := context.WithTimeout(ctx, *timeout)
+	props, err := elc.Get(getCtx, ip, nodeProfile, echonetlite.InstanceListS)
+	cancel()
+	if err != nil {
+		return err
+	}
+	objs := []echonetlite.Object{nodeProfile}
+	if len(props) > 0 {
+		list, err := echonetlite.ParseObjectList(props[0].Data)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, list...)
+	}
+
+	node := nodeOutput{Address: ip}
+	for _, obj := range objs {
+		out := newObjectOutput(obj)
+
+		getCtx, cancel := context.WithTimeout(ctx, *timeout)
+		props, err := elc.Get(getCtx, ip, obj, echonetlite.StageChangeAnnouncePropertyMap, echonetlite.SetPropertyMap, echonetlite.GetPropertyMap)
+		cancel()
+		if _, ok := err.(*echonetlite.ServiceError); err != nil && !ok {
+			return err
+		}
+
+		for _, p := range props {
+			codes, err := echonetlite.ParsePropertyMap(p.Data)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			list := []propertyOutput{}
+			for _, c := range codes {
+				list = append(list, newPropertyOutput(obj, echonetlite.Property{Code: byte(c)}))
+			}
+			switch echonetlite.PropertyCode(p.Code) {
+			case echonetlite.StageChangeAnnouncePropertyMap:
+				out.AnnoMap = list
+			case echonetlite.SetPropertyMap:
+				out.SetMap = list
+			case echonetlite.GetPropertyMap:
+				out.GetMap = list
+			}
+		}
+		node.Objects = append(node.Objects, out)
+	}
+
+	return output(node, func() {
+		fmt.Println(node.Address)
+		for _, o := range node.Objects {
+			fmt.Printf("%s %s\n", o.EOJ, o.Class)
+			for _, m := range []struct {
+				name  string
+				codes []propertyOutput
+			}{{"Anno", o.AnnoMap}, {"Set", o.SetMap}, {"Get", o.GetMap}} {
+				fmt.Printf("  %s:\n", m.name)
+				for _, p := range m.codes {
+					fmt.Printf("    %s %s\n", p.EPC, p.Name)
+				}
+			}
+		}
+	})
+}
+
+// evSetPermission returns permission to set EV charger/discharger given by flags
+func evSetPermission() echonetlite.EVSetPermission {
+	var perm echonetlite.EVSetPermission
+	if *allowEVCharge {
+		perm |= echonetlite.EVAllowCharge
+	}
+	if *allowEVDischarge {
+		perm |= echonetlite.EVAllowDischarge
+	}
+	return perm
+}
+
+func controllerObject() echonetlite.Object {
+	return echonetlite.NewObject(echonetlite.ControllerGroup, echonetlite.Controller, 0x01)
+}
+
+// parseAssignment parses "<epc>=<value>".
+// Value is validated by class dictionary, and only hex value prefixed with 0x is accepted for EPC not defined in it.
+func parseAssignment(obj echonetlite.Object, s string) (echonetlite.Property, error) {
+	tokens := strings.SplitN(s, "=", 2)
+	if len(tokens) != 2 {
+		return echonetlite.Property{}, fmt.Errorf("invalid assignment: %s", s)
+	}
+	code, err := echonetlite.ParsePropertyCode(tokens[0])
+	if err != nil {
+		return echonetlite.Property{}, err
+	}
+
+	dict := echonetlite.GetClassDictionary()
+	if _, ok := dict.Property(obj.ClassGroup, obj.Class, code); ok {
+		return dict.EncodeProperty(obj, code, tokens[1])
+	}
+	// 辞書にないメーカー独自のプロパティなどはそのまま送る
+	value := tokens[1]
+	if !strings.HasPrefix(value, "0x") {
+		return echonetlite.Property{}, fmt.Errorf("EPC %02x is not defined for class %02x%02x, value must be hex with 0x prefix: %s", byte(code), byte(obj.ClassGroup), byte(obj.Class), value)
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(data) == 0 {
+		return echonetlite.Property{}, fmt.Errorf("invalid hex value: %s", value)
+	}
+	return echonetlite.Property{Code: byte(code), Len: len(data), Data: data}, nil
+}
+
+func output(v interface{}, text func()) error {
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text()
+	return nil
+}
+
+func printProperty(p propertyOutput) {
+	fmt.Printf("  %s %s: %s", p.EPC, p.Name, p.EDT)
+	if p.Value != "" {
+		fmt.Printf(" (%s%s)", p.Value, p.Unit)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+type nodeOutput struct {
+	Address string         `json:"address"`
+	Objects []objectOutput `json:"objects"`
+}
+
+type objectOutput struct {
+	EOJ        string           `json:"eoj"`
+	Class      string           `json:"class,omitempty"`
+	Properties []propertyOutput `json:"properties,omitempty"`
+	AnnoMap    []propertyOutput `json:"anno_map,omitempty"`
+	SetMap     []propertyOutput `json:"set_map,omitempty"`
+	GetMap     []propertyOutput `json:"get_map,omitempty"`
+}
+
+type propertyOutput struct {
+	EPC   string `json:"epc"`
+	Name  string `json:"name,omitempty"`
+	EDT   string `json:"edt,omitempty"`
+	Value string `json:"value,omitempty"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+type notificationOutput struct {
+	Time    string       `json:"time"`
+	Address string       `json:"address"`
+	ESV     string       `json:"esv"`
+	Object  objectOutput `json:"object"`
+}
+
+func newObjectOutput(obj echonetlite.Object) objectOutput {
+	out := objectOutput{EOJ: fmt.Sprintf("%02x%02x%02x", byte(obj.ClassGroup), byte(obj.Class), byte(obj.Num))}
+	info := echonetlite.GetClassDictionary().Get(obj.ClassGroup, obj.Class)
+	if info.Desc != "unknown" {
+		out.Class = info.Desc
+	}
+	return out
+}
+
+func newPropertyOutput(obj echonetlite.Object, p echonetlite.Property) propertyOutput {
+	out := propertyOutput{EPC: fmt.Sprintf("%02x", p.Code), EDT: p.Data.String()}
+	switch echonetlite.PropertyCode(p.Code) {
+	case echonetlite.InstallationLocation:
+		if loc, err := echonetlite.ParseLocation(p.Data); err == nil {
+			out.Value = loc.Label()
+		}
+	case echonetlite.ID:
+		if id, err := echonetlite.ParseIdentification(p.Data); err == nil && id.LowerLayer == echonetlite.LowerLayerManufacturer {
+			out.Value = id.Manufacturer.String()
+		}
+	case echonetlite.ManufacturerCode:
+		if m, err := echonetlite.ParseManufacturer(p.Data); err == nil {
+			out.Value = m.String()
+		}
+	}
+	info, ok := echonetlite.GetClassDictionary().Property(obj.ClassGroup, obj.Class, echonetlite.PropertyCode(p.Code))
+	if !ok {
+		return out
+	}
+	out.Name = info.Detail
+	if out.Value != "" {
+		return out
+	}
+	if n, ok := decodeNumber(info, p.Data); ok {
+		out.Value = n.String()
+		if n.Valid() {
+			out.Unit = info.Unit
+		}
+	}
+	return out
+}
+
+// decodeNumber decodes data if the property is a number
+func decodeNumber(info echonetlite.PropertyInfo, data []byte) (echonetlite.Number, bool) {
+	t, ok := echonetlite.ParseNumberType(info.DataType)
+	if !ok {
+		return echonetlite.Number{}, false
+	}
+	n, err := echonetlite.DecodeNumber(t, data)
+	if err != nil {
+		return echonetlite.Number{}, false
+	}
+	return n, true
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/matsuu/go-el-controller/api"
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+// startAPI serves REST/JSON API of elc at /api/ of mux, or at its own listen address if it is configured
+func startAPI(ctx context.Context, elc *echonetlite.ControllerNode, mux *http.ServeMux, a config.API) error {
+	opts, err := apiOptions(a)
+	if err != nil {
+		return err
+	}
+	if opts.Token == "" {
+		log.Printf("API is read-only without token, use %s or api.token_file to set properties", config.EnvAPIToken)
+	}
+	s := api.NewServer(elc, echonetlite.GetClassDictionary(), opts)
+	go s.Run(ctx)
+	if a.ListenAddress == "" {
+		mux.Handle("/api/", s)
+		return nil
+	}
+	m := http.NewServeMux()
+	m.Handle("/api/", s)
+	go func() {
+		log.Println("start API: ", a.ListenAddress)
+		if err := http.ListenAndServe(a.ListenAddress, m); err != nil {
+			log.Printf("API finished: %s", err)
+		}
+	}()
+	return nil
+}
+
+// apiOptions returns options of API server, with token from environment variable or file if set
+func apiOptions(a config.API) (api.Options, error) {
+	token, err := a.Token()
+	if err != nil {
+		return api.Options{}, err
+	}
+	return api.Options{Token: token, EVPermission: apiEVPermission(a)}, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+// discoverTimeout is timeout to request instance list of a configured device
+const discoverTimeout = 5 * time.Second
+
+// loadConfig reads configuration file given by -config, and flags given explicitly override it
+func loadConfig() (config.Config, error) {
+	cfg := config.Default()
+	if *configPath != "" {
+		var err error
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			return cfg, err
+		}
+	}
+
+	e := &cfg.Exporter
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen-address":
+			e.ListenAddress = *exporterAddr
+		case "capture":
+			e.Capture = *capturePath
+		case "api":
+			e.API.Enabled = *enableAPI
+		case "mqtt-broker":
+			e.MQTT.Broker = *mqttBroker
+		case "stale-timeout":
+			e.StaleTimeout = *staleTimeout
+		case "properties":
+			e.Properties.Enabled = *exportProperties
+		case "include":
+			e.Properties.Include = ruleStrings(includeRules)
+		case "exclude":
+			e.Properties.Exclude = ruleStrings(excludeRules)
+		}
+	})
+	return cfg, cfg.Validate()
+}
+
+func ruleStrings(rules []echonetlite.PropertyRule) []string {
+	s := make([]string, 0, len(rules))
+	for _, r := range rules {
+		s = append(s, r.String())
+	}
+	return s
+}
+
+// warnNotReloaded logs settings which need restart to be changed
+func warnNotReloaded(prev, next config.Exporter) {
+	if prev.ListenAddress != next.ListenAddress {
+		log.Printf("listen_address is changed to %s, restart is needed to apply it", next.ListenAddress)
+	}
+	if prev.Capture != next.Capture {
+		log.Printf("capture is changed to %q, restart is needed to apply it", next.Capture)
+	}
+	if prev.Manufacturers != next.Manufacturers {
+		log.Printf("manufacturers is changed to %q, restart is needed to apply it", next.Manufacturers)
+	}
+	if prev.API != next.API {
+		log.Println("api is changed, restart is needed to apply it")
+	}
+	if prev.MQTT != next.MQTT {
+		log.Println("mqtt is changed, restart is needed to apply it")
+	}
+	if !reflect.DeepEqual(prev.Sinks, next.Sinks) {
+		log.Println("sinks are changed, restart is needed to apply them")
+	}
+}
+
+// evPermission returns permission to set EV charger/discharger, which is allowed to either MQTT or API
+func evPermission(e config.Exporter) echonetlite.EVSetPermission {
+	return mqttEVPermission(e.MQTT) | apiEVPermission(e.API)
+}
+
+// mqttEVPermission returns permission to set EV charger/discharger through MQTT
+func mqttEVPermission(m config.MQTT) echonetlite.EVSetPermission {
+	return newEVPermission(m.AllowEVCharge, m.AllowEVDischarge)
+}
+
+// apiEVPermission returns permission to set EV charger/discharger through API
+func apiEVPermission(a config.API) echonetlite.EVSetPermission {
+	return newEVPermission(a.AllowEVCharge, a.AllowEVDischarge)
+}
+
+func newEVPermission(charge, discharge bool) echonetlite.EVSetPermission {
+	var perm echonetlite.EVSetPermission
+	if charge {
+		perm |= echonetlite.EVAllowCharge
+	}
+	if discharge {
+		perm |= echonetlite.EVAllowDischarge
+	}
+	return perm
+}
+
+// addDevices registers configured devices which are not found yet.
+// Instance list is requested for a device without objects.
+func addDevices(ctx context.Context, elc *echonetlite.ControllerNode, devices []config.Device) {
+	found := map[string]bool{}
+	for _, n := range elc.Nodes() {
+		found[n.Address] = true
+	}
+	for _, d := range devices {
+		if found[d.Address] {
+			continue
+		}
+		if len(d.Objects) == 0 {
+			func() {
+				ctx, cancel := context.WithTimeout(ctx, discoverTimeout)
+				defer cancel()
+				if err := elc.DiscoverNode(ctx, d.Address); err != nil {
+					log.Printf("failed to discover %s: %s", d.Address, err)
+				}
+			}()
+			continue
+		}
+		objs := make([]echonetlite.Object, 0, len(d.Objects))
+		for _, s := range d.Objects {
+			// validated by config.Load
+			obj, _ := echonetlite.ParseObject(s)
+			objs = append(objs, obj)
+		}
+		elc.AddNode(d.Address, objs...)
+	}
+}
@@ -6,16 +6,56 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/transport"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/u-one/go-el-controller/echonetlite"
 )
 
 var version string
 
+var configPath = flag.String("config", "", "YAML configuration file, flags given explicitly override it")
+
 var exporterAddr = flag.String("listen-address", ":8083", "The address to listen on for HTTP requests.")
+var capturePath = flag.String("capture", "", "pcapng file to record ECHONET Lite datagrams")
+var staleTimeout = flag.Duration("stale-timeout", echonetlite.DefaultStaleTimeout, "time until a silent node is reported as stale")
+var enableAPI = flag.Bool("api", false, "serve REST/JSON API to read and write properties at /api/")
+var mqttBroker = flag.String("mqtt-broker", "", "MQTT broker (host:port) to publish properties to, disabled if empty")
+var exportProperties = flag.Bool("properties", false, "export all numeric and enumerated properties of discovered devices as echonet_property_* metrics")
+var includeRules, excludeRules ruleFlag
+
+func init() {
+	flag.Var(&includeRules, "include", "property rule <class>[:<epc>,...] to export with -properties (e.g. 0130:bb,be), repeatable")
+	flag.Var(&excludeRules, "exclude", "property rule <class>[:<epc>,...] not to export with -properties (e.g. *:9f), repeatable")
+}
+
+// ruleFlag is repeatable flag of property rules
+type ruleFlag []echonetlite.PropertyRule
+
+func (f *ruleFlag) String() string {
+	rules := []string{}
+	for _, r := range *f {
+		rules = append(rules, r.String())
+	}
+	return strings.Join(rules, " ")
+}
+
+func (f *ruleFlag) Set(s string) error {
+	r, err := echonetlite.ParsePropertyRule(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
 
 var (
 	verCounter = prometheus.NewCounterVec(
@@ -31,19 +71,26 @@ var (
 	)
 )
 
-func init() {
-	prometheus.MustRegister(verCounter)
-}
-
 func main() {
 	flag.Parse()
 
 	fmt.Printf("version: %s\n", version)
 	verCounter.WithLabelValues(version).Inc()
 
-	err := echonetlite.PrepareClassDictionary()
+	cfg, err := loadConfig()
 	if err != nil {
 		log.Println(err)
+		return
+	}
+
+	err = echonetlite.PrepareClassDictionary()
+	if err != nil {
+		log.Println(err)
+	}
+	if cfg.Exporter.Manufacturers != "" {
+		if err := echonetlite.LoadManufacturers(cfg.Exporter.Manufacturers); err != nil {
+			log.Printf("failed to load manufacturers: %s", err)
+		}
 	}
 
 	ctx := context.Background()
@@ -51,43 +98,213 @@ func main() {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	elc, err := echonetlite.NewControllerNode()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		verCounter,
+		elc.Collector(),
+	)
+	properties, err := setupProperties(reg, elc, nil, cfg.Exporter.Properties)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	server := http.NewServeMux()
+	server.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	server.Handle("/probe", probeHandler(elc))
+	if cfg.Exporter.API.Enabled {
+		if err := startAPI(ctx, elc, server, cfg.Exporter.API); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+
 	ch := make(chan error)
 	go func() {
 		defer close(ch)
-		server := http.NewServeMux()
-		server.Handle("/metrics", promhttp.Handler())
-		log.Println("startExporter: ", *exporterAddr)
-		http.Handle("/metrics", promhttp.Handler())
+		log.Println("startExporter: ", cfg.Exporter.ListenAddress)
 		select {
-		case ch <- http.ListenAndServe(*exporterAddr, server):
+		case ch <- http.ListenAndServe(cfg.Exporter.ListenAddress, server):
 		case <-ctx.Done():
 		}
 		log.Println("exporter finished")
 	}()
 
-	elc, err := echonetlite.NewControllerNode()
-	if err != nil {
-		log.Println(err)
-		return
+	if cfg.Exporter.Capture != "" {
+		w, err := transport.CreatePcapFile(cfg.Exporter.Capture)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		defer w.Close()
+		elc.Record(w)
 	}
+	elc.StaleTimeout = cfg.Exporter.StaleTimeout
+	elc.EVPermission = evPermission(cfg.Exporter)
 	elc.Start(ctx)
 	defer elc.Close()
+	addDevices(ctx, elc, cfg.Exporter.Devices)
+	if cfg.Exporter.MQTT.Broker != "" {
+		if err := startBridge(ctx, elc, cfg.Exporter.MQTT); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+	if cfg.Exporter.Sinks.Enabled() {
+		if err := startSinks(ctx, reg, cfg.Exporter.Sinks); err != nil {
+			log.Println(err)
+			return
+		}
+	}
 
 	log.Println("start sendLoop")
 
-	func() {
-		t := time.NewTicker(30 * time.Second)
-		defer t.Stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// SIGHUP で置き換えられる設定とプロパティエクスポータ
+	var mu sync.Mutex
+	current := func() (config.Exporter, *echonetlite.PropertyExporter) {
+		mu.Lock()
+		defer mu.Unlock()
+		return cfg.Exporter, properties
+	}
+
+	// 各クラスは設定された間隔ごとに要求する
+	tasks := []struct {
+		class string
+		run   func()
+	}{
+		{"aircon", elc.RequestAirConState},
+		{"storage_battery", elc.RequestStorageBatteryState},
+		{"ev_charger", elc.RequestEVChargerState},
+		{"solar_power", elc.RequestSolarPowerState},
+		{"water_heater", elc.RequestWaterHeaterState},
+		{"sensor", elc.RequestSensorStates},
+		{"distribution_board", func() {
+			elc.RequestDistributionBoardState()
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+			defer cancel()
+			elc.RequestDistributionBoardChannels(ctx)
+		}},
+		{"multi_input_pcs", elc.RequestMultiInputPCSState},
+		{"properties", func() {
+			_, properties := current()
+			if properties == nil {
+				return
+			}
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+			defer cancel()
+			properties.Poll(ctx)
+		}},
+		{"default", func() {
+			e, _ := current()
+			addDevices(ctx, elc, e.Devices)
+			for _, n := range elc.CheckStale() {
+				log.Printf("node %s (%s) is stale, last seen at %s", n.ID(), n.Address, n.LastSeen.Format(time.RFC3339))
+			}
+		}},
+	}
+
+	// 時間のかかるタスクが他のタスクと設定の再読み込みを遅らせないよう、それぞれ別の goroutine で動かす
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		task := task
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runTask(ctx, func() time.Duration {
+				e, _ := current()
+				return e.Interval(task.class)
+			}, task.run)
+		}()
+	}
 
+	func() {
 		for {
 			select {
-			case <-t.C:
-				elc.RequestAirConState()
+			case <-hup:
+				next, err := loadConfig()
+				if err != nil {
+					log.Printf("failed to reload config, previous config is kept: %s", err)
+					break
+				}
+				warnNotReloaded(cfg.Exporter, next.Exporter)
+				p, err := setupProperties(reg, elc, properties, next.Exporter.Properties)
+				if err != nil {
+					log.Printf("failed to reload properties: %s", err)
+				}
+				elc.SetStaleTimeout(next.Exporter.StaleTimeout)
+				// 再起動が必要な設定は元のまま使う
+				next.Exporter.ListenAddress = cfg.Exporter.ListenAddress
+				next.Exporter.Capture = cfg.Exporter.Capture
+				next.Exporter.MQTT = cfg.Exporter.MQTT
+				next.Exporter.API = cfg.Exporter.API
+				next.Exporter.Sinks = cfg.Exporter.Sinks
+				mu.Lock()
+				cfg, properties = next, p
+				mu.Unlock()
+				log.Println("config reloaded")
+				// 追加された機器は default タスクを待たずに登録する
+				go addDevices(ctx, elc, next.Exporter.Devices)
 			case <-ctx.Done():
 				return
 			}
 		}
 	}()
+	wg.Wait()
 
 	log.Println("finished")
 }
+
+// runTask runs task every interval, which is read on each tick to apply reloaded configuration
+func runTask(ctx context.Context, interval func() time.Duration, task func()) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case now := <-t.C:
+			if now.Sub(last) < interval() {
+				continue
+			}
+			last = now
+			task()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// setupProperties creates, updates or removes property exporter in reg for configuration p
+func setupProperties(reg *prometheus.Registry, elc *echonetlite.ControllerNode, e *echonetlite.PropertyExporter, p config.Properties) (*echonetlite.PropertyExporter, error) {
+	if !p.Enabled {
+		if e != nil {
+			reg.Unregister(e)
+		}
+		return nil, nil
+	}
+	filter, err := p.Filter()
+	if err != nil {
+		return e, err
+	}
+	if e != nil {
+		e.SetFilter(filter)
+		return e, nil
+	}
+	e = echonetlite.NewPropertyExporter(elc, echonetlite.GetClassDictionary(), filter)
+	if err := reg.Register(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
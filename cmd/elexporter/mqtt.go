@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/matsuu/go-el-controller/bridge"
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+// discoveryInterval is interval to announce newly found devices to Home Assistant
+const discoveryInterval = time.Minute
+
+// startBridge publishes properties received by elc to MQTT broker in background until ctx is done.
+// Devices are announced to Home Assistant if discovery is enabled.
+func startBridge(ctx context.Context, elc *echonetlite.ControllerNode, m config.MQTT) error {
+	opts, err := bridgeOptions(m)
+	if err != nil {
+		return err
+	}
+	s := bridge.NewSession(opts)
+	b := bridge.NewControllerBridge(elc, echonetlite.GetClassDictionary(), s)
+	go s.Run(ctx)
+	go b.Run(ctx)
+	if m.Discovery {
+		go b.RunDiscovery(ctx, bridge.NewDiscovery(s, m.DiscoveryPrefix), discoveryInterval)
+	}
+	return nil
+}
+
+// bridgeOptions returns options of MQTT session, with password from environment variable or file if set
+func bridgeOptions(m config.MQTT) (bridge.Options, error) {
+	pw, err := m.Password()
+	if err != nil {
+		return bridge.Options{}, err
+	}
+	return bridge.Options{
+		MQTT:         mqtt.Options{Broker: m.Broker, ClientID: m.ClientID, Username: m.Username, Password: pw},
+		Prefix:       m.Prefix,
+		QoS:          m.QoS,
+		Retain:       m.Retain,
+		EVPermission: mqttEVPermission(m),
+	}, nil
+}
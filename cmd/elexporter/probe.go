@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// defaultProbeTimeout is used if Prometheus does not send scrape timeout
+	defaultProbeTimeout = 10 * time.Second
+	// probeTimeoutOffset is subtracted from scrape timeout so that metrics are returned before Prometheus gives up
+	probeTimeoutOffset = 500 * time.Millisecond
+)
+
+// probeHandler serves /probe?target=<ip>&eoj=<eoj>&epc=<epc>,... which reads properties of a device for each scrape.
+// Properties in Get property map are read if epc is omitted.
+func probeHandler(elc *echonetlite.ControllerNode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		target := params.Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		obj, err := echonetlite.ParseObject(params.Get("eoj"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		codes, err := echonetlite.ParsePropertyCodes(params.Get("epc"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		timeout, err := probeTimeout(r.Header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "whether the probe succeeded",
+		})
+		probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "how many seconds the probe took",
+		})
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(probeSuccess, probeDuration)
+
+		start := time.Now()
+		c, err := echonetlite.Probe(ctx, elc, echonetlite.GetClassDictionary(), target, obj, codes)
+		probeDuration.Set(time.Since(start).Seconds())
+		if err != nil {
+			log.Printf("probe %s [%s] failed: %s", target, obj, err)
+		} else {
+			probeSuccess.Set(1)
+			reg.MustRegister(c)
+		}
+
+		promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+// probeTimeout returns timeout for a probe from scrape timeout header sent by Prometheus
+func probeTimeout(h http.Header) (time.Duration, error) {
+	v := h.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if v == "" {
+		return defaultProbeTimeout, nil
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timeout from Prometheus header: %s", err)
+	}
+	timeout := time.Duration(seconds*float64(time.Second)) - probeTimeoutOffset
+	if timeout <= 0 {
+		return 0, fmt.Errorf("scrape timeout is too short: %s", v)
+	}
+	return timeout, nil
+}
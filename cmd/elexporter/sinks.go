@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/sink"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// startSinks writes metrics in g to InfluxDB and files in background until ctx is done
+func startSinks(ctx context.Context, g prometheus.Gatherer, s config.Sinks) error {
+	e := sink.NewExporter(g, s.Metrics)
+	if s.InfluxDB.URL != "" {
+		opts, err := influxDBOptions(s.InfluxDB)
+		if err != nil {
+			return err
+		}
+		db, err := sink.NewInfluxDB(opts)
+		if err != nil {
+			return err
+		}
+		e.Add("InfluxDB", db, s.Buffer)
+	}
+	for _, f := range s.Files {
+		fs, err := sink.OpenFile(fileOptions(f))
+		if err != nil {
+			e.Close()
+			return err
+		}
+		e.Add(f.Path, fs, s.Buffer)
+	}
+	go e.Run(ctx, s.Interval)
+	return nil
+}
+
+// influxDBOptions returns options of InfluxDB sink, with token from environment variable or file if set
+func influxDBOptions(i config.InfluxDB) (sink.InfluxDBOptions, error) {
+	token, err := i.Token()
+	if err != nil {
+		return sink.InfluxDBOptions{}, err
+	}
+	return sink.InfluxDBOptions{URL: i.URL, Org: i.Org, Bucket: i.Bucket, Token: token}, nil
+}
+
+// fileOptions returns options of file sink
+func fileOptions(f config.File) sink.FileOptions {
+	return sink.FileOptions{Path: f.Path, Format: f.Format, MaxSize: int64(f.MaxSizeMB) << 20, Daily: f.Daily, Keep: f.Keep}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"reflect"
+
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/transport"
+	"github.com/matsuu/go-el-controller/wisun"
+)
+
+// loadConfig reads configuration file given by -config, and flags given explicitly override it
+func loadConfig() (config.Config, error) {
+	cfg := config.Default()
+	if *configPath != "" {
+		var err error
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			return cfg, err
+		}
+	}
+
+	s := &cfg.SmartMeter
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "module":
+			s.Module = *module
+		case "serial-port":
+			s.Serial.Port = *serialPort
+		case "exporter-port":
+			s.ListenAddress = ":" + *exporterPort
+		case "mqtt-broker":
+			s.MQTT.Broker = *mqttBroker
+		case "interval":
+			s.Interval = *updateInterval
+		}
+	})
+	return cfg, cfg.Validate()
+}
+
+// credentials returns B-route ID and password from flags, environment variables or files
+func credentials(s config.SmartMeter) (string, string, error) {
+	id, pw := *bRouteID, *bRoutePW
+	if pw != "" {
+		log.Printf("-broutepw is deprecated because the password is visible in the process list, use %s or broute_password_file instead", config.EnvBRoutePassword)
+	}
+	if id != "" && pw != "" {
+		return id, pw, nil
+	}
+	cid, cpw, err := s.Credentials()
+	if err != nil {
+		return "", "", err
+	}
+	if id == "" {
+		id = cid
+	}
+	if pw == "" {
+		pw = cpw
+	}
+	return id, pw, nil
+}
+
+// newClient opens serial port and returns client of the configured module
+func newClient(s config.SmartMeter) (wisun.Client, error) {
+	port, err := transport.OpenSerialWithBaudRate(s.Serial.Port, s.Serial.BaudRate)
+	if err != nil {
+		return nil, err
+	}
+	c, err := wisun.NewClient(s.Module, port)
+	if err != nil {
+		port.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// warnNotReloaded logs settings which need restart to be changed
+func warnNotReloaded(prev, next config.SmartMeter) {
+	if prev.ListenAddress != next.ListenAddress {
+		log.Printf("listen_address is changed to %s, restart is needed to apply it", next.ListenAddress)
+	}
+	if prev.Module != next.Module || prev.Serial != next.Serial {
+		log.Println("module or serial is changed, restart is needed to apply it")
+	}
+	if prev.BRouteIDFile != next.BRouteIDFile || prev.BRoutePasswordFile != next.BRoutePasswordFile {
+		log.Println("B-route credentials are changed, restart is needed to apply them")
+	}
+	if prev.MQTT != next.MQTT {
+		log.Println("mqtt is changed, restart is needed to apply it")
+	}
+	if !reflect.DeepEqual(prev.Sinks, next.Sinks) {
+		log.Println("sinks are changed, restart is needed to apply them")
+	}
+}
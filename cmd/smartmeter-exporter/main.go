@@ -14,14 +14,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/matsuu/go-el-controller/echonetlite"
-	"github.com/matsuu/go-el-controller/wisun"
 )
 
 var version string
+var configPath = flag.String("config", "", "YAML configuration file, flags given explicitly override it")
 var bRouteID = flag.String("brouteid", "", "B-route ID")
-var bRoutePW = flag.String("broutepw", "", "B-route password")
-var serialPort = flag.String("serial-port", "/dev/ttyS1", "serial port for RL7023")
+var bRoutePW = flag.String("broutepw", "", "B-route password (deprecated, use SMARTMETER_BROUTE_PASSWORD or broute_password_file)")
+var module = flag.String("module", "rl7023", "Wi-SUN module (rl7023, bp35c2, auto)")
+var serialPort = flag.String("serial-port", "/dev/ttyS1", "serial port for Wi-SUN module")
 var exporterPort = flag.String("exporter-port", "8080", "address for prometheus")
+var mqttBroker = flag.String("mqtt-broker", "", "MQTT broker (host:port) to publish readings to, disabled if empty")
 var updateInterval = flag.Duration("interval", 1*time.Minute, "interval to get data from smart-meter")
 
 var (
@@ -38,10 +40,6 @@ var (
 	)
 )
 
-func init() {
-	prometheus.MustRegister(verCounter)
-}
-
 func main() {
 	flag.Parse()
 	err := run()
@@ -51,22 +49,33 @@ func main() {
 }
 
 func run() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	id, pw, err := credentials(cfg.SmartMeter)
+	if err != nil {
+		return err
+	}
 
-	fmt.Printf("version: %s serial-port:%s exporter-port:%s\n", version, *serialPort, *exporterPort)
+	fmt.Printf("version: %s module:%s serial-port:%s listen-address:%s\n", version, cfg.SmartMeter.Module, cfg.SmartMeter.Serial.Port, cfg.SmartMeter.ListenAddress)
 	verCounter.WithLabelValues(version).Inc()
 
-	err := echonetlite.PrepareClassDictionary()
+	err = echonetlite.PrepareClassDictionary()
 	if err != nil {
 		log.Println(err)
 	}
 
-	wisunClient := wisun.NewRL7023Client(*serialPort)
+	wisunClient, err := newClient(cfg.SmartMeter)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", cfg.SmartMeter.Serial.Port, err)
+	}
 	node := echonetlite.NewElectricityControllerNode(wisunClient)
 
 	ctx := context.Background()
 	initCtx, cancel := context.WithTimeout(ctx, 300*time.Second)
 
-	err = node.Start(initCtx, *bRouteID, *bRoutePW)
+	err = node.Start(initCtx, id, pw)
 	// err != nilでもコネクションを張ってるのでこの位置でClose
 	defer node.Close()
 	if err != nil {
@@ -77,26 +86,45 @@ func run() error {
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		verCounter,
+		node.Collector(),
+	)
+
+	if cfg.SmartMeter.MQTT.Broker != "" {
+		if err := startBridge(ctx, node, cfg.SmartMeter.MQTT); err != nil {
+			return err
+		}
+	}
+	if cfg.SmartMeter.Sinks.Enabled() {
+		if err := startSinks(ctx, reg, cfg.SmartMeter.Sinks); err != nil {
+			return err
+		}
+	}
+
 	// Start prometheus exporter
 	ch := make(chan error)
 	go func() {
 		defer close(ch)
 		server := http.NewServeMux()
-		server.Handle("/metrics", promhttp.Handler())
-		log.Println("start exporter: ", *exporterPort)
+		server.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		log.Println("start exporter: ", cfg.SmartMeter.ListenAddress)
 		select {
-		case ch <- http.ListenAndServe(":"+*exporterPort, server):
+		case ch <- http.ListenAndServe(cfg.SmartMeter.ListenAddress, server):
 		case <-ctx.Done():
 		}
 		log.Println("exporter finished")
 	}()
 
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	func() {
-		t := time.NewTicker(*updateInterval)
-		defer t.Stop()
+		t := time.NewTicker(cfg.SmartMeter.Interval)
+		defer func() { t.Stop() }()
 
 		for {
 			select {
@@ -105,9 +133,32 @@ func run() error {
 				if err != nil {
 					log.Println(err)
 				}
+				_, err = node.GetCumulativeEnergy()
+				if err != nil {
+					log.Println(err)
+				}
+				_, err = node.GetReverseCumulativeEnergy()
+				if err != nil {
+					log.Println(err)
+				}
 			case <-ctx.Done():
 				return
 			case sig := <-sigCh:
+				if sig == syscall.SIGHUP {
+					next, err := loadConfig()
+					if err != nil {
+						log.Printf("failed to reload config, previous config is kept: %s", err)
+						break
+					}
+					warnNotReloaded(cfg.SmartMeter, next.SmartMeter)
+					if next.SmartMeter.Interval != cfg.SmartMeter.Interval {
+						t.Stop()
+						t = time.NewTicker(next.SmartMeter.Interval)
+					}
+					cfg.SmartMeter.Interval = next.SmartMeter.Interval
+					log.Println("config reloaded")
+					break
+				}
 				log.Println("Signal received:", sig)
 				return
 			}
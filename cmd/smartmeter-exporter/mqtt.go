@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/matsuu/go-el-controller/bridge"
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/mqtt"
+)
+
+// startBridge publishes readings of node to MQTT broker in background until ctx is done.
+// Sensors are announced to Home Assistant if discovery is enabled.
+func startBridge(ctx context.Context, node *echonetlite.ElectricityControllerNode, m config.MQTT) error {
+	opts, err := bridgeOptions(m)
+	if err != nil {
+		return err
+	}
+	s := bridge.NewSession(opts)
+	b := bridge.NewSmartMeterBridge(node, s)
+	if m.Discovery {
+		bridge.NewDiscovery(s, m.DiscoveryPrefix).Add(ctx, b.Entities()...)
+	}
+	go s.Run(ctx)
+	go b.Run(ctx)
+	return nil
+}
+
+// bridgeOptions returns options of MQTT session, with password from environment variable or file if set
+func bridgeOptions(m config.MQTT) (bridge.Options, error) {
+	pw, err := m.Password()
+	if err != nil {
+		return bridge.Options{}, err
+	}
+	return bridge.Options{
+		MQTT:   mqtt.Options{Broker: m.Broker, ClientID: m.ClientID, Username: m.Username, Password: pw},
+		Prefix: m.Prefix,
+		QoS:    m.QoS,
+		Retain: m.Retain,
+	}, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/matsuu/go-el-controller/config"
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/transport"
+	"github.com/matsuu/go-el-controller/wisun"
+)
+
+var version string
+
+var (
+	configPath = flag.String("config", "", "YAML configuration file to read B-route ID and password files from smartmeter section")
+	bRouteID   = flag.String("brouteid", "", "B-route ID")
+	bRoutePW   = flag.String("broutepw", "", "B-route password (deprecated, use SMARTMETER_BROUTE_PASSWORD or broute_password_file)")
+	serialPort = flag.String("serial-port", "/dev/ttyS1", "serial port for Wi-SUN module")
+	module     = flag.String("module", "rl7023", "Wi-SUN module (bp35c2, rl7023, auto)")
+	scanTime   = flag.Duration("scan-timeout", 300*time.Second, "time limit to scan smart-meter")
+	jsonOutput = flag.Bool("json", false, "print results in JSON")
+	verbose    = flag.Bool("v", false, "print logs")
+)
+
+// Exit codes for each stage
+const (
+	exitOK = iota
+	exitUsage
+	exitOpen
+	exitVersion
+	exitCredential
+	exitScan
+	exitJoin
+	exitRead
+)
+
+// client is Wi-SUN module client
+type client interface {
+	Version() (string, error)
+	SetBRoutePassword(password string) error
+	SetBRouteID(id string) error
+	Scan(ctx context.Context) (wisun.PanDesc, error)
+	LL64(addr string) (string, error)
+	SRegS2(channel string) error
+	SRegS3(panID string) error
+	Join(desc wisun.PanDesc) (bool, error)
+	Connect(ctx context.Context, bRouteID, bRoutePW string) error
+	Send(data []byte) ([]byte, error)
+	Close()
+}
+
+type scanResult struct {
+	Addr     string  `json:"addr"`
+	IPV6Addr string  `json:"ipv6_addr"`
+	Channel  string  `json:"channel"`
+	PanID    string  `json:"pan_id"`
+	LQI      int     `json:"lqi"`
+	RSSI     float64 `json:"rssi"`
+}
+
+type readResult struct {
+	InstantPower     *int     `json:"instant_power_w,omitempty"`
+	CurrentR         *float64 `json:"current_r_a,omitempty"`
+	CurrentT         *float64 `json:"current_t_a,omitempty"`
+	CumulativeEnergy *float64 `json:"cumulative_energy_kwh,omitempty"`
+}
+
+type result struct {
+	Module  string      `json:"module"`
+	Version string      `json:"version,omitempty"`
+	Scan    *scanResult `json:"scan,omitempty"`
+	Joined  bool        `json:"joined"`
+	Read    *readResult `json:"read,omitempty"`
+	Stage   string      `json:"stage,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// stageError is error with the stage where it occurred
+type stageError struct {
+	stage string
+	code  int
+	err   error
+}
+
+func (e *stageError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.stage, e.err)
+}
+
+func fail(stage string, code int, err error) error {
+	return &stageError{stage: stage, code: code, err: err}
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: smartmeter [flags]\n\nConnects to smart-meter through B-route and reads values once.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		echonetlite.SetLogOutput(ioutil.Discard)
+	}
+	log.Printf("smartmeter version: %s", version)
+
+	res := &result{Module: *module}
+	err := run(res)
+	code := exitOK
+	if err != nil {
+		code = exitUsage
+		if serr, ok := err.(*stageError); ok {
+			res.Stage = serr.stage
+			code = serr.code
+		}
+		res.Error = err.Error()
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(res)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(code)
+}
+
+func open(res *result) (client, error) {
+	switch *module {
+	case wisun.ModuleBP35C2:
+		return wisun.OpenBP35C2Client(*serialPort)
+	case wisun.ModuleRL7023:
+		return wisun.OpenRL7023Client(*serialPort)
+	case wisun.ModuleAuto:
+		s, err := transport.OpenSerial(*serialPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open serial [%s]: %w", *serialPort, err)
+		}
+		info, err := wisun.DetectModule(s)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to detect module: %w", err)
+		}
+		res.Module = info.Module
+		printf("detected: %s (version:%s addr64:%s ascii:%v)\n", info.Module, info.Version, info.Addr64, info.ASCII)
+		if info.Module == wisun.ModuleBP35C2 {
+			return wisun.NewBP35C2ClientWithSerial(s), nil
+		}
+		return wisun.NewRL7023ClientWithSerial(s), nil
+	}
+	return nil, fmt.Errorf("unknown module: %s", *module)
+}
+
+func printf(format string, a ...interface{}) {
+	if !*jsonOutput {
+		fmt.Printf(format, a...)
+	}
+}
+
+// credentials returns B-route ID and password from flags, environment variables or files in the configuration
+func credentials() (string, string, error) {
+	id, pw := *bRouteID, *bRoutePW
+	if pw != "" {
+		fmt.Fprintf(os.Stderr, "-broutepw is deprecated because the password is visible in the process list, use %s or broute_password_file instead\n", config.EnvBRoutePassword)
+	}
+	if id != "" && pw != "" {
+		return id, pw, nil
+	}
+	s := config.Default().SmartMeter
+	if *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return "", "", err
+		}
+		s = cfg.SmartMeter
+	}
+	cid, cpw, err := s.Credentials()
+	if err != nil {
+		return "", "", err
+	}
+	if id == "" {
+		id = cid
+	}
+	if pw == "" {
+		pw = cpw
+	}
+	return id, pw, nil
+}
+
+func run(res *result) error {
+	id, pw, err := credentials()
+	if err != nil {
+		return err
+	}
+	if *module != wisun.ModuleBP35C2 && *module != wisun.ModuleRL7023 && *module != wisun.ModuleAuto {
+		return fmt.Errorf("unknown module: %s", *module)
+	}
+
+	c, err := open(res)
+	if err != nil {
+		return fail("open", exitOpen, err)
+	}
+	defer c.Close()
+
+	ver, err := c.Version()
+	if err != nil {
+		return fail("version", exitVersion, err)
+	}
+	res.Version = ver
+	printf("version: %s\n", ver)
+
+	err = c.SetBRoutePassword(pw)
+	if err != nil {
+		return fail("credential", exitCredential, err)
+	}
+	err = c.SetBRouteID(id)
+	if err != nil {
+		return fail("credential", exitCredential, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *scanTime)
+	defer cancel()
+	pd, err := c.Scan(ctx)
+	if err != nil {
+		return fail("scan", exitScan, err)
+	}
+	if pd.Addr == "" {
+		return fail("scan", exitScan, fmt.Errorf("smart-meter not found"))
+	}
+	pd.IPV6Addr, err = c.LL64(pd.Addr)
+	if err != nil {
+		return fail("scan", exitScan, err)
+	}
+	res.Scan = &scanResult{
+		Addr:     pd.Addr,
+		IPV6Addr: pd.IPV6Addr,
+		Channel:  pd.Channel,
+		PanID:    pd.PanID,
+		LQI:      pd.LQI,
+		RSSI:     pd.RSSI(),
+	}
+	printf("scan: addr:%s ipv6:%s channel:%s panid:%s lqi:%d (%.1fdBm)\n", pd.Addr, pd.IPV6Addr, pd.Channel, pd.PanID, pd.LQI, pd.RSSI())
+
+	err = c.SRegS2(pd.Channel)
+	if err != nil {
+		return fail("join", exitJoin, err)
+	}
+	err = c.SRegS3(pd.PanID)
+	if err != nil {
+		return fail("join", exitJoin, err)
+	}
+	joined, err := c.Join(pd)
+	if err != nil {
+		return fail("join", exitJoin, err)
+	}
+	if !joined {
+		return fail("join", exitJoin, fmt.Errorf("PANA authentication rejected"))
+	}
+	res.Joined = true
+	printf("join: succeeded\n")
+
+	node := echonetlite.NewElectricityControllerNode(c)
+	res.Read = &readResult{}
+
+	power, err := node.GetPowerConsumption()
+	if err != nil {
+		return fail("read", exitRead, fmt.Errorf("instant power: %w", err))
+	}
+	res.Read.InstantPower = &power
+	printf("instant power: %d W\n", power)
+
+	r, t, err := node.GetInstantCurrent()
+	if err != nil {
+		return fail("read", exitRead, fmt.Errorf("instant current: %w", err))
+	}
+	res.Read.CurrentR, res.Read.CurrentT = &r, &t
+	printf("instant current: R:%.1f A T:%.1f A\n", r, t)
+
+	energy, err := node.GetCumulativeEnergy()
+	if err != nil {
+		return fail("read", exitRead, fmt.Errorf("cumulative energy: %w", err))
+	}
+	res.Read.CumulativeEnergy = &energy
+	printf("cumulative energy: %.3f kWh\n", energy)
+
+	return nil
+}
@@ -0,0 +1,498 @@
+// Package config loads configuration file of elexporter and smartmeter-exporter
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultInterval is polling interval of classes which are not configured
+	DefaultInterval = 30 * time.Second
+	// DefaultSmartMeterInterval is polling interval of smart-meter
+	DefaultSmartMeterInterval = time.Minute
+	// DefaultSinkInterval is interval to write metrics to sinks
+	DefaultSinkInterval = time.Minute
+	// DefaultSinkBuffer is number of points kept for each sink while it is unavailable
+	DefaultSinkBuffer = 100000
+	// DefaultBaudRate is baud rate of serial port of Wi-SUN module
+	DefaultBaudRate = 115200
+	// DefaultDiscoveryPrefix is discovery prefix of Home Assistant
+	DefaultDiscoveryPrefix = "homeassistant"
+
+	// EnvBRouteID is environment variable for B-route ID
+	EnvBRouteID = "SMARTMETER_BROUTE_ID"
+	// EnvBRoutePassword is environment variable for B-route password
+	EnvBRoutePassword = "SMARTMETER_BROUTE_PASSWORD"
+	// EnvMQTTPassword is environment variable for password of MQTT broker
+	EnvMQTTPassword = "MQTT_PASSWORD"
+	// EnvAPIToken is environment variable for token to set properties through REST/JSON API
+	EnvAPIToken = "ELEXPORTER_API_TOKEN"
+	// EnvInfluxDBToken is environment variable for API token of InfluxDB
+	EnvInfluxDBToken = "INFLUXDB_TOKEN"
+)
+
+// Classes is names of classes whose polling interval can be configured in elexporter.intervals.
+// "default" is used for classes which are not configured.
+var Classes = []string{
+	"aircon",
+	"storage_battery",
+	"ev_charger",
+	"solar_power",
+	"water_heater",
+	"sensor",
+	"distribution_board",
+	"multi_input_pcs",
+	"properties",
+}
+
+// Modules is names of supported Wi-SUN modules, "auto" detects the module on start
+var Modules = []string{"rl7023", "bp35c2", "auto"}
+
+// FileFormats is formats of file sink
+var FileFormats = []string{"csv", "jsonl"}
+
+// Config is configuration of both exporters
+type Config struct {
+	Exporter   Exporter   `yaml:"elexporter"`
+	SmartMeter SmartMeter `yaml:"smartmeter"`
+}
+
+// Exporter is configuration of elexporter
+type Exporter struct {
+	ListenAddress string                   `yaml:"listen_address"`
+	Capture       string                   `yaml:"capture"`
+	Manufacturers string                   `yaml:"manufacturers"` // CSV file of manufacturer codes and names
+	StaleTimeout  time.Duration            `yaml:"stale_timeout"`
+	Intervals     map[string]time.Duration `yaml:"intervals"`
+	Devices       []Device                 `yaml:"devices"`
+	Properties    Properties               `yaml:"properties"`
+	MQTT          MQTT                     `yaml:"mqtt"`
+	API           API                      `yaml:"api"`
+	Sinks         Sinks                    `yaml:"sinks"`
+}
+
+// Device is a node which is polled without discovery by multicast.
+// Instance list of the node is requested if Objects is empty.
+type Device struct {
+	Address string   `yaml:"address"`
+	Objects []string `yaml:"objects"` // EOJ in hex such as "013001"
+}
+
+// Properties is configuration of dictionary-driven property exporter
+type Properties struct {
+	Enabled bool     `yaml:"enabled"`
+	Include []string `yaml:"include"` // rules in format "<class>[:<epc>,...]"
+	Exclude []string `yaml:"exclude"`
+}
+
+// SmartMeter is configuration of smartmeter-exporter.
+// B-route credentials are read from environment variables or files, not to be written in the configuration file.
+type SmartMeter struct {
+	ListenAddress      string        `yaml:"listen_address"`
+	Interval           time.Duration `yaml:"interval"`
+	Module             string        `yaml:"module"`
+	Serial             Serial        `yaml:"serial"`
+	BRouteIDFile       string        `yaml:"broute_id_file"`
+	BRoutePasswordFile string        `yaml:"broute_password_file"`
+	MQTT               MQTT          `yaml:"mqtt"`
+	Sinks              Sinks         `yaml:"sinks"`
+}
+
+// MQTT is configuration of MQTT bridge, which is disabled if Broker is empty.
+// Password is read from environment variable or file like B-route credentials.
+type MQTT struct {
+	Broker       string `yaml:"broker"` // host:port with optional tcp:// prefix
+	ClientID     string `yaml:"client_id"`
+	Username     string `yaml:"username"`
+	PasswordFile string `yaml:"password_file"`
+	Prefix       string `yaml:"prefix"`
+	QoS          byte   `yaml:"qos"`
+	Retain       bool   `yaml:"retain"`
+
+	// Home Assistant MQTT discovery
+	Discovery       bool   `yaml:"discovery"`
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+
+	// set to EV charger/discharger through MQTT, which is refused by default
+	AllowEVCharge    bool `yaml:"allow_ev_charge"`
+	AllowEVDischarge bool `yaml:"allow_ev_discharge"`
+}
+
+// API is configuration of REST/JSON API at /api/.
+// Token is read from environment variable or file like B-route credentials, and required to set properties.
+type API struct {
+	Enabled       bool   `yaml:"enabled"`
+	ListenAddress string `yaml:"listen_address"` // served with /metrics if empty
+	TokenFile     string `yaml:"token_file"`
+
+	// set to EV charger/discharger through API, which is refused by default
+	AllowEVCharge    bool `yaml:"allow_ev_charge"`
+	AllowEVDischarge bool `yaml:"allow_ev_discharge"`
+}
+
+// Sinks is configuration of time-series outputs of metrics, which are disabled if neither InfluxDB nor files are set
+type Sinks struct {
+	Interval time.Duration `yaml:"interval"`
+	Metrics  []string      `yaml:"metrics"` // name prefixes of metrics, all but runtime metrics if empty
+	Buffer   int           `yaml:"buffer"`  // points kept for each sink while it is unavailable
+	InfluxDB InfluxDB      `yaml:"influxdb"`
+	Files    []File        `yaml:"files"`
+}
+
+// InfluxDB is configuration of InfluxDB sink, which is disabled if URL is empty.
+// Token is read from environment variable or file like B-route credentials.
+type InfluxDB struct {
+	URL       string `yaml:"url"`
+	Org       string `yaml:"org"`
+	Bucket    string `yaml:"bucket"` // "<database>/<retention policy>" for InfluxDB 1.8
+	TokenFile string `yaml:"token_file"`
+}
+
+// File is configuration of file sink
+type File struct {
+	Path      string `yaml:"path"`
+	Format    string `yaml:"format"`      // csv or jsonl
+	MaxSizeMB int    `yaml:"max_size_mb"` // not rotated by size if 0
+	Daily     bool   `yaml:"daily"`
+	Keep      int    `yaml:"keep"` // rotated files to keep, all files are kept if 0
+}
+
+// Serial is configuration of serial port of Wi-SUN module
+type Serial struct {
+	Port     string `yaml:"port"`
+	BaudRate int    `yaml:"baud_rate"`
+}
+
+// Default returns configuration used without configuration file
+func Default() Config {
+	return Config{
+		Exporter: Exporter{
+			ListenAddress: ":8083",
+			StaleTimeout:  echonetlite.DefaultStaleTimeout,
+			MQTT:          MQTT{ClientID: "elexporter", Prefix: "echonetlite", Retain: true, DiscoveryPrefix: DefaultDiscoveryPrefix},
+			Sinks:         Sinks{Interval: DefaultSinkInterval, Buffer: DefaultSinkBuffer},
+		},
+		SmartMeter: SmartMeter{
+			ListenAddress: ":8080",
+			Interval:      DefaultSmartMeterInterval,
+			Module:        "rl7023",
+			Serial:        Serial{Port: "/dev/ttyS1", BaudRate: DefaultBaudRate},
+			MQTT:          MQTT{ClientID: "smartmeter-exporter", Prefix: "smartmeter", Retain: true, DiscoveryPrefix: DefaultDiscoveryPrefix},
+			Sinks:         Sinks{Interval: DefaultSinkInterval, Buffer: DefaultSinkBuffer},
+		},
+	}
+}
+
+// Load reads configuration file at path over default values and validates it
+func Load(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses configuration in YAML over default values and validates it.
+// Unknown keys are errors to find typos.
+func Parse(data []byte) (Config, error) {
+	c := Default()
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// ValidationError has all problems found in configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate returns *ValidationError if c has invalid values
+func (c Config) Validate() error {
+	problems := []string{}
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	e := c.Exporter
+	if err := validateAddress(e.ListenAddress); err != nil {
+		add("elexporter.listen_address: %s", err)
+	}
+	if e.StaleTimeout <= 0 {
+		add("elexporter.stale_timeout: must be positive")
+	}
+	names := []string{}
+	for name := range e.Intervals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name != "default" && !contains(Classes, name) {
+			add("elexporter.intervals.%s: unknown class, one of default, %s is expected", name, strings.Join(Classes, ", "))
+		}
+		if e.Intervals[name] <= 0 {
+			add("elexporter.intervals.%s: must be positive", name)
+		}
+	}
+	for i, d := range e.Devices {
+		if net.ParseIP(d.Address) == nil {
+			add("elexporter.devices[%d].address: invalid IP address %q", i, d.Address)
+		}
+		for _, o := range d.Objects {
+			if _, err := echonetlite.ParseObject(o); err != nil {
+				add("elexporter.devices[%d].objects: %s", i, err)
+			}
+		}
+	}
+	for _, r := range e.Properties.Include {
+		if _, err := echonetlite.ParsePropertyRule(r); err != nil {
+			add("elexporter.properties.include: %s", err)
+		}
+	}
+	for _, r := range e.Properties.Exclude {
+		if _, err := echonetlite.ParsePropertyRule(r); err != nil {
+			add("elexporter.properties.exclude: %s", err)
+		}
+	}
+	for _, p := range e.MQTT.validate() {
+		add("elexporter.mqtt.%s", p)
+	}
+	if e.API.ListenAddress != "" {
+		if err := validateAddress(e.API.ListenAddress); err != nil {
+			add("elexporter.api.listen_address: %s", err)
+		}
+	}
+	for _, p := range e.Sinks.validate() {
+		add("elexporter.sinks.%s", p)
+	}
+
+	s := c.SmartMeter
+	if err := validateAddress(s.ListenAddress); err != nil {
+		add("smartmeter.listen_address: %s", err)
+	}
+	if s.Interval <= 0 {
+		add("smartmeter.interval: must be positive")
+	}
+	if !contains(Modules, s.Module) {
+		add("smartmeter.module: unknown module %q, one of %s is expected", s.Module, strings.Join(Modules, ", "))
+	}
+	if s.Serial.Port == "" {
+		add("smartmeter.serial.port: must not be empty")
+	}
+	if s.Serial.BaudRate <= 0 {
+		add("smartmeter.serial.baud_rate: must be positive")
+	}
+	for _, p := range s.MQTT.validate() {
+		add("smartmeter.mqtt.%s", p)
+	}
+	if s.MQTT.AllowEVCharge || s.MQTT.AllowEVDischarge {
+		add("smartmeter.mqtt.allow_ev_charge, allow_ev_discharge: not supported by smartmeter-exporter")
+	}
+	for _, p := range s.Sinks.validate() {
+		add("smartmeter.sinks.%s", p)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validateAddress(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("invalid address %q, [host]:port is expected", addr)
+	}
+	return nil
+}
+
+// validate returns problems of m in format "<key>: <problem>"
+func (m MQTT) validate() []string {
+	if m.Broker == "" {
+		return nil
+	}
+	problems := []string{}
+	if !validPrefix(m.Prefix) {
+		problems = append(problems, fmt.Sprintf("prefix: invalid topic prefix %q", m.Prefix))
+	}
+	if m.Discovery && !validPrefix(m.DiscoveryPrefix) {
+		problems = append(problems, fmt.Sprintf("discovery_prefix: invalid topic prefix %q", m.DiscoveryPrefix))
+	}
+	if m.QoS > 1 {
+		problems = append(problems, fmt.Sprintf("qos: %d is not supported, 0 or 1 is expected", m.QoS))
+	}
+	return problems
+}
+
+// validate returns problems of s in format "<key>: <problem>"
+func (s Sinks) validate() []string {
+	if !s.Enabled() {
+		return nil
+	}
+	problems := []string{}
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+	if s.Interval <= 0 {
+		add("interval: must be positive")
+	}
+	if s.Buffer < 0 {
+		add("buffer: must not be negative")
+	}
+	if s.InfluxDB.URL != "" {
+		if u, err := url.Parse(s.InfluxDB.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("influxdb.url: invalid URL %q, http(s)://host:port is expected", s.InfluxDB.URL)
+		}
+		if s.InfluxDB.Bucket == "" {
+			add("influxdb.bucket: must not be empty")
+		}
+	}
+	for i, f := range s.Files {
+		if f.Path == "" {
+			add("files[%d].path: must not be empty", i)
+		}
+		if !contains(FileFormats, f.Format) {
+			add("files[%d].format: unknown format %q, one of %s is expected", i, f.Format, strings.Join(FileFormats, ", "))
+		}
+		if f.MaxSizeMB < 0 {
+			add("files[%d].max_size_mb: must not be negative", i)
+		}
+		if f.Keep < 0 {
+			add("files[%d].keep: must not be negative", i)
+		}
+	}
+	return problems
+}
+
+func validPrefix(p string) bool {
+	return p != "" && !strings.ContainsAny(p, "+#") && !strings.HasPrefix(p, "/") && !strings.HasSuffix(p, "/")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Interval returns polling interval of class
+func (e Exporter) Interval(class string) time.Duration {
+	if d, ok := e.Intervals[class]; ok {
+		return d
+	}
+	if d, ok := e.Intervals["default"]; ok {
+		return d
+	}
+	return DefaultInterval
+}
+
+// Filter returns filter of property exporter
+func (p Properties) Filter() (echonetlite.PropertyFilter, error) {
+	f := echonetlite.PropertyFilter{}
+	for _, s := range p.Include {
+		r, err := echonetlite.ParsePropertyRule(s)
+		if err != nil {
+			return f, err
+		}
+		f.Include = append(f.Include, r)
+	}
+	for _, s := range p.Exclude {
+		r, err := echonetlite.ParsePropertyRule(s)
+		if err != nil {
+			return f, err
+		}
+		f.Exclude = append(f.Exclude, r)
+	}
+	return f, nil
+}
+
+// Credentials returns B-route ID and password.
+// Environment variables are used if they are set, otherwise they are read from files.
+func (s SmartMeter) Credentials() (id, password string, err error) {
+	id, err = secret(EnvBRouteID, s.BRouteIDFile)
+	if err != nil {
+		return "", "", fmt.Errorf("B-route ID: %w", err)
+	}
+	password, err = secret(EnvBRoutePassword, s.BRoutePasswordFile)
+	if err != nil {
+		return "", "", fmt.Errorf("B-route password: %w", err)
+	}
+	return id, password, nil
+}
+
+// Password returns password of MQTT broker from environment variable or file, or empty string if neither is set
+func (m MQTT) Password() (string, error) {
+	pw, err := optionalSecret(EnvMQTTPassword, m.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("MQTT password: %w", err)
+	}
+	return pw, nil
+}
+
+// Enabled returns true if InfluxDB or files are set
+func (s Sinks) Enabled() bool {
+	return s.InfluxDB.URL != "" || len(s.Files) > 0
+}
+
+// Token returns API token of InfluxDB from environment variable or file, or empty string if neither is set
+func (i InfluxDB) Token() (string, error) {
+	token, err := optionalSecret(EnvInfluxDBToken, i.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("InfluxDB token: %w", err)
+	}
+	return token, nil
+}
+
+// Token returns token to set properties through API from environment variable or file, or empty string if neither is set
+func (a API) Token() (string, error) {
+	token, err := optionalSecret(EnvAPIToken, a.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("API token: %w", err)
+	}
+	return token, nil
+}
+
+// optionalSecret returns secret like secret, or empty string if neither env nor file is set
+func optionalSecret(env, file string) (string, error) {
+	if os.Getenv(env) == "" && file == "" {
+		return "", nil
+	}
+	return secret(env, file)
+}
+
+// secret returns value of environment variable env, or content of file without trailing newline
+func secret(env, file string) (string, error) {
+	if v := os.Getenv(env); v != "" {
+		return v, nil
+	}
+	if file == "" {
+		return "", fmt.Errorf("neither %s nor file is set", env)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	v := strings.TrimRight(string(data), "\r\n")
+	if v == "" {
+		return "", fmt.Errorf("%s is empty", file)
+	}
+	return v, nil
+}
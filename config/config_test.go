@@ -0,0 +1,361 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`
+elexporter:
+  listen_address: ":9100"
+  manufacturers: /etc/elexporter/manufacturers.csv
+  api:
+    enabled: true
+    listen_address: "127.0.0.1:9101"
+    allow_ev_charge: true
+  intervals:
+    default: 1m
+    aircon: 10s
+  devices:
+    - address: 192.168.1.70
+      objects: ["013001"]
+    - address: 192.168.1.71
+  properties:
+    enabled: true
+    include: ["0130:bb,be"]
+smartmeter:
+  module: bp35c2
+  serial:
+    port: /dev/ttyUSB0
+  mqtt:
+    broker: tcp://192.168.1.5:1883
+    qos: 1
+    discovery: true
+  sinks:
+    metrics: ["home_smartmeter_"]
+    influxdb:
+      url: http://localhost:8086
+      bucket: energy
+    files:
+      - path: /var/lib/smartmeter/metrics.csv
+        format: csv
+        max_size_mb: 10
+        daily: true
+        keep: 7
+`)
+	c, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := c.Exporter.API; !a.Enabled || a.ListenAddress != "127.0.0.1:9101" || !a.AllowEVCharge || a.AllowEVDischarge {
+		t.Errorf("api differs: %+v", a)
+	}
+	if c.Exporter.ListenAddress != ":9100" || c.Exporter.Manufacturers != "/etc/elexporter/manufacturers.csv" {
+		t.Errorf("exporter differs: %+v", c.Exporter)
+	}
+	if got := c.Exporter.Interval("aircon"); got != 10*time.Second {
+		t.Errorf("aircon interval differs: %s", got)
+	}
+	if got := c.Exporter.Interval("sensor"); got != time.Minute {
+		t.Errorf("default interval should be used: %s", got)
+	}
+	if c.Exporter.StaleTimeout != echonetlite.DefaultStaleTimeout {
+		t.Errorf("default stale timeout should be kept: %s", c.Exporter.StaleTimeout)
+	}
+	if len(c.Exporter.Devices) != 2 {
+		t.Errorf("devices differ: %v", c.Exporter.Devices)
+	}
+	f, err := c.Exporter.Properties.Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Include) != 1 || f.Include[0].String() != "0130:bb,be" {
+		t.Errorf("include rules differ: %v", f.Include)
+	}
+	s := c.SmartMeter
+	if s.Module != "bp35c2" || s.Serial.Port != "/dev/ttyUSB0" || s.Serial.BaudRate != 115200 || s.Interval != time.Minute {
+		t.Errorf("smartmeter differs: %+v", s)
+	}
+	if m := s.MQTT; m.Broker != "tcp://192.168.1.5:1883" || m.QoS != 1 || m.Prefix != "smartmeter" || !m.Retain || !m.Discovery || m.DiscoveryPrefix != "homeassistant" {
+		t.Errorf("smartmeter mqtt differs: %+v", m)
+	}
+	if c.Exporter.MQTT.Broker != "" {
+		t.Errorf("elexporter mqtt should be disabled: %+v", c.Exporter.MQTT)
+	}
+	if sk := s.Sinks; !sk.Enabled() || sk.Interval != time.Minute || sk.Buffer != 100000 || len(sk.Metrics) != 1 || sk.InfluxDB.Bucket != "energy" || len(sk.Files) != 1 {
+		t.Errorf("smartmeter sinks differ: %+v", sk)
+	}
+	if f := s.Sinks.Files[0]; f.Path != "/var/lib/smartmeter/metrics.csv" || f.MaxSizeMB != 10 || !f.Daily || f.Keep != 7 {
+		t.Errorf("file differs: %+v", f)
+	}
+	if c.Exporter.Sinks.Enabled() {
+		t.Errorf("elexporter sinks should be disabled: %+v", c.Exporter.Sinks)
+	}
+}
+
+func TestParse_Default(t *testing.T) {
+	c, err := Parse([]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Exporter.Interval("aircon"); got != DefaultInterval {
+		t.Errorf("interval differs: %s", got)
+	}
+	if c.SmartMeter.Module != "rl7023" {
+		t.Errorf("module differs: %s", c.SmartMeter.Module)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	testcases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "unknown key",
+			in:   "elexporter:\n  listen_adress: \":8083\"\n",
+			want: []string{"listen_adress"},
+		},
+		{
+			name: "invalid values",
+			in: `
+elexporter:
+  listen_address: "8083"
+  intervals:
+    airconditioner: 10s
+    sensor: 0s
+  devices:
+    - address: example
+      objects: ["0130"]
+  properties:
+    include: ["01"]
+  mqtt:
+    broker: localhost
+    prefix: home/#
+    qos: 2
+    discovery: true
+    discovery_prefix: ""
+  api:
+    listen_address: "9101"
+  sinks:
+    interval: 0s
+    influxdb:
+      url: localhost:8086
+    files:
+      - path: metrics.txt
+        format: txt
+        keep: -1
+smartmeter:
+  module: wsr35a
+  serial:
+    baud_rate: -1
+  mqtt:
+    allow_ev_charge: true
+`,
+			want: []string{
+				"elexporter.listen_address: invalid address \"8083\"",
+				"elexporter.intervals.airconditioner: unknown class",
+				"elexporter.intervals.sensor: must be positive",
+				"elexporter.devices[0].address: invalid IP address \"example\"",
+				"elexporter.devices[0].objects: invalid EOJ \"0130\"",
+				"elexporter.properties.include: invalid rule \"01\": invalid class \"01\"",
+				"elexporter.mqtt.prefix: invalid topic prefix \"home/#\"",
+				"elexporter.mqtt.qos: 2 is not supported",
+				"elexporter.mqtt.discovery_prefix: invalid topic prefix \"\"",
+				"elexporter.api.listen_address: invalid address \"9101\"",
+				"elexporter.sinks.interval: must be positive",
+				"elexporter.sinks.influxdb.url: invalid URL \"localhost:8086\"",
+				"elexporter.sinks.influxdb.bucket: must not be empty",
+				"elexporter.sinks.files[0].format: unknown format \"txt\"",
+				"elexporter.sinks.files[0].keep: must not be negative",
+				"smartmeter.module: unknown module \"wsr35a\"",
+				"smartmeter.serial.baud_rate: must be positive",
+				"smartmeter.mqtt.allow_ev_charge, allow_ev_discharge: not supported",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		_, err := Parse([]byte(tc.in))
+		if err == nil {
+			t.Errorf("%s: error expected", tc.name)
+			continue
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("%s: %q is not in error:\n%s", tc.name, w, err)
+			}
+		}
+	}
+}
+
+func TestSmartMeter_Credentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	idFile := filepath.Join(dir, "id")
+	pwFile := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(idFile, []byte("0000ID\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pwFile, []byte("PASSWORD\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := SmartMeter{BRouteIDFile: idFile, BRoutePasswordFile: pwFile}
+	os.Unsetenv(EnvBRouteID)
+	os.Unsetenv(EnvBRoutePassword)
+
+	id, pw, err := s.Credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "0000ID" || pw != "PASSWORD" {
+		t.Errorf("credentials from files differ: %s %s", id, pw)
+	}
+
+	os.Setenv(EnvBRoutePassword, "FROMENV")
+	defer os.Unsetenv(EnvBRoutePassword)
+	_, pw, err = s.Credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pw != "FROMENV" {
+		t.Errorf("environment variable should be used: %s", pw)
+	}
+
+	if _, _, err := (SmartMeter{}).Credentials(); err == nil || !strings.Contains(err.Error(), EnvBRouteID) {
+		t.Errorf("missing credential should be reported: %v", err)
+	}
+}
+
+func TestMQTT_Password(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pwFile := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(pwFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv(EnvMQTTPassword)
+
+	m := Default().Exporter.MQTT
+	m.Broker = "localhost"
+	if m.ClientID != "elexporter" || m.Prefix != "echonetlite" || !m.Retain || m.AllowEVCharge || m.AllowEVDischarge {
+		t.Errorf("default differs: %+v", m)
+	}
+	pw, err := m.Password()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pw != "" {
+		t.Errorf("password should be empty without file: %q", pw)
+	}
+
+	m.PasswordFile = pwFile
+	pw, err = m.Password()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pw != "secret" {
+		t.Errorf("password from file differs: %q", pw)
+	}
+
+	m.PasswordFile = filepath.Join(dir, "missing")
+	if _, err := m.Password(); err == nil {
+		t.Error("missing password file should be reported")
+	}
+}
+
+func TestInfluxDB_Token(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv(EnvInfluxDBToken)
+
+	i := InfluxDB{URL: "http://localhost:8086", Org: "home", Bucket: "energy"}
+	token, err := i.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		t.Errorf("token should be empty without file: %q", token)
+	}
+
+	i.TokenFile = tokenFile
+	token, err = i.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret" {
+		t.Errorf("token from file differs: %q", token)
+	}
+
+	os.Setenv(EnvInfluxDBToken, "from-env")
+	defer os.Unsetenv(EnvInfluxDBToken)
+	token, err = i.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "from-env" {
+		t.Errorf("token from environment variable differs: %q", token)
+	}
+
+	os.Unsetenv(EnvInfluxDBToken)
+	i.TokenFile = filepath.Join(dir, "missing")
+	if _, err := i.Token(); err == nil {
+		t.Error("missing token file should be reported")
+	}
+}
+
+func TestAPI_Token(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv(EnvAPIToken)
+
+	a := API{Enabled: true}
+	token, err := a.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		t.Errorf("token should be empty without file: %q", token)
+	}
+
+	a.TokenFile = tokenFile
+	token, err = a.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret" {
+		t.Errorf("token from file differs: %q", token)
+	}
+}
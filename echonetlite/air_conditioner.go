@@ -0,0 +1,246 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// AirConditionerMode is operation mode of home air conditioner (0xB0)
+type AirConditionerMode byte
+
+// definition of operation modes
+const (
+	ModeOther AirConditionerMode = 0x40
+	ModeAuto  AirConditionerMode = 0x41
+	ModeCool  AirConditionerMode = 0x42
+	ModeHeat  AirConditionerMode = 0x43
+	ModeDry   AirConditionerMode = 0x44
+	ModeFan   AirConditionerMode = 0x45
+)
+
+func (m AirConditionerMode) String() string {
+	switch m {
+	case ModeOther:
+		return "other"
+	case ModeAuto:
+		return "auto"
+	case ModeCool:
+		return "cool"
+	case ModeHeat:
+		return "heat"
+	case ModeDry:
+		return "dry"
+	case ModeFan:
+		return "fan"
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(m))
+}
+
+// ParseAirConditionerMode returns mode from its name
+func ParseAirConditionerMode(s string) (AirConditionerMode, error) {
+	for _, m := range []AirConditionerMode{ModeAuto, ModeCool, ModeHeat, ModeDry, ModeFan, ModeOther} {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode: %s", s)
+}
+
+// FanSpeed is air flow rate of home air conditioner (0xA0)
+type FanSpeed byte
+
+// FanAuto is automatic air flow rate
+const FanAuto FanSpeed = 0x41
+
+// FanLevel returns FanSpeed of level (1-8)
+func FanLevel(level int) (FanSpeed, error) {
+	if level < 1 || level > 8 {
+		return 0, fmt.Errorf("fan level out of range [1-8]: %d", level)
+	}
+	return FanSpeed(0x30 + level), nil
+}
+
+// Level returns level of fan speed, or 0 if it is automatic
+func (f FanSpeed) Level() int {
+	if f >= 0x31 && f <= 0x38 {
+		return int(f - 0x30)
+	}
+	return 0
+}
+
+func (f FanSpeed) String() string {
+	if f == FanAuto {
+		return "auto"
+	}
+	if l := f.Level(); l != 0 {
+		return fmt.Sprint(l)
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(f))
+}
+
+func (f FanSpeed) valid() bool {
+	return f == FanAuto || f.Level() != 0
+}
+
+// AirConditionerState is state of home air conditioner.
+// Properties which the device doesn't provide are left zero value,
+// and measured values are nil if they are not available or unmeasurable.
+// Temperature is nil if the device doesn't provide it or it is undetermined (0xFD).
+type AirConditionerState struct {
+	On                 bool
+	Mode               AirConditionerMode
+	Temperature        *int // 温度設定値 [℃]
+	FanSpeed           FanSpeed
+	Humidity           int // 除湿モード時相対湿度設定値 [%]
+	PowerSaving        bool
+	RoomTemperature    *float64 // 室内温度計測値 [℃]
+	RoomHumidity       *float64 // 室内相対湿度計測値 [%]
+	OutdoorTemperature *float64 // 外気温度計測値 [℃]
+}
+
+// AirConditioner is client to read and control home air conditioner (0x0130)
+type AirConditioner struct {
+	*Device
+}
+
+// NewAirConditioner returns AirConditioner for the instance at addr
+func NewAirConditioner(elc *ControllerNode, addr string, instance int) *AirConditioner {
+	return &AirConditioner{NewDevice(elc, addr, NewObject(AirConditionerGroup, HomeAirConditioner, instance))}
+}
+
+// State reads current state of the air conditioner
+func (a *AirConditioner) State(ctx context.Context) (AirConditionerState, error) {
+	props, err := a.Get(ctx,
+		OperationStatus, OperationModeSetting, TemperatureSetting, AirFlowRateSetting, DehumidifyingSetting,
+		PowerReductionState, MeasuredRoomTemperature, MeasuredRoomHumidity, MeasuredOutdoorTemperature)
+	if err != nil {
+		return AirConditionerState{}, err
+	}
+
+	state := AirConditionerState{}
+	for _, p := range props {
+		if len(p.Data) != 1 {
+			continue
+		}
+		d := p.Data[0]
+		switch PropertyCode(p.Code) {
+		case OperationStatus:
+			state.On = d == 0x30
+		case OperationModeSetting:
+			state.Mode = AirConditionerMode(d)
+		case TemperatureSetting:
+			// 0xFD は温度設定値が未定 (自動運転など) であることを示す
+			if d != 0xfd {
+				t := int(d)
+				state.Temperature = &t
+			}
+		case AirFlowRateSetting:
+			state.FanSpeed = FanSpeed(d)
+		case DehumidifyingSetting:
+			state.Humidity = int(d)
+		case PowerReductionState:
+			state.PowerSaving = d == 0x41
+		case MeasuredRoomTemperature:
+			state.RoomTemperature = measured(SignedChar, p.Data, 1)
+		case MeasuredRoomHumidity:
+			state.RoomHumidity = measured(UnsignedChar, p.Data, 1)
+		case MeasuredOutdoorTemperature:
+			state.OutdoorTemperature = measured(SignedChar, p.Data, 1)
+		}
+	}
+	return state, nil
+}
+
+// PowerProperty returns property to turn on/off
+func PowerProperty(on bool) Property {
+	if on {
+		return byteProperty(OperationStatus, 0x30)
+	}
+	return byteProperty(OperationStatus, 0x31)
+}
+
+// ModeProperty returns property to set operation mode
+func ModeProperty(mode AirConditionerMode) (Property, error) {
+	if mode < ModeOther || mode > ModeFan {
+		return Property{}, fmt.Errorf("invalid mode: %s", mode)
+	}
+	return byteProperty(OperationModeSetting, byte(mode)), nil
+}
+
+// TemperatureProperty returns property to set temperature (0-50℃)
+func TemperatureProperty(temp int) (Property, error) {
+	if temp < 0 || temp > 50 {
+		return Property{}, fmt.Errorf("temperature out of range [0-50]: %d", temp)
+	}
+	return byteProperty(TemperatureSetting, byte(temp)), nil
+}
+
+// FanSpeedProperty returns property to set air flow rate
+func FanSpeedProperty(f FanSpeed) (Property, error) {
+	if !f.valid() {
+		return Property{}, fmt.Errorf("invalid fan speed: %s", f)
+	}
+	return byteProperty(AirFlowRateSetting, byte(f)), nil
+}
+
+// HumidityProperty returns property to set relative humidity for dry mode (0-100%)
+func HumidityProperty(humidity int) (Property, error) {
+	if humidity < 0 || humidity > 100 {
+		return Property{}, fmt.Errorf("humidity out of range [0-100]: %d", humidity)
+	}
+	return byteProperty(DehumidifyingSetting, byte(humidity)), nil
+}
+
+// PowerSavingProperty returns property to enable/disable power saving operation
+func PowerSavingProperty(on bool) Property {
+	if on {
+		return byteProperty(PowerReductionState, 0x41)
+	}
+	return byteProperty(PowerReductionState, 0x42)
+}
+
+// SetPower turns on/off the air conditioner
+func (a *AirConditioner) SetPower(ctx context.Context, on bool) error {
+	return a.Set(ctx, PowerProperty(on))
+}
+
+// SetMode changes operation mode
+func (a *AirConditioner) SetMode(ctx context.Context, mode AirConditionerMode) error {
+	p, err := ModeProperty(mode)
+	if err != nil {
+		return err
+	}
+	return a.Set(ctx, p)
+}
+
+// SetTemperature changes set temperature
+func (a *AirConditioner) SetTemperature(ctx context.Context, temp int) error {
+	p, err := TemperatureProperty(temp)
+	if err != nil {
+		return err
+	}
+	return a.Set(ctx, p)
+}
+
+// SetFanSpeed changes air flow rate
+func (a *AirConditioner) SetFanSpeed(ctx context.Context, f FanSpeed) error {
+	p, err := FanSpeedProperty(f)
+	if err != nil {
+		return err
+	}
+	return a.Set(ctx, p)
+}
+
+// SetHumidity changes relative humidity for dry mode
+func (a *AirConditioner) SetHumidity(ctx context.Context, humidity int) error {
+	p, err := HumidityProperty(humidity)
+	if err != nil {
+		return err
+	}
+	return a.Set(ctx, p)
+}
+
+// SetPowerSaving enables/disables power saving operation
+func (a *AirConditioner) SetPowerSaving(ctx context.Context, on bool) error {
+	return a.Set(ctx, PowerSavingProperty(on))
+}
@@ -0,0 +1,146 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// airconResponder emulates home air conditioner set to 26℃ which accepts temperature up to 30℃
+func airconResponder(req Frame) []byte {
+	return respondAircon(req, 0x1a)
+}
+
+func respondAircon(req Frame, temperature byte) []byte {
+	values := map[PropertyCode]Data{
+		OperationStatus:            {0x30},
+		OperationModeSetting:       {0x42},
+		TemperatureSetting:         {temperature},
+		AirFlowRateSetting:         {0x41},
+		PowerReductionState:        {0x42},
+		MeasuredRoomTemperature:    {0x1b},
+		MeasuredRoomHumidity:       {0x3c},
+		MeasuredOutdoorTemperature: {0xfe},
+		SetPropertyMap:             {0x05, 0x80, 0x8f, 0xa0, 0xb0, 0xb3},
+	}
+
+	props := []Property{}
+	esv := GetRes
+	switch req.ESV {
+	case Get:
+		for _, p := range req.Properties {
+			d, ok := values[PropertyCode(p.Code)]
+			if !ok {
+				esv = GetSNA
+				d = Data{}
+			}
+			props = append(props, Property{Code: p.Code, Len: len(d), Data: d})
+		}
+	case SetC:
+		esv = SetRes
+		for _, p := range req.Properties {
+			if PropertyCode(p.Code) == TemperatureSetting && p.Data[0] > 30 {
+				esv = SetCSNA
+				props = append(props, p)
+				continue
+			}
+			props = append(props, Property{Code: p.Code, Len: 0, Data: Data{}})
+		}
+	}
+	res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, esv, props)
+	return res.Serialize()
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestAirConditioner_State(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	a := NewAirConditioner(newTestController(t, ctx, airconResponder), "192.168.1.15", 1)
+	got, err := a.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AirConditionerState{
+		On:                 true,
+		Mode:               ModeCool,
+		Temperature:        intPtr(26),
+		FanSpeed:           FanAuto,
+		RoomTemperature:    float64Ptr(27),
+		RoomHumidity:       float64Ptr(60),
+		OutdoorTemperature: float64Ptr(-2),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("state differs: (-want +got)\n%s", diff)
+	}
+}
+
+func TestAirConditioner_StateUndeterminedTemperature(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	responder := func(req Frame) []byte {
+		return respondAircon(req, 0xfd)
+	}
+	a := NewAirConditioner(newTestController(t, ctx, responder), "192.168.1.15", 1)
+	got, err := a.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Temperature != nil {
+		t.Errorf("nil expected for undetermined temperature: %d", *got.Temperature)
+	}
+}
+
+func TestAirConditioner_Set(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	a := NewAirConditioner(newTestController(t, ctx, airconResponder), "192.168.1.15", 1)
+
+	if err := a.SetTemperature(ctx, 24); err != nil {
+		t.Errorf("SetTemperature failed: %v", err)
+	}
+	if err := a.SetMode(ctx, ModeCool); err != nil {
+		t.Errorf("SetMode failed: %v", err)
+	}
+	if err := a.SetPowerSaving(ctx, true); err != nil {
+		t.Errorf("SetPowerSaving failed: %v", err)
+	}
+
+	err := a.SetHumidity(ctx, 50)
+	if nerr, ok := err.(*NotSettableError); !ok || nerr.Code != DehumidifyingSetting {
+		t.Errorf("NotSettableError expected: %v", err)
+	}
+
+	err = a.SetTemperature(ctx, 31)
+	serr, ok := err.(*ServiceError)
+	if !ok {
+		t.Fatalf("ServiceError expected: %v", err)
+	}
+	if diff := cmp.Diff([]PropertyCode{TemperatureSetting}, serr.Rejected()); diff != "" {
+		t.Errorf("rejected properties differ: (-want +got)\n%s", diff)
+	}
+
+	if err := a.SetTemperature(ctx, 51); err == nil {
+		t.Errorf("error expected for out of range temperature")
+	}
+	if _, err := FanLevel(9); err == nil {
+		t.Errorf("error expected for out of range fan level")
+	}
+}
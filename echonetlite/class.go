@@ -22,6 +22,14 @@ const (
 // Profile is definition of profile object class code
 const Profile ClassCode = 0xF0
 
+// definition of class codes for SensorGroup
+const (
+	IlluminanceSensor ClassCode = 0x0D
+	TemperatureSensor ClassCode = 0x11
+	HumiditySensor    ClassCode = 0x12
+	CO2Sensor         ClassCode = 0x1B
+)
+
 // definition of class codes for AirConditionerGroup
 const (
 	HomeAirConditioner ClassCode = 0x30
@@ -29,7 +37,15 @@ const (
 
 // definition of class codes for HomeEquipmentGroup
 const (
+	ElectricWaterHeater  ClassCode = 0x6B
+	HomeSolarPower       ClassCode = 0x79
+	StorageBattery       ClassCode = 0x7D
+	EVChargerDischarger  ClassCode = 0x7E
+	DistributionBoard    ClassCode = 0x87
 	LowVoltageSmartMeter ClassCode = 0x88
+	GeneralLighting      ClassCode = 0x90
+	SingleFuncLighting   ClassCode = 0x91
+	MultiInputPCS        ClassCode = 0xA5
 )
 
 // definition of class codes for ControllerGroup
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -20,9 +21,15 @@ var (
 
 // PrepareClassDictionary prepares information about Echonet Lite classes
 func PrepareClassDictionary() error {
-	classDictionary, err := load(classInfoPath)
-	classDictionary.merge(loadNodeProfile(classInfoPath))
-	classDictionary.merge(loadControllerProfile())
+	return LoadClassDictionary(classInfoPath)
+}
+
+// LoadClassDictionary prepares information about Echonet Lite classes from files in basePath
+func LoadClassDictionary(basePath string) error {
+	dict, err := load(basePath)
+	dict.merge(loadNodeProfile(basePath))
+	dict.merge(loadControllerProfile())
+	classDictionary = dict
 	return err
 }
 
@@ -46,8 +53,12 @@ type PropertyDictionary map[PropertyCode]PropertyInfo
 
 // PropertyInfo is static information about property
 type PropertyInfo struct {
-	Code   PropertyCode
-	Detail string
+	Code     PropertyCode
+	Detail   string
+	Unit     string
+	DataType string
+	Size     int             // data size in bytes, 0 if unknown or variable
+	Values   map[byte]string // names of enumerated values (e.g. ON=0x30), nil if the property is not enumeration
 }
 
 // NewClassDictionary returns ClassDictionary
@@ -94,6 +105,24 @@ func (dict ClassDictionary) Get(g ClassGroupCode, c ClassCode) ClassInfo {
 	}
 }
 
+// Property returns PropertyInfo of the class.
+// Properties of super class (0x80-0x9F) are looked up in node profile if the class does not have them.
+func (dict ClassDictionary) Property(g ClassGroupCode, c ClassCode, code PropertyCode) (PropertyInfo, bool) {
+	if i, ok := dict.get(g, c); ok {
+		if p, ok := i.Properties[code]; ok {
+			return p, true
+		}
+	}
+	if code < 0xA0 {
+		if i, ok := dict.get(ProfileGroup, Profile); ok {
+			if p, ok := i.Properties[code]; ok {
+				return p, true
+			}
+		}
+	}
+	return PropertyInfo{Code: code}, false
+}
+
 // load loads class information from files SonyCSL provides
 // https://github.com/SonyCSL/ECHONETLite-ObjectDatabase
 func load(basePath string) (ClassDictionary, error) {
@@ -119,13 +148,13 @@ func load(basePath string) (ClassDictionary, error) {
 		logger.Println(file)
 		logger.Println(basePath, file.Name())
 
-		properties := loadClassInfo(basePath + "/" + file.Name())
+		properties, name := loadClassInfo(basePath + "/" + file.Name())
 		if properties != nil {
 			clsInfo := ClassInfo{
 				ClassGroup: ClassGroupCode(codes[0]),
 				Class:      ClassCode(codes[1]),
 				Properties: properties,
-				Desc:       "",
+				Desc:       name,
 			}
 			classMap.add(clsInfo.ClassGroup, clsInfo.Class, clsInfo)
 		}
@@ -137,7 +166,7 @@ func load(basePath string) (ClassDictionary, error) {
 func loadNodeProfile(basePath string) ClassDictionary {
 	classMap := NewClassDictionary()
 
-	properties := loadClassInfo(basePath + "/DeviceObject.csv")
+	properties, _ := loadClassInfo(basePath + "/DeviceObject.csv")
 	if properties != nil {
 		properties[0xd3] = PropertyInfo{Code: 0xd3, Detail: "自ノードインスタンス数"}
 		properties[0xd4] = PropertyInfo{Code: 0xd4, Detail: "自ノードクラス数"}
@@ -187,16 +216,17 @@ func classCode(file os.FileInfo) []byte {
 	return decodedClassCodes
 }
 
-// loadPropertyInfo load PropertyInfo from file(0xXXYY.csv)
+// loadPropertyInfo load PropertyInfo and class name from file(0xXXYY.csv)
 // which describes about property information for a Echonet Lite class
-func loadClassInfo(filePath string) PropertyDictionary {
+func loadClassInfo(filePath string) (PropertyDictionary, string) {
 
 	properties := PropertyDictionary{}
+	name := ""
 
 	f, err := os.Open(filePath)
 	if err != nil {
 		logger.Println("failed to open file:", err)
-		return properties
+		return properties, name
 	}
 	defer f.Close()
 
@@ -213,7 +243,8 @@ func loadClassInfo(filePath string) PropertyDictionary {
 	//   ...
 
 	r := csv.NewReader(f)
-	for {
+	r.FieldsPerRecord = -1
+	for line := 1; ; line++ {
 		record, err := r.Read()
 		if err == io.EOF {
 			break
@@ -222,6 +253,9 @@ func loadClassInfo(filePath string) PropertyDictionary {
 			logger.Println(err)
 			continue
 		}
+		if line == 2 && len(record) > 0 {
+			name = record[0]
+		}
 		if record[0] == "EPC" {
 			epcBegan = true
 			continue
@@ -246,8 +280,51 @@ func loadClassInfo(filePath string) PropertyDictionary {
 			Code:   PropertyCode(d[0]),
 			Detail: record[1],
 		}
+		if len(record) > 6 {
+			p.Values = parseValueRange(record[3])
+			p.Unit = normalizeUnit(record[4])
+			p.DataType = record[5]
+			p.Size, _ = strconv.Atoi(strings.TrimSpace(record[6]))
+		}
 		properties[PropertyCode(d[0])] = p
 	}
 
-	return properties
+	return properties, name
+}
+
+// parseValueRange returns enumerated values in value range of the database (e.g. "ON=0x30, OFF=0x31").
+// nil is returned if the range has no enumeration, such as "0x00～0x64(0～100%)".
+func parseValueRange(s string) map[byte]string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == '\n' || r == '\r'
+	})
+	values := map[byte]string{}
+	for _, f := range fields {
+		tokens := strings.SplitN(strings.Replace(f, "＝", "=", 1), "=", 2)
+		if len(tokens) != 2 {
+			continue
+		}
+		name, v := strings.TrimSpace(tokens[0]), strings.TrimSpace(tokens[1])
+		if name == "" || !strings.HasPrefix(v, "0x") || len(v) != 4 {
+			continue
+		}
+		d, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+		if err != nil {
+			continue
+		}
+		values[d[0]] = name
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// normalizeUnit returns unit string, "." and "-" in the database mean no unit
+func normalizeUnit(u string) string {
+	u = strings.TrimSpace(u)
+	if u == "." || u == "-" || u == "－" {
+		return ""
+	}
+	return u
 }
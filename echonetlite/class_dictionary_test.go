@@ -197,3 +197,20 @@ func Test_ClassDictionary_add(t *testing.T) {
 	}
 
 }
+
+func Test_parseValueRange(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want map[byte]string
+	}{
+		{in: "ON=0x30, OFF=0x31", want: map[byte]string{0x30: "ON", 0x31: "OFF"}},
+		{in: "自動=0x41\n冷房=0x42，暖房＝0x43", want: map[byte]string{0x41: "自動", 0x42: "冷房", 0x43: "暖房"}},
+		{in: "0x00～0x32(0～50℃)", want: nil},
+		{in: "", want: nil},
+	}
+	for _, tc := range testcases {
+		if diff := cmp.Diff(tc.want, parseValueRange(tc.in)); diff != "" {
+			t.Errorf("%q: (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package echonetlite
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// stateKey identifies an object in a node
+type stateKey struct {
+	addr string
+	obj  Object
+}
+
+// deviceState is the last state of an object and the time it is received
+type deviceState struct {
+	value   interface{}
+	updated time.Time
+}
+
+// storeState keeps v as the last state of obj at addr, which is exported by Collector on scrape
+func (elc *ControllerNode) storeState(addr string, obj Object, v interface{}) {
+	switch v.(type) {
+	case AirconObject, StorageBatteryObject, EVChargerObject, SolarPowerObject, WaterHeaterObject,
+		DistributionBoardObject, MultiInputPCSObject, SensorObject:
+	default:
+		return
+	}
+
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+	if elc.states == nil {
+		elc.states = map[stateKey]deviceState{}
+	}
+	key := stateKey{addr: addr, obj: obj}
+	// 分電盤のチャンネルは範囲指定した分だけ応答されるので前回の値とマージする
+	if b, ok := v.(DistributionBoardObject); ok {
+		if prev, ok := elc.states[key].value.(DistributionBoardObject); ok {
+			v = b.merge(prev)
+		}
+	}
+	elc.states[key] = deviceState{value: v, updated: time.Now()}
+}
+
+// moveStates moves states of objects at from to addr, keeping newer one if both exist.
+// elc.mu must be held.
+func (elc *ControllerNode) moveStates(from, addr string) {
+	for k, s := range elc.states {
+		if k.addr != from {
+			continue
+		}
+		key := stateKey{addr: addr, obj: k.obj}
+		if cur, ok := elc.states[key]; !ok || s.updated.After(cur.updated) {
+			elc.states[key] = s
+		}
+		delete(elc.states, k)
+	}
+}
+
+// Collector returns prometheus.Collector which exports the last states of devices found by elc.
+// Metrics are built on each scrape, and states not updated within stale timeout are dropped,
+// so it should be registered to the registry of the program explicitly.
+func (elc *ControllerNode) Collector() prometheus.Collector {
+	return &controllerCollector{elc: elc}
+}
+
+type controllerCollector struct {
+	elc *ControllerNode
+}
+
+// Describe implements prometheus.Collector
+func (c *controllerCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeGauges(ch, deviceGauges)
+}
+
+// Collect implements prometheus.Collector
+func (c *controllerCollector) Collect(ch chan<- prometheus.Metric) {
+	w := newMetricWriter(ch)
+
+	for _, n := range c.elc.Nodes() {
+		w.setGauge(nodeStale, prometheus.Labels{"id": n.ID(), "ip": n.Address}, boolValue(&n.Stale))
+		for obj, id := range n.Identities {
+			info := 1.0
+			w.setGauge(deviceInfo, deviceInfoLabels(n.Address, obj, id), &info)
+		}
+	}
+
+	c.elc.mu.Lock()
+	keys := make([]stateKey, 0, len(c.elc.states))
+	states := make(map[stateKey]deviceState, len(c.elc.states))
+	for k, s := range c.elc.states {
+		// 応答のなくなった機器の古い値は出力しない
+		if c.elc.isStale(s.updated) {
+			delete(c.elc.states, k)
+			continue
+		}
+		keys = append(keys, k)
+		states[k] = s
+	}
+	c.elc.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].addr != keys[j].addr {
+			return keys[i].addr < keys[j].addr
+		}
+		return keys[i].obj.String() < keys[j].obj.String()
+	})
+
+	for _, k := range keys {
+		s := states[k]
+		base := prometheus.Labels{"id": c.elc.DeviceID(k.addr, k.obj)}
+		switch o := s.value.(type) {
+		case AirconObject:
+			collectAirconMetrics(w, base, o)
+		case StorageBatteryObject:
+			collectStorageBatteryMetrics(w, base, o)
+		case EVChargerObject:
+			collectEVChargerMetrics(w, base, o)
+		case SolarPowerObject:
+			collectSolarPowerMetrics(w, base, o)
+		case WaterHeaterObject:
+			collectWaterHeaterMetrics(w, base, o)
+		case DistributionBoardObject:
+			collectDistributionBoardMetrics(w, base, o)
+		case MultiInputPCSObject:
+			collectMultiInputPCSMetrics(w, base, o)
+		case SensorObject:
+			collectSensorMetrics(w, base, o)
+		}
+	}
+}
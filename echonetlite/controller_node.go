@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/matsuu/go-el-controller/transport"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/u-one/go-el-controller/transport"
 )
 
 var clogger *log.Logger
@@ -18,7 +21,7 @@ func init() {
 }
 
 var (
-	tempMetrics = prometheus.NewGaugeVec(
+	tempMetrics = newDeviceGauge(
 		prometheus.GaugeOpts{
 			Namespace: "home",
 			Subsystem: "aircon",
@@ -26,14 +29,27 @@ var (
 			Help:      "aircon temp",
 		},
 		[]string{
-			"ip", "type", "location",
+			"id", "type", "location",
 		},
 	)
 )
 
-func init() {
-	prometheus.MustRegister(tempMetrics)
+// temperature returns value of n, or nil if it is overflow/unmeasurable code
+func temperature(n *Number) *float64 {
+	if n == nil {
+		return nil
+	}
+	v, ok := n.Scaled(1)
+	if !ok {
+		return nil
+	}
+	return &v
+}
 
+func collectAirconMetrics(w *metricWriter, base prometheus.Labels, o AirconObject) {
+	loc := o.InstallLocation.Label()
+	w.setGauge(tempMetrics, prometheus.Labels{"id": base["id"], "location": loc, "type": "room"}, temperature(o.InternalTemp))
+	w.setGauge(tempMetrics, prometheus.Labels{"id": base["id"], "location": loc, "type": "outside"}, temperature(o.OuterTemp))
 }
 
 const (
@@ -43,13 +59,40 @@ const (
 	Port = ":3610"
 )
 
+// NodeController is controller of discovered nodes used by MQTT bridge and HTTP API, which is implemented by *ControllerNode
+type NodeController interface {
+	Nodes() []Node
+	Subscribe() (<-chan Message, func())
+	DeviceID(addr string, obj Object) string
+	LookupDevice(id string, obj Object) (string, bool)
+	Get(ctx context.Context, addr string, obj Object, codes ...PropertyCode) ([]Property, error)
+	Set(ctx context.Context, addr string, obj Object, props ...Property) error
+}
+
+var _ NodeController = (*ControllerNode)(nil)
+
 // ControllerNode is ECHONETLite controller
 type ControllerNode struct {
 	MulticastReceiver transport.MulticastReceiver
 	UnicastReceiver   transport.UnicastReceiver
 	MulticastSender   transport.MulticastSender
-	tid               uint16
-	nodeList          NodeList
+	UnicastSender     transport.UnicastSender
+
+	// ResolveMAC looks up MAC address of IP address, LookupMAC is used if nil
+	ResolveMAC func(ip string) (net.HardwareAddr, error)
+	// StaleTimeout is time until a silent node is considered stale, DefaultStaleTimeout is used if zero
+	StaleTimeout time.Duration
+	// EVPermission permits Set to EV charger/discharger, which is refused by default
+	EVPermission EVSetPermission
+
+	mu          sync.Mutex
+	tid         uint16
+	nodeList    NodeList
+	states      map[stateKey]deviceState
+	waiters     map[uint16]*waiter
+	subscribers map[chan Message]struct{}
+	macLookups  map[string]time.Time // address -> time when MAC address may be looked up again
+	lookups     sync.WaitGroup       // MAC address lookups in progress
 }
 
 // NewControllerNode returns ControllerNode
@@ -59,54 +102,164 @@ func NewControllerNode() (*ControllerNode, error) {
 		log.Println(err)
 		return &ControllerNode{}, err
 	}
+	us, err := transport.NewUDPUnicastSender(Port)
+	if err != nil {
+		log.Println(err)
+		ms.Close()
+		return &ControllerNode{}, err
+	}
 	return &ControllerNode{
 		MulticastReceiver: &transport.UDPMulticastReceiver{},
 		MulticastSender:   ms,
 		UnicastReceiver:   &transport.UDPUnicastReceiver{},
+		UnicastSender:     us,
 	}, nil
 }
 
 // Close closes all resources open
-func (elc ControllerNode) Close() {
+func (elc *ControllerNode) Close() {
 	elc.MulticastSender.Close()
+	if elc.UnicastSender != nil {
+		elc.UnicastSender.Close()
+	}
+}
+
+// Record wraps receivers and senders so that all datagrams are recorded by rec.
+// It must be called before Start.
+func (elc *ControllerNode) Record(rec transport.Recorder) {
+	elc.MulticastReceiver = &transport.RecordingMulticastReceiver{Receiver: elc.MulticastReceiver, Recorder: rec}
+	elc.UnicastReceiver = &transport.RecordingUnicastReceiver{Receiver: elc.UnicastReceiver, Recorder: rec}
+	elc.MulticastSender = &transport.RecordingMulticastSender{Sender: elc.MulticastSender, Recorder: rec, Address: MulticastIP + Port}
+	if elc.UnicastSender != nil {
+		elc.UnicastSender = &transport.RecordingUnicastSender{Sender: elc.UnicastSender, Recorder: rec, Port: Port}
+	}
 }
 
-// NodeList is list of node profile objects
-type NodeList map[string]Node
+// NodeList is list of node profile objects keyed by IP address
+type NodeList map[string]*Node
 
-// Add adds Node
-func (nlist *NodeList) Add(addr string, obj Object) {
-	if _, ok := (*nlist)[addr]; !ok {
-		(*nlist)[addr] = Node{}
+// Add adds obj to the node at addr
+func (nlist NodeList) Add(addr string, obj Object) {
+	node, ok := nlist[addr]
+	if !ok {
+		node = &Node{Address: addr}
+		nlist[addr] = node
 	}
+	if obj.isNodeProfile() {
+		return
+	}
+	for _, o := range node.Devices {
+		if o == obj {
+			return
+		}
+	}
+	node.Devices = append(node.Devices, obj)
 }
 
-// Node represents a node profile object
+// Node represents a node profile object.
+// Identities has identification numbers (0x83) of objects found in responses,
+// and MAC is looked up from ARP/NDP table if the node profile doesn't have identification number.
+// LastSeen is the time of the last response or notification from the node.
 type Node struct {
-	Devices []Object
+	Address    string
+	Devices    []Object
+	Identities map[Object]Identification
+	MAC        net.HardwareAddr
+	LastSeen   time.Time
+	Stale      bool
+}
+
+// Nodes returns nodes found so far sorted by address
+func (elc *ControllerNode) Nodes() []Node {
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+
+	nodes := make([]Node, 0, len(elc.nodeList))
+	for _, n := range elc.nodeList {
+		node := Node{Address: n.Address, Devices: append([]Object{}, n.Devices...), MAC: n.MAC, LastSeen: n.LastSeen, Stale: elc.isStale(n.LastSeen)}
+		if len(n.Identities) > 0 {
+			node.Identities = make(map[Object]Identification, len(n.Identities))
+			for obj, id := range n.Identities {
+				node.Identities[obj] = id
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Address < nodes[j].Address })
+	return nodes
+}
+
+func (elc *ControllerNode) addNode(addr string, objs ...Object) {
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+
+	if elc.nodeList == nil {
+		elc.nodeList = make(NodeList)
+	}
+	for _, obj := range objs {
+		elc.nodeList.Add(addr, obj)
+	}
+}
+
+// AddNode registers objects of a node which is not found by multicast discovery
+func (elc *ControllerNode) AddNode(addr string, objs ...Object) {
+	elc.addNode(addr, objs...)
+}
+
+// DiscoverNode requests instance list of the node at addr by unicast.
+// Objects in the response are added to the node list.
+func (elc *ControllerNode) DiscoverNode(ctx context.Context, addr string) error {
+	props, err := elc.Get(ctx, addr, NewObject(ProfileGroup, Profile, 0x01), InstanceListS)
+	if err != nil {
+		return err
+	}
+	for _, p := range props {
+		if PropertyCode(p.Code) != InstanceListS {
+			continue
+		}
+		objs, err := ParseObjectList(p.Data)
+		if err != nil {
+			return fmt.Errorf("invalid instance list from %s: %w", addr, err)
+		}
+		elc.addNode(addr, objs...)
+	}
+	return nil
+}
+
+// hostOf returns IP address part of "ip" or "ip:port"
+func hostOf(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return addr
 }
 
 // Start starts controller
-func (elc ControllerNode) Start(ctx context.Context) {
-	elc.tid = 0
-	elc.nodeList = make(NodeList)
+func (elc *ControllerNode) Start(ctx context.Context) {
+	elc.Listen(ctx)
+	elc.startSequence(ctx)
+}
 
+// Listen starts to receive frames without announcing this controller
+func (elc *ControllerNode) Listen(ctx context.Context) {
 	sch := elc.UnicastReceiver.Start(ctx, Port)
 	go elc.handleUnicastResult(ctx, sch)
 
 	mch := elc.MulticastReceiver.Start(ctx, MulticastIP, Port)
 	go elc.handleMulticastResult(ctx, mch)
-
-	elc.startSequence(ctx)
 }
 
-func (elc ControllerNode) handleMulticastResult(ctx context.Context, results <-chan transport.ReceiveResult) {
+func (elc *ControllerNode) handleMulticastResult(ctx context.Context, results <-chan transport.ReceiveResult) {
 	for {
 		select {
 		case <-ctx.Done():
 			clogger.Println("readMulticast handler ctx.Done")
 			return
-		case result := <-results:
+		case result, ok := <-results:
+			if !ok {
+				clogger.Println("receiver closed")
+				return
+			}
 			if result.Err != nil {
 				clogger.Printf("[Error] failed to receive [%s]\n", result.Err)
 				break
@@ -121,13 +274,17 @@ func (elc ControllerNode) handleMulticastResult(ctx context.Context, results <-c
 	}
 }
 
-func (elc ControllerNode) handleUnicastResult(ctx context.Context, results <-chan transport.ReceiveResult) {
+func (elc *ControllerNode) handleUnicastResult(ctx context.Context, results <-chan transport.ReceiveResult) {
 	for {
 		select {
 		case <-ctx.Done():
 			clogger.Println("readUnicast handler ctx.Done")
 			return
-		case result := <-results:
+		case result, ok := <-results:
+			if !ok {
+				clogger.Println("receiver closed")
+				return
+			}
 			if result.Err != nil {
 				clogger.Printf("[Error] failed to receive [%s]\n", result.Err)
 				break
@@ -142,12 +299,13 @@ func (elc ControllerNode) handleUnicastResult(ctx context.Context, results <-cha
 	}
 }
 
-func (elc ControllerNode) onReceive(ctx context.Context, recv transport.ReceiveResult) error {
+func (elc *ControllerNode) onReceive(ctx context.Context, recv transport.ReceiveResult) error {
 	frame, err := ParseFrame(recv.Data)
 	if err != nil {
 		return fmt.Errorf("parse failed: %w", err)
 	}
 	clogger.Printf("[%v] %s\n", recv.Address, frame)
+	elc.dispatch(Message{Address: recv.Address, Frame: frame})
 
 	var targetObj Object
 	if frame.ESV.isResponseOrNotification() {
@@ -160,6 +318,17 @@ func (elc ControllerNode) onReceive(ctx context.Context, recv transport.ReceiveR
 		return fmt.Errorf("ParseProperties failed: %w", err)
 	}
 
+	if frame.ESV.isResponseOrNotification() {
+		host := hostOf(recv.Address)
+		elc.addNode(host, append(instanceList(frame), frame.SEOJ)...)
+		if p, ok := frame.Property(ID); ok && len(p.Data) > 0 {
+			if id, err := ParseIdentification(p.Data); err == nil {
+				elc.setIdentification(host, frame.SEOJ, id)
+			}
+		}
+		elc.seen(host)
+	}
+
 	switch frame.ESV {
 	// 要求
 	case SetI, // プロパティ値書き込み(応答不要)
@@ -169,23 +338,11 @@ func (elc ControllerNode) onReceive(ctx context.Context, recv transport.ReceiveR
 		SetGet: // プロパティ値書き込み・読み出し要求
 	// 応答・通知
 	case SetRes: // プロパティ値書き込み
-	case GetRes: // プロパティ値読み出し応答
-		switch obj.(type) {
-		case AirconObject:
-			o := obj.(AirconObject)
-			lc := o.InstallLocation.Code
-			ln := o.InstallLocation.Number
-			loc := lc.String()
-			if ln != 0 {
-				loc = fmt.Sprintf("%s%d", lc, ln)
-			}
-
-			tempMetrics.With(prometheus.Labels{"ip": recv.Address, "location": loc, "type": "room"}).Set(o.InternalTemp)
-			tempMetrics.With(prometheus.Labels{"ip": recv.Address, "location": loc, "type": "outside"}).Set(o.OuterTemp)
-
-		}
+	case GetRes, // プロパティ値読み出し応答
+		GetSNA: // 一部のプロパティが存在しない場合も取得できた値は使う
+		//[Controller]2021/02/23 16:58:06 [192.168.50.102] 108100020ef00105ff0152088001308204010c0100d303000001d4020002d500d60401013001d7030101309f0e0d808283898a9d9e9fbfd3d4d6d7 EHD[1081] TID[0002] SEOJ[{0ef001}](unknown) DEOJ[{05ff01}](unknown) ESV[Get_SNA] OPC[8] EPC0[80]() PDC0[1] EDT0[30] EPC1[82]() PDC1[4] EDT1[010c0100] EPC2[d3]() PDC2[3] EDT2[000001] EPC3[d4]() PDC3[2] EDT3[0002] EPC4[d5]() PDC4[0] EDT4[] EPC5[d6]() PDC5[4] EDT5[01013001] EPC6[d7]() PDC6[3] EDT6[010130] EPC7[9f]() PDC7[14] EDT7[0d808283898a9d9e9fbfd3d4d6d7]
+		elc.storeState(hostOf(recv.Address), frame.SEOJ, obj)
 	case Inf: // プロパティ値通知
-		elc.nodeList.Add(recv.Address, frame.SEOJ)
 		//[Controller]2019/09/27 01:52:59 [192.168.1.15] 108100010ef00105ff017301d50401013001 EHD[1081] TID[0001] SEOJ[0ef001](ノードプロファイル) DEOJ[05ff01](コントローラ) ESV[INF] OPC[01] EPC0[d5](インスタンスリスト通知) PDC0[4] EDT0[01013001]
 		//[Controller]2019/09/27 01:52:59 [192.168.1.10] 108100010ef00105ff017301d50401013001 EHD[1081] TID[0001] SEOJ[0ef001](ノードプロファイル) DEOJ[05ff01](コントローラ) ESV[INF] OPC[01] EPC0[d5](インスタンスリスト通知) PDC0[4] EDT0[01013001]
 	case InfC: //
@@ -193,8 +350,6 @@ func (elc ControllerNode) onReceive(ctx context.Context, recv transport.ReceiveR
 	case SetGetRes: //
 	case SetISNA: //
 	case SetCSNA: //
-	case GetSNA: //
-	//[Controller]2021/02/23 16:58:06 [192.168.50.102] 108100020ef00105ff0152088001308204010c0100d303000001d4020002d500d60401013001d7030101309f0e0d808283898a9d9e9fbfd3d4d6d7 EHD[1081] TID[0002] SEOJ[{0ef001}](unknown) DEOJ[{05ff01}](unknown) ESV[Get_SNA] OPC[8] EPC0[80]() PDC0[1] EDT0[30] EPC1[82]() PDC1[4] EDT1[010c0100] EPC2[d3]() PDC2[3] EDT2[000001] EPC3[d4]() PDC3[2] EDT3[0002] EPC4[d5]() PDC4[0] EDT4[] EPC5[d6]() PDC5[4] EDT5[01013001] EPC6[d7]() PDC6[3] EDT6[010130] EPC7[9f]() PDC7[14] EDT7[0d808283898a9d9e9fbfd3d4d6d7]
 	case InfSNA: //
 	case SetGetSNA: //
 	}
@@ -204,21 +359,29 @@ func (elc ControllerNode) onReceive(ctx context.Context, recv transport.ReceiveR
 func (elc *ControllerNode) sendFrame(f *Frame) {
 	clogger.Printf(">>>>>>>> SEND : %s\n", f)
 	elc.MulticastSender.Send([]byte(f.Serialize()))
+}
+
+// nextTID returns transaction ID for a new frame
+func (elc *ControllerNode) nextTID() uint16 {
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+	tid := elc.tid
 	elc.tid++
+	return tid
 }
 
 func (elc *ControllerNode) startSequence(ctx context.Context) {
 	clogger.Println("Start Sequnce Begin")
 
-	f := CreateInfFrame(elc.tid)
+	f := CreateInfFrame(elc.nextTID())
 	elc.sendFrame(f)
 
 	// ver.1.0
-	f = CreateInfReqFrame(elc.tid)
+	f = CreateInfReqFrame(elc.nextTID())
 	elc.sendFrame(f)
 
 	// ver.1.1
-	f = CreateGetFrame(elc.tid)
+	f = CreateGetFrame(elc.nextTID())
 	elc.sendFrame(f)
 
 	time.Sleep(time.Second * 3)
@@ -227,6 +390,6 @@ func (elc *ControllerNode) startSequence(ctx context.Context) {
 
 // RequestAirConState sends request to get air conditioner states
 func (elc *ControllerNode) RequestAirConState() {
-	f := CreateAirconGetFrame(elc.tid)
+	f := CreateAirconGetFrame(elc.nextTID())
 	elc.sendFrame(f)
 }
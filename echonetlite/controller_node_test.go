@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	gomock "github.com/golang/mock/gomock"
-	"github.com/u-one/go-el-controller/transport"
+	"github.com/matsuu/go-el-controller/transport"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestController(t *testing.T) {
@@ -57,7 +59,7 @@ func TestController(t *testing.T) {
 		state++
 	})
 
-	getFrame := []byte{0x10, 0x81, 0x0, 0x2, 0x05, 0xff, 0x01, 0x0e, 0xf0, 0x01, 0x62, 0x08, 0x80, 0x00, 0x82, 0x00, 0xd3, 0x00, 0xd4, 0x00, 0xd5, 0x00, 0xd6, 0x00, 0xd7, 0x00, 0x9f, 0x00}
+	getFrame := []byte{0x10, 0x81, 0x0, 0x2, 0x05, 0xff, 0x01, 0x0e, 0xf0, 0x01, 0x62, 0x09, 0x80, 0x00, 0x82, 0x00, 0x83, 0x00, 0xd3, 0x00, 0xd4, 0x00, 0xd5, 0x00, 0xd6, 0x00, 0xd7, 0x00, 0x9f, 0x00}
 	s.EXPECT().Send(getFrame).Do(func(data []byte) {
 		fmt.Println("getFrame sent", state)
 
@@ -102,3 +104,190 @@ func TestController(t *testing.T) {
 	<-ctx.Done()
 
 }
+
+func TestController_Replay(t *testing.T) {
+	airconResp := []byte{0x10, 0x81, 0x0, 0x0, 0x1, 0x30, 0x1, 0x5, 0xff, 0x1, 0x72, 0x4, 0x81, 0x1, 0x41, 0x83, 0x11, 0xfe, 0x0, 0x0, 0x8, 0x60, 0xf1, 0x89, 0x30, 0x6d, 0xf5, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xbb, 0x01, 0x1b, 0xbe, 0x1, 0x16}
+	replay := transport.NewReplay([]transport.Packet{
+		{Direction: transport.Inbound, Peer: "192.168.1.15:3610", Local: "192.168.1.2:3610", Data: airconResp},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := ControllerNode{
+		MulticastReceiver: replay.Multicast(),
+		MulticastSender:   replay,
+		UnicastReceiver:   replay.Unicast(),
+	}
+	c.Start(ctx)
+
+	// 識別番号(0x83)があればIPアドレスではなく識別番号がidになる
+	labels := prometheus.Labels{"id": "fe00000860f189306df500000000000000", "location": "Room1", "type": "room"}
+	collector := c.Collector()
+	m := gatherMetric(t, collector, tempMetrics, labels)
+	if m == nil {
+		t.Fatalf("room temperature is not collected")
+	}
+	if m.GetGauge().GetValue() != 27 {
+		t.Errorf("room temperature differs: want:27 got:%v", m.GetGauge().GetValue())
+	}
+	if m.TimestampMs != nil {
+		t.Errorf("metric should not have explicit timestamp")
+	}
+	labels["type"] = "outside"
+	if got := gaugeValue(t, collector, tempMetrics, labels); got != 22 {
+		t.Errorf("outside temperature differs: want:22 got:%v", got)
+	}
+
+	info := prometheus.Labels{"id": "fe00000860f189306df500000000000000", "ip": "192.168.1.15", "eoj": "013001", "manufacturer": "Daikin"}
+	if got := gaugeValue(t, collector, deviceInfo, info); got != 1 {
+		t.Errorf("device info differs: want:1 got:%v", got)
+	}
+
+	if got := len(replay.Sent()); got != 3 {
+		t.Errorf("number of sent frames differs: want:3 got:%d", got)
+	}
+}
+
+// memoryRecorder keeps recorded packets
+type memoryRecorder struct {
+	mu      sync.Mutex
+	packets []transport.Packet
+}
+
+func (r *memoryRecorder) Record(p transport.Packet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packets = append(r.packets, p)
+	return nil
+}
+
+func (r *memoryRecorder) recorded() []transport.Packet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]transport.Packet(nil), r.packets...)
+}
+
+func TestController_RecordUnicast(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	mr := transport.NewMockMulticastReceiver(ctrl)
+	ur := transport.NewMockUnicastReceiver(ctrl)
+	ms := transport.NewMockMulticastSender(ctrl)
+	us := transport.NewMockUnicastSender(ctrl)
+	uch := make(chan transport.ReceiveResult, 1)
+	mr.EXPECT().Start(gomock.Any(), MulticastIP, Port).Return(make(chan transport.ReceiveResult))
+	ur.EXPECT().Start(gomock.Any(), Port).Return(uch)
+	us.EXPECT().SendTo(gomock.Any(), "192.168.1.15").DoAndReturn(func(data []byte, ip string) error {
+		req, err := ParseFrame(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, []Property{{Code: 0x80, Len: 1, Data: Data{0x30}}})
+		uch <- transport.ReceiveResult{Data: res.Serialize(), Address: ip + ":3610"}
+		return nil
+	})
+
+	rec := &memoryRecorder{}
+	c := &ControllerNode{MulticastReceiver: mr, UnicastReceiver: ur, MulticastSender: ms, UnicastSender: us}
+	c.Record(rec)
+	c.Listen(ctx)
+
+	if _, err := c.Get(ctx, "192.168.1.15", NewObject(AirConditionerGroup, HomeAirConditioner, 1), OperationStatus); err != nil {
+		t.Fatal(err)
+	}
+	// 応答の記録は受信側のgoroutineで行われる
+	var packets []transport.Packet
+	for i := 0; i < 100 && len(packets) < 2; i++ {
+		packets = rec.recorded()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(packets) != 2 {
+		t.Fatalf("recorded packets: %+v", packets)
+	}
+	if p := packets[0]; p.Direction != transport.Outbound || p.Peer != "192.168.1.15:3610" {
+		t.Errorf("request is not recorded: %+v", p)
+	}
+	if p := packets[1]; p.Direction != transport.Inbound || p.Peer != "192.168.1.15:3610" {
+		t.Errorf("response is not recorded: %+v", p)
+	}
+}
+
+func TestCollectAirconMetrics(t *testing.T) {
+	labels := prometheus.Labels{"id": "192.168.1.99", "location": "Room1", "type": "outside"}
+	o := AirconObject{
+		SuperObject:  SuperObject{InstallLocation: Location{Code: Room, Number: 1}},
+		InternalTemp: &Number{Value: 126, Status: NumberNoData},
+		OuterTemp:    &Number{Value: -5},
+	}
+	c := collectFunc(func(w *metricWriter) { collectAirconMetrics(w, prometheus.Labels{"id": "192.168.1.99"}, o) })
+
+	if got := gaugeValue(t, c, tempMetrics, labels); got != -5 {
+		t.Errorf("temperature differs: want:-5 got:%v", got)
+	}
+	labels["type"] = "room"
+	if hasGauge(t, c, tempMetrics, labels) {
+		t.Errorf("gauge should not exist for unmeasurable value")
+	}
+}
+
+func TestController_CollectDropsStaleStates(t *testing.T) {
+	c := &ControllerNode{StaleTimeout: time.Minute}
+	power := -1000.0
+	fresh := NewObject(HomeEquipmentGroup, StorageBattery, 1)
+	old := NewObject(HomeEquipmentGroup, StorageBattery, 2)
+	c.storeState("192.168.1.20", fresh, StorageBatteryObject{Instance: 1, InstantPower: &power})
+	c.storeState("192.168.1.20", old, StorageBatteryObject{Instance: 2, InstantPower: &power})
+	key := stateKey{addr: "192.168.1.20", obj: old}
+	c.states[key] = deviceState{value: c.states[key].value, updated: time.Now().Add(-2 * time.Minute)}
+
+	collector := c.Collector()
+	if !hasGauge(t, collector, batteryPower, prometheus.Labels{"id": "192.168.1.20", "instance": "1"}) {
+		t.Errorf("gauge of fresh state should exist")
+	}
+	if hasGauge(t, collector, batteryPower, prometheus.Labels{"id": "192.168.1.20", "instance": "2"}) {
+		t.Errorf("gauge of stale state should not exist")
+	}
+	if _, ok := c.states[key]; ok {
+		t.Errorf("stale state should be dropped")
+	}
+}
+
+func TestDiscoverNode(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		if !req.DEOJ.isNodeProfile() {
+			t.Errorf("unexpected DEOJ: %s", req.DEOJ)
+			return nil
+		}
+		props := []Property{{Code: byte(InstanceListS), Len: 4, Data: Data{0x01, 0x01, 0x30, 0x01}}}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, props)
+		return res.Serialize()
+	})
+
+	if err := c.DiscoverNode(ctx, "192.168.1.80"); err != nil {
+		t.Fatal(err)
+	}
+	nodes := c.Nodes()
+	if len(nodes) != 1 || nodes[0].Address != "192.168.1.80" {
+		t.Fatalf("node is not found: %v", nodes)
+	}
+	found := false
+	for _, obj := range nodes[0].Devices {
+		if obj == aircon {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("aircon is not found: %v", nodes[0].Devices)
+	}
+}
@@ -0,0 +1,116 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// NotSettableError is returned when the property is not in Set property map of the device
+type NotSettableError struct {
+	Object Object
+	Code   PropertyCode
+}
+
+func (e *NotSettableError) Error() string {
+	return fmt.Sprintf("EPC[%02x] is not settable for %s", byte(e.Code), e.Object)
+}
+
+// Device is client for an object at addr, which typed clients are built on.
+// Set returns *NotSettableError if the device doesn't accept the property,
+// and *ServiceError if the device responds with SetC_SNA.
+type Device struct {
+	controller *ControllerNode
+	Address    string
+	Object     Object
+
+	mu     sync.Mutex
+	setMap map[PropertyCode]bool
+	getMap map[PropertyCode]bool
+}
+
+// NewDevice returns Device for obj at addr
+func NewDevice(elc *ControllerNode, addr string, obj Object) *Device {
+	return &Device{controller: elc, Address: addr, Object: obj}
+}
+
+// Get reads properties of the device.
+// If some of properties are not available, available ones are returned without error.
+func (d *Device) Get(ctx context.Context, codes ...PropertyCode) ([]Property, error) {
+	props, err := d.controller.Get(ctx, d.Address, d.Object, codes...)
+	if _, ok := err.(*ServiceError); ok {
+		return props, nil
+	}
+	return props, err
+}
+
+// SettableProperties returns properties in Set property map (0x9E) of the device.
+// The map is read once and cached.
+func (d *Device) SettableProperties(ctx context.Context) (map[PropertyCode]bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.setMap != nil {
+		return d.setMap, nil
+	}
+	m, err := d.readPropertyMap(ctx, SetPropertyMap, "Set")
+	if err != nil {
+		return nil, err
+	}
+	d.setMap = m
+	return m, nil
+}
+
+// GettableProperties returns properties in Get property map (0x9F) of the device.
+// The map is read once and cached.
+func (d *Device) GettableProperties(ctx context.Context) (map[PropertyCode]bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.getMap != nil {
+		return d.getMap, nil
+	}
+	m, err := d.readPropertyMap(ctx, GetPropertyMap, "Get")
+	if err != nil {
+		return nil, err
+	}
+	d.getMap = m
+	return m, nil
+}
+
+// readPropertyMap reads property map of code from the device
+func (d *Device) readPropertyMap(ctx context.Context, code PropertyCode, name string) (map[PropertyCode]bool, error) {
+	props, err := d.controller.Get(ctx, d.Address, d.Object, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s property map: %w", name, err)
+	}
+	p, ok := Frame{Properties: props}.Property(code)
+	if !ok {
+		return nil, fmt.Errorf("%s property map not found", name)
+	}
+	codes, err := ParsePropertyMap(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	m := map[PropertyCode]bool{}
+	for _, c := range codes {
+		m[c] = true
+	}
+	return m, nil
+}
+
+// Set writes properties at once after checking they are settable
+func (d *Device) Set(ctx context.Context, props ...Property) error {
+	settable, err := d.SettableProperties(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range props {
+		if !settable[PropertyCode(p.Code)] {
+			return &NotSettableError{Object: d.Object, Code: PropertyCode(p.Code)}
+		}
+	}
+	return d.controller.Set(ctx, d.Address, d.Object, props...)
+}
+
+func byteProperty(code PropertyCode, d byte) Property {
+	return Property{Code: byte(code), Len: 1, Data: Data{d}}
+}
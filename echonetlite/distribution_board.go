@@ -0,0 +1,286 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxChannelsPerQuery is max number of channels in a list property (PDC <= 255)
+const maxChannelsPerQuery = 63
+
+// ChannelValue is measured value of a channel of distribution board.
+// Value is nil if it is not available.
+type ChannelValue struct {
+	Channel int
+	Value   *float64
+}
+
+// DistributionBoardObject is object for distribution board metering.
+// Channels is zero if the board doesn't provide it,
+// values of main breaker and channels are nil if they are not available or out of range,
+// and cumulative energies are nil without the unit (0xC2) in the same frame.
+type DistributionBoardObject struct {
+	Instance            int
+	Channels            int            // 計測チャンネル数(片方向)
+	CumulativeEnergy    *float64       // 主幹積算電力量計測値(正方向) [kWh]
+	CumulativeEnergyRev *float64       // 主幹積算電力量計測値(逆方向) [kWh]
+	InstantPower        *float64       // 主幹瞬時電力計測値 [W]
+	CurrentR            *float64       // 主幹瞬時電流計測値(R相) [A]
+	CurrentT            *float64       // 主幹瞬時電流計測値(T相) [A]
+	VoltageRS           *float64       // 主幹瞬時電圧計測値(R-S線間) [V]
+	VoltageST           *float64       // 主幹瞬時電圧計測値(S-T線間) [V]
+	ChannelEnergy       []ChannelValue // 積算電力量計測値リスト [kWh]
+	ChannelPower        []ChannelValue // 瞬時電力計測値リスト [W]
+}
+
+// boardMainProperties is identification number and properties of main breaker, which are requested in every Get to distribution board
+var boardMainProperties = []PropertyCode{
+	ID,
+	BoardCumulativeEnergy,
+	BoardCumulativeEnergyRev,
+	BoardCumulativeEnergyUnit,
+	BoardInstantPower,
+	BoardInstantCurrent,
+	BoardInstantVoltage,
+}
+
+// parseChannelList parses list of channel values: start channel, number of channels and 4 bytes values
+func parseChannelList(d []byte, t NumberType, scale float64) []ChannelValue {
+	if len(d) < 2 {
+		return nil
+	}
+	start, n := int(d[0]), int(d[1])
+	if len(d) != 2+n*t.Size() {
+		logger.Printf("[Error] channel list invalid length: %d", len(d))
+		return nil
+	}
+	values := make([]ChannelValue, 0, n)
+	for i := 0; i < n; i++ {
+		off := 2 + i*t.Size()
+		values = append(values, ChannelValue{Channel: start + i, Value: measured(t, d[off:off+t.Size()], scale)})
+	}
+	return values
+}
+
+// measuredPair decodes 2 values of t in d
+func measuredPair(t NumberType, d []byte, scale float64) (*float64, *float64) {
+	if len(d) != 2*t.Size() {
+		return nil, nil
+	}
+	return measured(t, d[:t.Size()], scale), measured(t, d[t.Size():], scale)
+}
+
+func parseDistributionBoardProperties(instance int, properties []Property) DistributionBoardObject {
+	obj := DistributionBoardObject{Instance: instance}
+
+	// 積算電力量は同じフレームの単位で換算する
+	unit, hasUnit := 0.0, false
+	if p, ok := (Frame{Properties: properties}).Property(BoardCumulativeEnergyUnit); ok && len(p.Data) == 1 {
+		unit, hasUnit = integralPowerUnits[p.Data[0]]
+	}
+
+	for _, p := range properties {
+		switch PropertyCode(p.Code) {
+		case BoardChannels:
+			if len(p.Data) == 1 {
+				obj.Channels = int(p.Data[0])
+			}
+		case BoardCumulativeEnergy:
+			if hasUnit {
+				obj.CumulativeEnergy = measured(UnsignedLong, p.Data, unit)
+			}
+		case BoardCumulativeEnergyRev:
+			if hasUnit {
+				obj.CumulativeEnergyRev = measured(UnsignedLong, p.Data, unit)
+			}
+		case BoardInstantPower:
+			obj.InstantPower = measured(SignedLong, p.Data, 1)
+		case BoardInstantCurrent:
+			obj.CurrentR, obj.CurrentT = measuredPair(SignedShort, p.Data, 0.1)
+		case BoardInstantVoltage:
+			obj.VoltageRS, obj.VoltageST = measuredPair(UnsignedShort, p.Data, 0.1)
+		case BoardEnergyList:
+			if hasUnit {
+				obj.ChannelEnergy = parseChannelList(p.Data, UnsignedLong, unit)
+			}
+		case BoardPowerList:
+			obj.ChannelPower = parseChannelList(p.Data, SignedLong, 1)
+		}
+	}
+	return obj
+}
+
+// merge returns o with values of prev which are not in o.
+// Channel lists have only the range requested, so channels out of the range are kept.
+func (o DistributionBoardObject) merge(prev DistributionBoardObject) DistributionBoardObject {
+	if o.Channels == 0 {
+		o.Channels = prev.Channels
+	}
+	o.ChannelEnergy = mergeChannels(o.ChannelEnergy, prev.ChannelEnergy)
+	o.ChannelPower = mergeChannels(o.ChannelPower, prev.ChannelPower)
+	return o
+}
+
+// mergeChannels returns values with prev of channels not in values, sorted by channel
+func mergeChannels(values, prev []ChannelValue) []ChannelValue {
+	merged := map[int]ChannelValue{}
+	for _, c := range prev {
+		merged[c.Channel] = c
+	}
+	for _, c := range values {
+		merged[c.Channel] = c
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	list := make([]ChannelValue, 0, len(merged))
+	for _, c := range merged {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Channel < list[j].Channel })
+	return list
+}
+
+// RequestDistributionBoardState sends request to get main breaker states of each distribution board found in instance lists
+func (elc *ControllerNode) RequestDistributionBoardState() {
+	elc.requestClass(HomeEquipmentGroup, DistributionBoard, append([]PropertyCode{BoardChannels}, boardMainProperties...))
+}
+
+// RequestDistributionBoardChannels reads all channels of distribution boards found in instance lists.
+// Metrics are updated by their responses.
+func (elc *ControllerNode) RequestDistributionBoardChannels(ctx context.Context) {
+	for _, n := range elc.Nodes() {
+		for _, obj := range n.Devices {
+			if obj.ClassGroup != HomeEquipmentGroup || obj.Class != DistributionBoard {
+				continue
+			}
+			b := NewDistributionBoardClient(elc, n.Address, obj.Num)
+			if _, _, err := b.AllChannels(ctx); err != nil {
+				clogger.Printf("[Error] failed to read channels of %s [%s]: %s", n.Address, obj, err)
+			}
+		}
+	}
+}
+
+func newBoardGauge(name, help string, labels ...string) *gauge {
+	return newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "distribution_board",
+			Name:      name,
+			Help:      help,
+		},
+		append([]string{"id", "instance"}, labels...),
+	)
+}
+
+var (
+	boardEnergy        = newBoardGauge("cumulative_energy_kwh", "cumulative electric energy of main breaker [kWh]", "direction")
+	boardPower         = newBoardGauge("power_watts", "instantaneous electric power of main breaker [W]")
+	boardCurrent       = newBoardGauge("current_amperes", "instantaneous current of main breaker [A]", "phase")
+	boardVoltage       = newBoardGauge("voltage_volts", "instantaneous voltage of main breaker [V]", "phase")
+	boardChannelEnergy = newBoardGauge("channel_cumulative_energy_kwh", "cumulative electric energy consumption of channel [kWh]", "channel")
+	boardChannelPower  = newBoardGauge("channel_power_watts", "instantaneous electric power consumption of channel [W]", "channel")
+)
+
+func collectDistributionBoardMetrics(w *metricWriter, base prometheus.Labels, o DistributionBoardObject) {
+	labels := withLabel(base, "instance", strconv.Itoa(o.Instance))
+	w.setGauge(boardEnergy, withLabel(labels, "direction", "normal"), o.CumulativeEnergy)
+	w.setGauge(boardEnergy, withLabel(labels, "direction", "reverse"), o.CumulativeEnergyRev)
+	w.setGauge(boardPower, labels, o.InstantPower)
+	w.setGauge(boardCurrent, withLabel(labels, "phase", "r"), o.CurrentR)
+	w.setGauge(boardCurrent, withLabel(labels, "phase", "t"), o.CurrentT)
+	w.setGauge(boardVoltage, withLabel(labels, "phase", "rs"), o.VoltageRS)
+	w.setGauge(boardVoltage, withLabel(labels, "phase", "st"), o.VoltageST)
+
+	// リストは範囲指定した分だけ更新する
+	for _, c := range o.ChannelEnergy {
+		w.setGauge(boardChannelEnergy, withLabel(labels, "channel", strconv.Itoa(c.Channel)), c.Value)
+	}
+	for _, c := range o.ChannelPower {
+		w.setGauge(boardChannelPower, withLabel(labels, "channel", strconv.Itoa(c.Channel)), c.Value)
+	}
+}
+
+// DistributionBoardClient is client to read distribution board metering (0x0287)
+type DistributionBoardClient struct {
+	*Device
+}
+
+// NewDistributionBoardClient returns DistributionBoardClient for the instance at addr
+func NewDistributionBoardClient(elc *ControllerNode, addr string, instance int) *DistributionBoardClient {
+	return &DistributionBoardClient{NewDevice(elc, addr, NewObject(HomeEquipmentGroup, DistributionBoard, instance))}
+}
+
+// State reads number of channels and current state of main breaker
+func (b *DistributionBoardClient) State(ctx context.Context) (DistributionBoardObject, error) {
+	props, err := b.Get(ctx, append([]PropertyCode{BoardChannels}, boardMainProperties...)...)
+	if err != nil {
+		return DistributionBoardObject{}, err
+	}
+	return parseDistributionBoardProperties(b.Object.Num, props), nil
+}
+
+// queryChannels sets channel range to rangeCode, then reads listCode with main breaker properties
+func (b *DistributionBoardClient) queryChannels(ctx context.Context, rangeCode, listCode PropertyCode, start, count int) (DistributionBoardObject, error) {
+	if start < 1 || start > 252 {
+		return DistributionBoardObject{}, fmt.Errorf("start channel out of range [1-252]: %d", start)
+	}
+	if count < 1 || count > maxChannelsPerQuery {
+		return DistributionBoardObject{}, fmt.Errorf("number of channels out of range [1-%d]: %d", maxChannelsPerQuery, count)
+	}
+	err := b.Set(ctx, Property{Code: byte(rangeCode), Len: 2, Data: Data{byte(start), byte(count)}})
+	if err != nil {
+		return DistributionBoardObject{}, err
+	}
+	props, err := b.Get(ctx, append([]PropertyCode{listCode}, boardMainProperties...)...)
+	if err != nil {
+		return DistributionBoardObject{}, err
+	}
+	return parseDistributionBoardProperties(b.Object.Num, props), nil
+}
+
+// ChannelEnergy reads cumulative energy [kWh] of count channels from start (0xB2, 0xB3)
+func (b *DistributionBoardClient) ChannelEnergy(ctx context.Context, start, count int) ([]ChannelValue, error) {
+	obj, err := b.queryChannels(ctx, BoardEnergyChannelRange, BoardEnergyList, start, count)
+	return obj.ChannelEnergy, err
+}
+
+// ChannelPower reads instantaneous power [W] of count channels from start (0xB6, 0xB7)
+func (b *DistributionBoardClient) ChannelPower(ctx context.Context, start, count int) ([]ChannelValue, error) {
+	obj, err := b.queryChannels(ctx, BoardPowerChannelRange, BoardPowerList, start, count)
+	return obj.ChannelPower, err
+}
+
+// AllChannels reads cumulative energy and instantaneous power of all channels
+func (b *DistributionBoardClient) AllChannels(ctx context.Context) (energy, power []ChannelValue, err error) {
+	props, err := b.Get(ctx, BoardChannels)
+	if err != nil {
+		return nil, nil, err
+	}
+	channels := parseDistributionBoardProperties(b.Object.Num, props).Channels
+	if channels == 0 {
+		return nil, nil, fmt.Errorf("number of channels is not available")
+	}
+	for start := 1; start <= channels; start += maxChannelsPerQuery {
+		count := channels - start + 1
+		if count > maxChannelsPerQuery {
+			count = maxChannelsPerQuery
+		}
+		e, err := b.ChannelEnergy(ctx, start, count)
+		if err != nil {
+			return nil, nil, err
+		}
+		energy = append(energy, e...)
+		p, err := b.ChannelPower(ctx, start, count)
+		if err != nil {
+			return nil, nil, err
+		}
+		power = append(power, p...)
+	}
+	return energy, power, nil
+}
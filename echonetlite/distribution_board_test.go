@@ -0,0 +1,154 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var boardMainValues = []Property{
+	{Code: 0xc0, Len: 4, Data: Data{0x00, 0x00, 0x30, 0x39}},
+	{Code: 0xc1, Len: 4, Data: Data{0xff, 0xff, 0xff, 0xfe}},
+	{Code: 0xc2, Len: 1, Data: Data{0x01}},
+	{Code: 0xc6, Len: 4, Data: Data{0x00, 0x00, 0x04, 0xb0}},
+	{Code: 0xc7, Len: 4, Data: Data{0x00, 0x64, 0x7f, 0xfe}},
+	{Code: 0xc8, Len: 4, Data: Data{0x04, 0xb0, 0xff, 0xfe}},
+}
+
+func TestParseDistributionBoardProperties(t *testing.T) {
+	t.Parallel()
+
+	props := append([]Property{
+		{Code: 0xb3, Len: 10, Data: Data{0x02, 0x02, 0x00, 0x00, 0x00, 0x64, 0xff, 0xff, 0xff, 0xff}},
+		{Code: 0xb7, Len: 6, Data: Data{0x05, 0x01, 0xff, 0xff, 0xff, 0xf6}},
+	}, boardMainValues...)
+	got, err := parseProperties(NewObject(HomeEquipmentGroup, DistributionBoard, 1), props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DistributionBoardObject{
+		Instance:         1,
+		CumulativeEnergy: float64Ptr(1234.5),
+		InstantPower:     float64Ptr(1200),
+		CurrentR:         float64Ptr(10),
+		VoltageRS:        float64Ptr(120),
+		ChannelEnergy:    []ChannelValue{{Channel: 2, Value: float64Ptr(10)}, {Channel: 3}},
+		ChannelPower:     []ChannelValue{{Channel: 5, Value: float64Ptr(-10)}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// 単位がなければ積算電力量は換算できない
+	got, _ = parseProperties(NewObject(HomeEquipmentGroup, DistributionBoard, 1), boardMainValues[:2])
+	if diff := cmp.Diff(DistributionBoardObject{Instance: 1}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestCollectDistributionBoardMetrics(t *testing.T) {
+	obj := parseDistributionBoardProperties(1, append([]Property{
+		{Code: 0xb7, Len: 10, Data: Data{0x01, 0x02, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0xc8}},
+	}, boardMainValues...))
+	c := collectFunc(func(w *metricWriter) {
+		collectDistributionBoardMetrics(w, prometheus.Labels{"id": "192.168.1.22"}, obj)
+	})
+
+	labels := prometheus.Labels{"id": "192.168.1.22", "instance": "1"}
+	if got := gaugeValue(t, c, boardPower, labels); got != 1200 {
+		t.Errorf("power differs: want:1200 got:%v", got)
+	}
+	if got := gaugeValue(t, c, boardEnergy, withLabel(labels, "direction", "normal")); got != 1234.5 {
+		t.Errorf("energy differs: want:1234.5 got:%v", got)
+	}
+	if hasGauge(t, c, boardCurrent, withLabel(labels, "phase", "t")) {
+		t.Errorf("gauge for unavailable phase should not exist")
+	}
+	if got := gaugeValue(t, c, boardChannelPower, withLabel(labels, "channel", "2")); got != 200 {
+		t.Errorf("channel power differs: want:200 got:%v", got)
+	}
+}
+
+func TestDistributionBoardClient_AllChannels(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var start, count byte
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		esv := GetRes
+		switch req.ESV {
+		case Get:
+			if _, ok := req.Property(SetPropertyMap); ok {
+				props = append(props, Property{Code: 0x9e, Len: 3, Data: Data{0x02, 0xb2, 0xb6}})
+				break
+			}
+			if _, ok := req.Property(BoardChannels); ok {
+				props = append(props, Property{Code: 0xb1, Len: 1, Data: Data{0x03}})
+				break
+			}
+			code := req.Properties[0].Code
+			d := Data{start, count}
+			for i := byte(0); i < count; i++ {
+				d = append(d, 0x00, 0x00, 0x00, 10*(start+i))
+			}
+			props = append(props, Property{Code: code, Len: len(d), Data: d})
+			props = append(props, boardMainValues...)
+		case SetC:
+			esv = SetRes
+			start, count = req.Properties[0].Data[0], req.Properties[0].Data[1]
+			props = append(props, Property{Code: req.Properties[0].Code, Len: 0, Data: Data{}})
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, esv, props)
+		return res.Serialize()
+	})
+
+	b := NewDistributionBoardClient(c, "192.168.1.22", 1)
+	energy, power, err := b.AllChannels(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEnergy := []ChannelValue{{1, float64Ptr(1)}, {2, float64Ptr(2)}, {3, float64Ptr(3)}}
+	if diff := cmp.Diff(wantEnergy, energy); diff != "" {
+		t.Errorf("energy differs: (-want +got):\n%s", diff)
+	}
+	wantPower := []ChannelValue{{1, float64Ptr(10)}, {2, float64Ptr(20)}, {3, float64Ptr(30)}}
+	if diff := cmp.Diff(wantPower, power); diff != "" {
+		t.Errorf("power differs: (-want +got):\n%s", diff)
+	}
+
+	if _, err := b.ChannelPower(ctx, 1, maxChannelsPerQuery+1); err == nil {
+		t.Errorf("error expected for too many channels")
+	}
+}
+
+func TestDistributionBoardObject_Merge(t *testing.T) {
+	prev := DistributionBoardObject{
+		Instance:     1,
+		Channels:     3,
+		InstantPower: float64Ptr(1000),
+		ChannelPower: []ChannelValue{{Channel: 1, Value: float64Ptr(100)}, {Channel: 2, Value: float64Ptr(200)}},
+	}
+	got := DistributionBoardObject{
+		Instance:      1,
+		InstantPower:  float64Ptr(1200),
+		ChannelPower:  []ChannelValue{{Channel: 2, Value: float64Ptr(250)}, {Channel: 3, Value: nil}},
+		ChannelEnergy: []ChannelValue{{Channel: 1, Value: float64Ptr(1.5)}},
+	}.merge(prev)
+
+	want := DistributionBoardObject{
+		Instance:      1,
+		Channels:      3,
+		InstantPower:  float64Ptr(1200),
+		ChannelPower:  []ChannelValue{{Channel: 1, Value: float64Ptr(100)}, {Channel: 2, Value: float64Ptr(250)}, {Channel: 3, Value: nil}},
+		ChannelEnergy: []ChannelValue{{Channel: 1, Value: float64Ptr(1.5)}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
@@ -4,25 +4,34 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
-	gpower = prometheus.NewGauge(
+	gpower = newGauge(
 		prometheus.GaugeOpts{
 			Namespace: "home",
 			Subsystem: "smartmeter_exporter",
 			Name:      "instantpower",
-			Help:      "",
+			Help:      "instantaneous electric power [W]",
 		},
+		nil,
+	)
+	genergy = newGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "smartmeter_exporter",
+			Name:      "cumulative_energy_kwh",
+			Help:      "cumulative electric energy [kWh]",
+		},
+		[]string{"direction"},
 	)
 )
 
-func init() {
-	prometheus.MustRegister(gpower)
-}
-
 // SmartMeterClient is interface for smart-meter cleint
 type SmartMeterClient interface {
 	Connect(ctx context.Context, bRouteID, bRoutePW string) error
@@ -33,20 +42,122 @@ type SmartMeterClient interface {
 // ElectricityControllerNode is node for smart-meter
 type ElectricityControllerNode struct {
 	client SmartMeterClient
+	tid    uint16
+
+	mu          sync.Mutex
+	readings    map[string]Reading
+	subscribers map[chan Reading]struct{}
+}
+
+// Names of readings of smart-meter
+const (
+	ReadingPower         = "power"          // instantaneous electric power [W]
+	ReadingEnergyNormal  = "energy_normal"  // cumulative electric energy in normal direction [kWh]
+	ReadingEnergyReverse = "energy_reverse" // cumulative electric energy in reverse direction [kWh]
+)
+
+// Reading is a value read from smart-meter and the time it is read
+type Reading struct {
+	Name    string
+	Unit    string
+	Value   float64
+	Updated time.Time
 }
 
 // NewElectricityControllerNode returns ElectricityControllerNode instance
 func NewElectricityControllerNode(c SmartMeterClient) *ElectricityControllerNode {
-	return &ElectricityControllerNode{c}
+	return &ElectricityControllerNode{client: c}
 }
 
 // Close closes client
-func (n ElectricityControllerNode) Close() {
+func (n *ElectricityControllerNode) Close() {
 	n.client.Close()
 }
 
+// record keeps v as the last reading of name, which is exported by Collector on scrape and sent to subscribers
+func (n *ElectricityControllerNode) record(name, unit string, v float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.readings == nil {
+		n.readings = map[string]Reading{}
+	}
+	r := Reading{Name: name, Unit: unit, Value: v, Updated: time.Now()}
+	n.readings[name] = r
+	for ch := range n.subscribers {
+		select {
+		case ch <- r:
+		default:
+		}
+	}
+}
+
+// Readings returns the last readings sorted by name
+func (n *ElectricityControllerNode) Readings() []Reading {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	readings := make([]Reading, 0, len(n.readings))
+	for _, r := range n.readings {
+		readings = append(readings, r)
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Name < readings[j].Name })
+	return readings
+}
+
+// Subscribe returns channel which receives readings and function to stop subscription.
+// Readings are dropped if the channel is not read.
+func (n *ElectricityControllerNode) Subscribe() (<-chan Reading, func()) {
+	ch := make(chan Reading, 16)
+
+	n.mu.Lock()
+	if n.subscribers == nil {
+		n.subscribers = map[chan Reading]struct{}{}
+	}
+	n.subscribers[ch] = struct{}{}
+	n.mu.Unlock()
+
+	return ch, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if _, ok := n.subscribers[ch]; ok {
+			delete(n.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Collector returns prometheus.Collector which exports the last readings of smart-meter
+func (n *ElectricityControllerNode) Collector() prometheus.Collector {
+	return &electricityCollector{node: n}
+}
+
+type electricityCollector struct {
+	node *ElectricityControllerNode
+}
+
+// Describe implements prometheus.Collector
+func (c *electricityCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeGauges(ch, []*gauge{gpower, genergy})
+}
+
+// Collect implements prometheus.Collector
+func (c *electricityCollector) Collect(ch chan<- prometheus.Metric) {
+	w := newMetricWriter(ch)
+	for _, r := range c.node.Readings() {
+		r := r
+		w.updated = r.Updated
+		switch r.Name {
+		case ReadingPower:
+			w.setGauge(gpower, nil, &r.Value)
+		case ReadingEnergyNormal:
+			w.setGauge(genergy, prometheus.Labels{"direction": "normal"}, &r.Value)
+		case ReadingEnergyReverse:
+			w.setGauge(genergy, prometheus.Labels{"direction": "reverse"}, &r.Value)
+		}
+	}
+}
+
 // Start starts to connect to smart-meter
-func (n ElectricityControllerNode) Start(ctx context.Context, bRouteID, bRoutePassword string) error {
+func (n *ElectricityControllerNode) Start(ctx context.Context, bRouteID, bRoutePassword string) error {
 	err := n.client.Connect(ctx, bRouteID, bRoutePassword)
 	if err != nil {
 		return fmt.Errorf("exec Connect failed: %v", err)
@@ -55,8 +166,9 @@ func (n ElectricityControllerNode) Start(ctx context.Context, bRouteID, bRoutePa
 }
 
 // GetPowerConsumption requests power consumption and receives
-func (n ElectricityControllerNode) GetPowerConsumption() (int, error) {
-	f := CreateCurrentPowerConsumptionFrame(1) // TODO: increment
+func (n *ElectricityControllerNode) GetPowerConsumption() (int, error) {
+	n.tid++
+	f := CreateCurrentPowerConsumptionFrame(n.tid)
 
 	rdata, err := n.client.Send(f.Serialize())
 	if err != nil {
@@ -80,7 +192,7 @@ func (n ElectricityControllerNode) GetPowerConsumption() (int, error) {
 					switch PropertyCode(p.Code) {
 					case InstantPower:
 						power := binary.BigEndian.Uint32(p.Data)
-						gpower.Set(float64(power))
+						n.record(ReadingPower, "W", float64(power))
 						logger.Printf("Power: %d [W]", power)
 						return int(power), nil
 					}
@@ -105,3 +217,120 @@ func CreateCurrentPowerConsumptionFrame(transID uint16) *Frame {
 	frame := NewFrame(transID, src, dest, Get, props)
 	return &frame
 }
+
+// get requests properties of smart-meter and returns the response
+func (n *ElectricityControllerNode) get(codes ...PropertyCode) (Frame, error) {
+	n.tid++
+	props := []Property{}
+	for _, c := range codes {
+		props = append(props, Property{Code: byte(c), Len: 0, Data: []byte{}})
+	}
+	f := NewFrame(n.tid, NewObject(ControllerGroup, Controller, 0x01), NewObject(HomeEquipmentGroup, LowVoltageSmartMeter, 0x01), Get, props)
+
+	rdata, err := n.client.Send(f.Serialize())
+	if err != nil {
+		return Frame{}, err
+	}
+	rf, err := ParseFrame(rdata)
+	if err != nil {
+		return Frame{}, fmt.Errorf("invalid frame: %w", err)
+	}
+	rf.Print()
+
+	if rf.ESV != GetRes && rf.ESV != GetSNA {
+		return Frame{}, fmt.Errorf("unexpected response: %s", rf.ESV)
+	}
+	return rf, nil
+}
+
+// GetInstantCurrent requests instantaneous current and returns R phase and T phase in ampere.
+// T phase is 0 for single-phase 2-wire meters.
+func (n *ElectricityControllerNode) GetInstantCurrent() (float64, float64, error) {
+	rf, err := n.get(InstantCurrent)
+	if err != nil {
+		return 0, 0, err
+	}
+	p, ok := rf.Property(InstantCurrent)
+	if !ok || len(p.Data) != 4 {
+		return 0, 0, fmt.Errorf("instant current is not available")
+	}
+
+	phase := func(d []byte) float64 {
+		// 0x7FFE: 相が存在しない
+		n, _ := DecodeNumber(SignedShort, d)
+		v, _ := n.Scaled(0.1)
+		return v
+	}
+	r, t := phase(p.Data[0:2]), phase(p.Data[2:4])
+	logger.Printf("Current: R:%.1f T:%.1f [A]", r, t)
+	return r, t, nil
+}
+
+// integralPowerUnits is multiplier for kWh by IntegralPowerConsumptionUnit
+var integralPowerUnits = map[byte]float64{
+	0x00: 1,
+	0x01: 0.1,
+	0x02: 0.01,
+	0x03: 0.001,
+	0x04: 0.0001,
+	0x0A: 10,
+	0x0B: 100,
+	0x0C: 1000,
+	0x0D: 10000,
+}
+
+// GetCumulativeEnergy requests cumulative energy (normal direction) and returns it in kWh
+func (n *ElectricityControllerNode) GetCumulativeEnergy() (float64, error) {
+	energy, err := n.cumulativeEnergy(IntegralPowerConsumption)
+	if err == nil {
+		n.record(ReadingEnergyNormal, "kWh", energy)
+	}
+	return energy, err
+}
+
+// GetReverseCumulativeEnergy requests cumulative energy (reverse direction, e.g. sold solar power) and returns it in kWh
+func (n *ElectricityControllerNode) GetReverseCumulativeEnergy() (float64, error) {
+	energy, err := n.cumulativeEnergy(IntegralPowerConsumptionRev)
+	if err == nil {
+		n.record(ReadingEnergyReverse, "kWh", energy)
+	}
+	return energy, err
+}
+
+func (n *ElectricityControllerNode) cumulativeEnergy(code PropertyCode) (float64, error) {
+	rf, err := n.get(code, IntegralPowerConsumptionUnit, Coefficient)
+	if err != nil {
+		return 0, err
+	}
+
+	p, ok := rf.Property(code)
+	if !ok || len(p.Data) != 4 {
+		return 0, fmt.Errorf("cumulative energy is not available")
+	}
+	value, err := DecodeNumber(UnsignedLong, p.Data)
+	if err != nil {
+		return 0, err
+	}
+	if !value.Valid() {
+		return 0, fmt.Errorf("cumulative energy is not measured")
+	}
+
+	unit := 1.0
+	if p, ok := rf.Property(IntegralPowerConsumptionUnit); ok && len(p.Data) == 1 {
+		u, ok := integralPowerUnits[p.Data[0]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit of cumulative energy: %#x", p.Data[0])
+		}
+		unit = u
+	}
+
+	// 係数は任意プロパティ、なければ1
+	coefficient := uint32(1)
+	if p, ok := rf.Property(Coefficient); ok && len(p.Data) == 4 {
+		coefficient = binary.BigEndian.Uint32(p.Data)
+	}
+
+	energy := float64(value.Value) * float64(coefficient) * unit
+	logger.Printf("Cumulative energy (%02x): %f [kWh]", byte(code), energy)
+	return energy, nil
+}
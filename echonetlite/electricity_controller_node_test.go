@@ -7,7 +7,8 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
-	"github.com/u-one/go-el-controller/wisun"
+	"github.com/matsuu/go-el-controller/wisun"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestStart(t *testing.T) {
@@ -149,6 +150,116 @@ func TestGetPowerConsumption(t *testing.T) {
 
 }
 
+func TestGetInstantCurrent(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock := wisun.NewMockClient(ctrl)
+	mock.EXPECT().
+		Send([]byte("\x10\x81\x00\x01\x05\xff\x01\x02\x88\x01\x62\x01\xe8\x00")).
+		Return([]byte("\x10\x81\x00\x01\x02\x88\x01\x05\xff\x01\x72\x01\xe8\x04\x00\x2a\x7f\xfe"), nil)
+
+	node := NewElectricityControllerNode(mock)
+	r, tp, err := node.GetInstantCurrent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != 4.2 || tp != 0 {
+		t.Errorf("Diffrent result: want:4.2 0, got:%v %v", r, tp)
+	}
+}
+
+func TestGetCumulativeEnergy(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name string
+		res  []byte
+		want float64
+		err  error
+	}{
+		{
+			name: "with coefficient",
+			res:  []byte("\x10\x81\x00\x01\x02\x88\x01\x05\xff\x01\x72\x03\xe0\x04\x00\x00\x30\x39\xe1\x01\x01\xd3\x04\x00\x00\x00\x02"),
+			want: 2469.0,
+		},
+		{
+			name: "without coefficient",
+			res:  []byte("\x10\x81\x00\x01\x02\x88\x01\x05\xff\x01\x52\x03\xe0\x04\x00\x00\x30\x39\xe1\x01\x0a\xd3\x00"),
+			want: 123450.0,
+		},
+		{
+			name: "not measured",
+			res:  []byte("\x10\x81\x00\x01\x02\x88\x01\x05\xff\x01\x72\x02\xe0\x04\xff\xff\xff\xfe\xe1\x01\x01"),
+			err:  fmt.Errorf("cumulative energy is not measured"),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mock := wisun.NewMockClient(ctrl)
+			mock.EXPECT().
+				Send([]byte("\x10\x81\x00\x01\x05\xff\x01\x02\x88\x01\x62\x03\xe0\x00\xe1\x00\xd3\x00")).
+				Return(tc.res, nil)
+
+			node := NewElectricityControllerNode(mock)
+			got, err := node.GetCumulativeEnergy()
+
+			if tc.err != nil && err != nil {
+				if tc.err.Error() != err.Error() {
+					t.Errorf("Diffrent result: want:%#v, got:%#v", tc.err, err)
+				}
+			} else if tc.err != err {
+				t.Errorf("Diffrent result: want:%#v, got:%#v", tc.err, err)
+			}
+
+			if diff := got - tc.want; diff > 1e-6 || diff < -1e-6 {
+				t.Errorf("Diffrent result: want:%v, got:%v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetReverseCumulativeEnergy(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock := wisun.NewMockClient(ctrl)
+	mock.EXPECT().
+		Send([]byte("\x10\x81\x00\x01\x05\xff\x01\x02\x88\x01\x62\x03\xe3\x00\xe1\x00\xd3\x00")).
+		Return([]byte("\x10\x81\x00\x01\x02\x88\x01\x05\xff\x01\x72\x03\xe3\x04\x00\x00\x04\xd2\xe1\x01\x01\xd3\x04\x00\x00\x00\x01"), nil)
+
+	node := NewElectricityControllerNode(mock)
+	readings, stop := node.Subscribe()
+	defer stop()
+
+	got, err := node.GetReverseCumulativeEnergy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := got - 123.4; diff > 1e-6 || diff < -1e-6 {
+		t.Errorf("Diffrent result: want:123.4, got:%v", got)
+	}
+	if r := <-readings; r.Name != ReadingEnergyReverse || r.Unit != "kWh" || r.Value != got {
+		t.Errorf("Diffrent reading: %+v", r)
+	}
+
+	c := node.Collector()
+	if v := gaugeValue(t, c, genergy, prometheus.Labels{"direction": "reverse"}); v != got {
+		t.Errorf("Diffrent metric: want:%v, got:%v", got, v)
+	}
+	if hasGauge(t, c, genergy, prometheus.Labels{"direction": "normal"}) {
+		t.Errorf("metric should not exist before it is read")
+	}
+}
+
 /*
 func Test_Get(t *testing.T) {
 	ctrl := gomock.NewController(t)
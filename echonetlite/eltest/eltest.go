@@ -0,0 +1,172 @@
+// Package eltest provides fake ECHONET Lite controller and class dictionary for tests of packages using echonetlite
+package eltest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+// Address is IP address of the node of the fake controller, and DeviceID is ID of its objects
+const (
+	Address  = "192.168.0.10"
+	DeviceID = "dev1"
+)
+
+// Objects of the node of the fake controller
+var (
+	Aircon = echonetlite.NewObject(echonetlite.AirConditionerGroup, echonetlite.HomeAirConditioner, 1)
+	EV     = echonetlite.NewObject(echonetlite.HomeEquipmentGroup, echonetlite.EVChargerDischarger, 1)
+)
+
+// Dictionary returns class dictionary of Aircon and EV
+func Dictionary() echonetlite.ClassDictionary {
+	return echonetlite.ClassDictionary{
+		echonetlite.AirConditionerGroup: {
+			echonetlite.HomeAirConditioner: {
+				ClassGroup: echonetlite.AirConditionerGroup,
+				Class:      echonetlite.HomeAirConditioner,
+				Desc:       "家庭用エアコン",
+				Properties: echonetlite.PropertyDictionary{
+					0x80: {Code: 0x80, Detail: "動作状態", DataType: "unsigned char", Values: map[byte]string{0x30: "ON", 0x31: "OFF"}},
+					0xb0: {Code: 0xb0, Detail: "運転モード設定", DataType: "unsigned char", Values: map[byte]string{0x41: "自動", 0x42: "冷房"}},
+					0xb3: {Code: 0xb3, Detail: "温度設定値", Unit: "℃", DataType: "unsigned char"},
+					0xbb: {Code: 0xbb, Detail: "室内温度計測値", Unit: "℃", DataType: "signed char"},
+				},
+			},
+		},
+		echonetlite.HomeEquipmentGroup: {
+			echonetlite.EVChargerDischarger: {
+				ClassGroup: echonetlite.HomeEquipmentGroup,
+				Class:      echonetlite.EVChargerDischarger,
+				Desc:       "車載電池充放電システム",
+				Properties: echonetlite.PropertyDictionary{
+					0xda: {Code: 0xda, Detail: "運転モード設定", DataType: "unsigned char", Values: map[byte]string{0x42: "充電", 0x43: "放電", 0x44: "待機"}},
+				},
+			},
+		},
+	}
+}
+
+// Controller is fake echonetlite.NodeController of a node at Address, whose objects are Aircon and EV.
+// Aircon is found by Nodes, and both objects are found by LookupDevice with DeviceID or Address.
+// Objects share property values, and responses to Get are delivered to subscribers.
+type Controller struct {
+	mu          sync.Mutex
+	values      map[echonetlite.PropertyCode]echonetlite.Property
+	subscribers map[chan echonetlite.Message]struct{}
+}
+
+var _ echonetlite.NodeController = (*Controller)(nil)
+
+// NewController returns Controller whose aircon is ON, set to cool at 26℃ and measures -2℃
+func NewController() *Controller {
+	return &Controller{
+		values: map[echonetlite.PropertyCode]echonetlite.Property{
+			0x80:                       {Code: 0x80, Len: 1, Data: echonetlite.Data{0x30}},
+			0xb0:                       {Code: 0xb0, Len: 1, Data: echonetlite.Data{0x42}},
+			0xb3:                       {Code: 0xb3, Len: 1, Data: echonetlite.Data{0x1a}},
+			0xbb:                       {Code: 0xbb, Len: 1, Data: echonetlite.Data{0xfe}},
+			echonetlite.GetPropertyMap: {Code: 0x9f, Len: 5, Data: echonetlite.Data{0x04, 0x80, 0xb0, 0xb3, 0xbb}},
+			echonetlite.SetPropertyMap: {Code: 0x9e, Len: 4, Data: echonetlite.Data{0x03, 0x80, 0xb0, 0xb3}},
+		},
+		subscribers: map[chan echonetlite.Message]struct{}{},
+	}
+}
+
+// Nodes implements echonetlite.NodeController
+func (c *Controller) Nodes() []echonetlite.Node {
+	return []echonetlite.Node{{
+		Address:  Address,
+		Devices:  []echonetlite.Object{Aircon},
+		MAC:      net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55},
+		LastSeen: time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+	}}
+}
+
+// Subscribe implements echonetlite.NodeController
+func (c *Controller) Subscribe() (<-chan echonetlite.Message, func()) {
+	ch := make(chan echonetlite.Message, 16)
+	c.mu.Lock()
+	c.subscribers[ch] = struct{}{}
+	c.mu.Unlock()
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers, ch)
+	}
+}
+
+// DeviceID implements echonetlite.NodeController
+func (c *Controller) DeviceID(addr string, obj echonetlite.Object) string {
+	return DeviceID
+}
+
+// LookupDevice implements echonetlite.NodeController
+func (c *Controller) LookupDevice(id string, obj echonetlite.Object) (string, bool) {
+	if (id == DeviceID || id == Address) && (obj == Aircon || obj == EV) {
+		return Address, true
+	}
+	return "", false
+}
+
+// Get implements echonetlite.NodeController
+func (c *Controller) Get(ctx context.Context, addr string, obj echonetlite.Object, codes ...echonetlite.PropertyCode) ([]echonetlite.Property, error) {
+	c.mu.Lock()
+	props := []echonetlite.Property{}
+	for _, code := range codes {
+		props = append(props, c.values[code])
+	}
+	c.mu.Unlock()
+	c.send(obj, echonetlite.GetRes, props...)
+	return props, nil
+}
+
+// Set implements echonetlite.NodeController.
+// Temperature setting (0xB3) over 50℃ is rejected with *echonetlite.ServiceError.
+func (c *Controller) Set(ctx context.Context, addr string, obj echonetlite.Object, props ...echonetlite.Property) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range props {
+		if p.Code == 0xb3 && p.Data[0] > 50 {
+			res := echonetlite.NewFrame(1, obj, controllerObject(), echonetlite.SetCSNA, props)
+			return &echonetlite.ServiceError{Address: addr, Frame: res}
+		}
+	}
+	for _, p := range props {
+		c.values[echonetlite.PropertyCode(p.Code)] = p
+	}
+	return nil
+}
+
+// Value returns the current value of property
+func (c *Controller) Value(code echonetlite.PropertyCode) echonetlite.Property {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[code]
+}
+
+// Receive emulates frame from Aircon
+func (c *Controller) Receive(esv echonetlite.ESVType, props ...echonetlite.Property) {
+	c.send(Aircon, esv, props...)
+}
+
+func (c *Controller) send(obj echonetlite.Object, esv echonetlite.ESVType, props ...echonetlite.Property) {
+	f := echonetlite.NewFrame(1, obj, controllerObject(), esv, props)
+	c.mu.Lock()
+	subscribers := []chan echonetlite.Message{}
+	for ch := range c.subscribers {
+		subscribers = append(subscribers, ch)
+	}
+	c.mu.Unlock()
+	for _, ch := range subscribers {
+		ch <- echonetlite.Message{Address: Address + ":3610", Frame: f}
+	}
+}
+
+func controllerObject() echonetlite.Object {
+	return echonetlite.NewObject(echonetlite.ControllerGroup, echonetlite.Controller, 1)
+}
@@ -0,0 +1,345 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// EVConnection is vehicle connection and chargeable/dischargeable status of EV charger/discharger (0xC7)
+type EVConnection byte
+
+// definition of vehicle connection status
+const (
+	EVNotConnected                     EVConnection = 0x30 // 未接続
+	EVConnected                        EVConnection = 0x40 // 接続・充電不可・放電不可
+	EVConnectedChargeable              EVConnection = 0x41 // 接続・充電可・放電不可
+	EVConnectedDischargeable           EVConnection = 0x42 // 接続・充電不可・放電可
+	EVConnectedChargeableDischargeable EVConnection = 0x43 // 接続・充電可・放電可
+	EVConnectedUnknown                 EVConnection = 0x44 // 接続・充放電可否不明
+	EVConnectionUndetermined           EVConnection = 0xFF // 不定
+)
+
+var evConnectionNames = map[EVConnection]string{
+	EVNotConnected:                     "not_connected",
+	EVConnected:                        "connected",
+	EVConnectedChargeable:              "chargeable",
+	EVConnectedDischargeable:           "dischargeable",
+	EVConnectedChargeableDischargeable: "chargeable_dischargeable",
+	EVConnectedUnknown:                 "connected_unknown",
+	EVConnectionUndetermined:           "undetermined",
+}
+
+func (c EVConnection) String() string {
+	if s, ok := evConnectionNames[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(c))
+}
+
+// Connected returns true if vehicle is connected
+func (c EVConnection) Connected() bool {
+	return c >= EVConnected && c <= EVConnectedUnknown
+}
+
+// Chargeable returns true if vehicle is connected and chargeable
+func (c EVConnection) Chargeable() bool {
+	return c == EVConnectedChargeable || c == EVConnectedChargeableDischargeable
+}
+
+// Dischargeable returns true if vehicle is connected and dischargeable
+func (c EVConnection) Dischargeable() bool {
+	return c == EVConnectedDischargeable || c == EVConnectedChargeableDischargeable
+}
+
+// EVChargerMode is operation mode of EV charger/discharger (0xDA)
+type EVChargerMode byte
+
+// definition of EV charger/discharger operation modes
+const (
+	EVModeOther       EVChargerMode = 0x40
+	EVModeCharging    EVChargerMode = 0x42
+	EVModeDischarging EVChargerMode = 0x43
+	EVModeStandby     EVChargerMode = 0x44
+	EVModeIdle        EVChargerMode = 0x47
+)
+
+var evChargerModeNames = map[EVChargerMode]string{
+	EVModeOther:       "other",
+	EVModeCharging:    "charging",
+	EVModeDischarging: "discharging",
+	EVModeStandby:     "standby",
+	EVModeIdle:        "idle",
+}
+
+func (m EVChargerMode) String() string {
+	if s, ok := evChargerModeNames[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(m))
+}
+
+// ParseEVChargerMode returns mode from its name
+func ParseEVChargerMode(s string) (EVChargerMode, error) {
+	for m, name := range evChargerModeNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode: %s", s)
+}
+
+// EVChargerObject is object for EV charger/discharger.
+// Mode is zero if the device doesn't provide it, Connection is nil without a vehicle status,
+// and power, capacities and power settings are nil if they are not available or out of range.
+type EVChargerObject struct {
+	Instance                       int
+	Connection                     *EVConnection // 車両接続・充放電可否状態
+	Mode                           EVChargerMode // 運転モード設定
+	InstantPower                   *float64      // 瞬時充放電電力計測値 [W] (充電:+ 放電:-)
+	DischargeableCapacity          *float64      // 車載電池の放電可能容量値1 [Wh]
+	RemainingDischargeableCapacity *float64      // 車載電池の放電可能残容量1 [Wh]
+	ChargeableCapacity             *float64      // 車載電池の充電可能容量値 [Wh]
+	RemainingChargeableCapacity    *float64      // 車載電池の充電可能残容量値 [Wh]
+	RemainingCapacity              *float64      // 車載電池の電池残容量1 [Wh]
+	RemainingCapacityPercent       *float64      // 車載電池の電池残容量3 [%]
+	ChargingPower                  *float64      // 充電電力設定値 [W]
+	DischargingPower               *float64      // 放電電力設定値 [W]
+}
+
+// evChargerProperties is properties requested to EV charger/discharger
+var evChargerProperties = []PropertyCode{
+	ID,
+	EVConnectionStatus,
+	EVOperationMode,
+	EVInstantPower,
+	EVDischargeableCapacity1,
+	EVRemainingDischargeableCapacity1,
+	EVChargeableCapacity,
+	EVRemainingChargeableCapacity,
+	EVRemainingCapacity1,
+	EVRemainingCapacity3,
+	EVChargingPowerSetting,
+	EVDischargingPowerSetting,
+}
+
+func parseEVChargerProperty(p Property, obj *EVChargerObject) bool {
+	switch PropertyCode(p.Code) {
+	case EVConnectionStatus:
+		if len(p.Data) == 1 {
+			c := EVConnection(p.Data[0])
+			obj.Connection = &c
+		}
+		return true
+	case EVOperationMode:
+		if len(p.Data) == 1 {
+			obj.Mode = EVChargerMode(p.Data[0])
+		}
+		return true
+	case EVInstantPower:
+		obj.InstantPower = measured(SignedLong, p.Data, 1)
+		return true
+	case EVDischargeableCapacity1:
+		obj.DischargeableCapacity = measured(UnsignedLong, p.Data, 1)
+		return true
+	case EVRemainingDischargeableCapacity1:
+		obj.RemainingDischargeableCapacity = measured(UnsignedLong, p.Data, 1)
+		return true
+	case EVChargeableCapacity:
+		obj.ChargeableCapacity = measured(UnsignedLong, p.Data, 1)
+		return true
+	case EVRemainingChargeableCapacity:
+		obj.RemainingChargeableCapacity = measured(UnsignedLong, p.Data, 1)
+		return true
+	case EVRemainingCapacity1:
+		obj.RemainingCapacity = measured(UnsignedLong, p.Data, 1)
+		return true
+	case EVRemainingCapacity3:
+		obj.RemainingCapacityPercent = measured(UnsignedChar, p.Data, 1)
+		return true
+	case EVChargingPowerSetting:
+		obj.ChargingPower = measured(UnsignedLong, p.Data, 1)
+		return true
+	case EVDischargingPowerSetting:
+		obj.DischargingPower = measured(UnsignedLong, p.Data, 1)
+		return true
+	}
+	return false
+}
+
+// RequestEVChargerState sends request to each EV charger/discharger found in instance lists
+func (elc *ControllerNode) RequestEVChargerState() {
+	elc.requestClass(HomeEquipmentGroup, EVChargerDischarger, evChargerProperties)
+}
+
+func newEVChargerGauge(name, help string, labels ...string) *gauge {
+	return newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "ev_charger",
+			Name:      name,
+			Help:      help,
+		},
+		append([]string{"id", "instance"}, labels...),
+	)
+}
+
+var (
+	evPower                    = newEVChargerGauge("power_watts", "instantaneous charging (+) / discharging (-) electric power [W]")
+	evRemainingCapacity        = newEVChargerGauge("remaining_capacity_wh", "remaining capacity of vehicle mounted battery [Wh]")
+	evRemainingCapacityPercent = newEVChargerGauge("remaining_capacity_percent", "remaining capacity of vehicle mounted battery [%]")
+	evChargeableCapacity       = newEVChargerGauge("remaining_chargeable_capacity_wh", "remaining chargeable capacity of vehicle mounted battery [Wh]")
+	evDischargeableCapacity    = newEVChargerGauge("remaining_dischargeable_capacity_wh", "remaining dischargeable capacity of vehicle mounted battery [Wh]")
+	evConnectionState          = newEVChargerGauge("connection_state", "1 for the current vehicle connection and chargeable/dischargeable status", "state")
+	evOperationMode            = newEVChargerGauge("operation_mode", "1 for the current operation mode", "mode")
+)
+
+func collectEVChargerMetrics(w *metricWriter, base prometheus.Labels, o EVChargerObject) {
+	labels := withLabel(base, "instance", strconv.Itoa(o.Instance))
+	w.setGauge(evPower, labels, o.InstantPower)
+	w.setGauge(evRemainingCapacity, labels, o.RemainingCapacity)
+	w.setGauge(evRemainingCapacityPercent, labels, o.RemainingCapacityPercent)
+	w.setGauge(evChargeableCapacity, labels, o.RemainingChargeableCapacity)
+	w.setGauge(evDischargeableCapacity, labels, o.RemainingDischargeableCapacity)
+
+	if o.Connection != nil {
+		states := []string{}
+		for _, name := range evConnectionNames {
+			states = append(states, name)
+		}
+		w.setStateSet(evConnectionState, labels, "state", states, o.Connection.String())
+	}
+	if _, ok := evChargerModeNames[o.Mode]; ok {
+		modes := []string{}
+		for _, name := range evChargerModeNames {
+			modes = append(modes, name)
+		}
+		w.setStateSet(evOperationMode, labels, "mode", modes, o.Mode.String())
+	}
+}
+
+// EVSetPermission is opt-in flags which must be given to send Set to EV charger/discharger.
+// Zero value permits nothing.
+type EVSetPermission int
+
+// definition of EV charger/discharger Set permissions
+const (
+	EVAllowCharge    EVSetPermission = 1 << iota // 充電を伴う設定を許可
+	EVAllowDischarge                             // 放電を伴う設定を許可
+)
+
+// EVSetNotPermittedError is returned when Set to EV charger/discharger is not opted in
+type EVSetNotPermittedError struct {
+	Code     PropertyCode
+	Required EVSetPermission
+}
+
+func (e *EVSetNotPermittedError) Error() string {
+	var s string
+	switch e.Required {
+	case EVAllowCharge:
+		s = "charge"
+	case EVAllowDischarge:
+		s = "discharge"
+	default:
+		s = "charge and discharge"
+	}
+	return fmt.Sprintf("Set EPC[%02x] to EV charger/discharger requires permission to %s", byte(e.Code), s)
+}
+
+// CheckEVChargerSet returns *EVSetNotPermittedError if props are not allowed by perm.
+// Charging mode and charging power require EVAllowCharge, discharging mode and discharging power require EVAllowDischarge,
+// stopping (standby, idle) requires either of them, and any other property requires both.
+func CheckEVChargerSet(perm EVSetPermission, props ...Property) error {
+	for _, p := range props {
+		code := PropertyCode(p.Code)
+		required := EVAllowCharge | EVAllowDischarge
+		switch code {
+		case EVChargingPowerSetting:
+			required = EVAllowCharge
+		case EVDischargingPowerSetting:
+			required = EVAllowDischarge
+		case EVOperationMode:
+			if len(p.Data) != 1 {
+				break
+			}
+			switch EVChargerMode(p.Data[0]) {
+			case EVModeCharging:
+				required = EVAllowCharge
+			case EVModeDischarging:
+				required = EVAllowDischarge
+			case EVModeStandby, EVModeIdle:
+				if perm != 0 {
+					continue
+				}
+			}
+		}
+		if perm&required != required {
+			return &EVSetNotPermittedError{Code: code, Required: required}
+		}
+	}
+	return nil
+}
+
+// EVChargerClient is client to read and control EV charger/discharger (0x027E), including V2H.
+// Set is sent only if it is permitted by EVPermission of the controller.
+type EVChargerClient struct {
+	*Device
+}
+
+// NewEVChargerClient returns EVChargerClient for the instance at addr
+func NewEVChargerClient(elc *ControllerNode, addr string, instance int) *EVChargerClient {
+	return &EVChargerClient{Device: NewDevice(elc, addr, NewObject(HomeEquipmentGroup, EVChargerDischarger, instance))}
+}
+
+// State reads current state of the EV charger/discharger
+func (e *EVChargerClient) State(ctx context.Context) (EVChargerObject, error) {
+	props, err := e.Get(ctx, evChargerProperties...)
+	if err != nil {
+		return EVChargerObject{}, err
+	}
+	obj := EVChargerObject{Instance: e.Object.Num}
+	for _, p := range props {
+		parseEVChargerProperty(p, &obj)
+	}
+	return obj, nil
+}
+
+// SetMode changes operation mode to charging, discharging, standby or idle
+func (e *EVChargerClient) SetMode(ctx context.Context, m EVChargerMode) error {
+	switch m {
+	case EVModeCharging, EVModeDischarging, EVModeStandby, EVModeIdle:
+	default:
+		return fmt.Errorf("mode not allowed: %s", m)
+	}
+	return e.Set(ctx, byteProperty(EVOperationMode, byte(m)))
+}
+
+// powerProperty returns property of power setting in unsigned long
+func powerProperty(code PropertyCode, watts int) (Property, error) {
+	if watts < 0 || int64(watts) > 0xFFFFFFFD {
+		return Property{}, fmt.Errorf("power out of range: %d", watts)
+	}
+	w := uint32(watts)
+	return Property{Code: byte(code), Len: 4, Data: Data{byte(w >> 24), byte(w >> 16), byte(w >> 8), byte(w)}}, nil
+}
+
+// SetChargingPower changes charging electric power [W]
+func (e *EVChargerClient) SetChargingPower(ctx context.Context, watts int) error {
+	p, err := powerProperty(EVChargingPowerSetting, watts)
+	if err != nil {
+		return err
+	}
+	return e.Set(ctx, p)
+}
+
+// SetDischargingPower changes discharging electric power [W]
+func (e *EVChargerClient) SetDischargingPower(ctx context.Context, watts int) error {
+	p, err := powerProperty(EVDischargingPowerSetting, watts)
+	if err != nil {
+		return err
+	}
+	return e.Set(ctx, p)
+}
@@ -0,0 +1,171 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var evChargerValues = []Property{
+	{Code: 0xc7, Len: 1, Data: Data{0x43}},
+	{Code: 0xda, Len: 1, Data: Data{0x42}},
+	{Code: 0xd3, Len: 4, Data: Data{0x00, 0x00, 0x0b, 0xb8}},
+	{Code: 0xcf, Len: 4, Data: Data{0x00, 0x00, 0x4e, 0x20}},
+	{Code: 0xe2, Len: 4, Data: Data{0x00, 0x00, 0x75, 0x30}},
+	{Code: 0xe4, Len: 1, Data: Data{0x3c}},
+	{Code: 0xc2, Len: 0, Data: Data{}},
+}
+
+func TestParseEVChargerProperties(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseProperties(NewObject(HomeEquipmentGroup, EVChargerDischarger, 1), evChargerValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := EVConnectedChargeableDischargeable
+	want := EVChargerObject{
+		Instance:                    1,
+		Connection:                  &conn,
+		Mode:                        EVModeCharging,
+		InstantPower:                float64Ptr(3000),
+		RemainingChargeableCapacity: float64Ptr(20000),
+		RemainingCapacity:           float64Ptr(30000),
+		RemainingCapacityPercent:    float64Ptr(60),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if !conn.Connected() || !conn.Chargeable() || !conn.Dischargeable() {
+		t.Errorf("connection status differs: %s", conn)
+	}
+	if EVNotConnected.Connected() || EVConnectionUndetermined.Connected() {
+		t.Errorf("vehicle should not be connected")
+	}
+}
+
+func TestCollectEVChargerMetrics(t *testing.T) {
+	obj, _ := parseProperties(NewObject(HomeEquipmentGroup, EVChargerDischarger, 1), evChargerValues)
+	c := collectFunc(func(w *metricWriter) {
+		collectEVChargerMetrics(w, prometheus.Labels{"id": "192.168.1.21"}, obj.(EVChargerObject))
+	})
+
+	labels := prometheus.Labels{"id": "192.168.1.21", "instance": "1"}
+	if got := gaugeValue(t, c, evPower, labels); got != 3000 {
+		t.Errorf("power differs: want:3000 got:%v", got)
+	}
+	if hasGauge(t, c, evDischargeableCapacity, labels) {
+		t.Errorf("gauge for unavailable property should not exist")
+	}
+	state := prometheus.Labels{"id": "192.168.1.21", "instance": "1", "state": "chargeable_dischargeable"}
+	if got := gaugeValue(t, c, evConnectionState, state); got != 1 {
+		t.Errorf("connection state differs: want:1 got:%v", got)
+	}
+	mode := prometheus.Labels{"id": "192.168.1.21", "instance": "1", "mode": "discharging"}
+	if got := gaugeValue(t, c, evOperationMode, mode); got != 0 {
+		t.Errorf("operation mode differs: want:0 got:%v", got)
+	}
+}
+
+func TestCheckEVChargerSet(t *testing.T) {
+	t.Parallel()
+
+	charging := byteProperty(EVOperationMode, byte(EVModeCharging))
+	discharging := byteProperty(EVOperationMode, byte(EVModeDischarging))
+	standby := byteProperty(EVOperationMode, byte(EVModeStandby))
+	chargingPower, _ := powerProperty(EVChargingPowerSetting, 3000)
+	other := Property{Code: 0xcd, Len: 1, Data: Data{0x10}}
+
+	tests := []struct {
+		name  string
+		perm  EVSetPermission
+		props []Property
+		ok    bool
+	}{
+		{"no permission", 0, []Property{standby}, false},
+		{"standby", EVAllowCharge, []Property{standby}, true},
+		{"charging", EVAllowCharge, []Property{charging, chargingPower}, true},
+		{"discharging without permission", EVAllowCharge, []Property{discharging}, false},
+		{"discharging", EVAllowDischarge, []Property{discharging}, true},
+		{"charging power without permission", EVAllowDischarge, []Property{chargingPower}, false},
+		{"other property", EVAllowCharge, []Property{other}, false},
+		{"other property with both", EVAllowCharge | EVAllowDischarge, []Property{other}, true},
+	}
+	for _, tt := range tests {
+		err := CheckEVChargerSet(tt.perm, tt.props...)
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if _, ok := err.(*EVSetNotPermittedError); !tt.ok && !ok {
+			t.Errorf("%s: EVSetNotPermittedError expected: %v", tt.name, err)
+		}
+	}
+}
+
+func TestEVChargerClient(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	sets := 0
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		esv := GetRes
+		switch req.ESV {
+		case Get:
+			if _, ok := req.Property(SetPropertyMap); ok {
+				props = append(props, Property{Code: 0x9e, Len: 3, Data: Data{0x02, 0xda, 0xeb}})
+				break
+			}
+			esv = GetSNA
+			props = evChargerValues
+		case SetC:
+			esv = SetRes
+			sets++
+			props = append(props, Property{Code: req.Properties[0].Code, Len: 0, Data: Data{}})
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, esv, props)
+		return res.Serialize()
+	})
+
+	e := NewEVChargerClient(c, "192.168.1.21", 1)
+	state, err := e.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.RemainingCapacityPercent == nil || *state.RemainingCapacityPercent != 60 {
+		t.Errorf("remaining capacity differs: %v", state.RemainingCapacityPercent)
+	}
+	if err := e.SetMode(ctx, EVModeStandby); err == nil {
+		t.Errorf("error expected without permission")
+	}
+	// Deviceやコントローラを直接使っても許可なしでは送らない
+	standby := byteProperty(EVOperationMode, byte(EVModeStandby))
+	if _, ok := e.Device.Set(ctx, standby).(*EVSetNotPermittedError); !ok {
+		t.Errorf("EVSetNotPermittedError expected from Device.Set")
+	}
+	if _, ok := c.Set(ctx, e.Address, e.Object, standby).(*EVSetNotPermittedError); !ok {
+		t.Errorf("EVSetNotPermittedError expected from ControllerNode.Set")
+	}
+	if sets != 0 {
+		t.Errorf("Set should not be sent without permission")
+	}
+
+	c.EVPermission = EVAllowCharge
+	if err := e.SetMode(ctx, EVModeCharging); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetChargingPower(ctx, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetMode(ctx, EVModeDischarging); err == nil {
+		t.Errorf("error expected for discharging")
+	}
+	if sets != 2 {
+		t.Errorf("Set count differs: want:2 got:%d", sets)
+	}
+}
@@ -3,6 +3,7 @@ package echonetlite
 import (
 	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"os"
 )
@@ -15,6 +16,12 @@ func init() {
 
 }
 
+// SetLogOutput sets output destination of loggers in this package
+func SetLogOutput(w io.Writer) {
+	logger.SetOutput(w)
+	clogger.SetOutput(w)
+}
+
 // Data represents binary data
 type Data []byte
 
@@ -123,6 +130,11 @@ func parseProperties(obj Object, properties []Property) (interface{}, error) {
 	logger.Printf("ParseProperties: %v", properties)
 
 	switch obj.classGroupCode() {
+	case SensorGroup:
+		if isSensor(obj) {
+			logger.Println("センサ")
+			return parseSensorProperties(obj, properties), nil
+		}
 	case ProfileGroup:
 		switch obj.classCode() {
 		case Profile:
@@ -143,6 +155,47 @@ func parseProperties(obj Object, properties []Property) (interface{}, error) {
 			return obj, nil
 		}
 		break
+	case HomeEquipmentGroup:
+		switch obj.classCode() {
+		case StorageBattery:
+			logger.Println("蓄電池")
+			o := StorageBatteryObject{Instance: obj.Num}
+			for _, p := range properties {
+				parseStorageBatteryProperty(p, &o)
+			}
+			return o, nil
+		case EVChargerDischarger:
+			logger.Println("電気自動車充放電器")
+			o := EVChargerObject{Instance: obj.Num}
+			for _, p := range properties {
+				parseEVChargerProperty(p, &o)
+			}
+			return o, nil
+		case DistributionBoard:
+			logger.Println("分電盤メータリング")
+			return parseDistributionBoardProperties(obj.Num, properties), nil
+		case MultiInputPCS:
+			logger.Println("マルチ入力PCS")
+			o := MultiInputPCSObject{Instance: obj.Num}
+			for _, p := range properties {
+				parseMultiInputPCSProperty(p, &o)
+			}
+			return o, nil
+		case ElectricWaterHeater:
+			logger.Println("電気温水器")
+			o := WaterHeaterObject{Instance: obj.Num}
+			for _, p := range properties {
+				parseWaterHeaterProperty(p, &o)
+			}
+			return o, nil
+		case HomeSolarPower:
+			logger.Println("住宅用太陽光発電")
+			o := SolarPowerObject{Instance: obj.Num}
+			for _, p := range properties {
+				parseSolarPowerProperty(p, &o)
+			}
+			return o, nil
+		}
 	}
 	return nil, nil
 }
@@ -154,11 +207,12 @@ type SuperObject struct {
 	InstallLocation Location
 }
 
-// AirconObject is object for aircon
+// AirconObject is object for aircon.
+// Temperatures are nil if they are not in the frame.
 type AirconObject struct {
 	SuperObject
-	InternalTemp float64
-	OuterTemp    float64
+	InternalTemp *Number
+	OuterTemp    *Number
 }
 
 func parseSuperObjectProperty(p Property) bool {
@@ -182,7 +236,11 @@ func parseSuperObjectProperty(p Property) bool {
 		logger.Printf("SpecVersion: %c", rune(p.Data[2]))
 		return true
 	case ID: // 0x83
-		logger.Printf("ID: %x", p.Data)
+		if id, err := ParseIdentification(p.Data); err == nil {
+			logger.Printf("ID: %s メーカコード: %s", id, id.Manufacturer)
+		} else {
+			logger.Printf("ID: %x (%s)", p.Data, err)
+		}
 		return true
 	case NumOfInstances:
 		return true
@@ -244,60 +302,31 @@ func parseHomeAirConditionerProperty(p Property, obj *AirconObject) bool {
 	case OperationStatus:
 		return true
 	case InstallationLocation:
-		if p.Len != 1 {
-			logger.Printf("[Error] InstallationLocation invalid length: %d", p.Len)
-			return true
-		}
-		var d byte = p.Data[0]
-		logger.Printf("%08b\n", d)
-		if d>>7 == 1 {
-			// free definition
-			logger.Println("free definition")
-			return true
-		}
-		locationCode := (d >> 3) & 0x0F
-		locationNo := d & 0x07
-		obj.InstallLocation = Location{Code: LocationCode(locationCode), Number: int32(locationNo)}
-		logger.Printf("locationCode: %0b locationNo: %0b\n", locationCode, locationNo)
-		return true
-	case ID:
-		if p.Len == 0 {
-			logger.Printf("[Error] ID invalid length: %d", p.Len)
-			return true
-		}
-		lowerCommunicationLayerID := p.Data[0]
-		switch {
-		case 0x00 == lowerCommunicationLayerID:
-		case 0xFE > lowerCommunicationLayerID:
-		case 0xFE == lowerCommunicationLayerID:
-			manufacturerCode := p.Data[1:4]
-			manufacturerID := p.Data[4:]
-			logger.Printf("メーカコード: %#v メーカID: %#v\n", manufacturerCode, manufacturerID)
-		case 0xFF == lowerCommunicationLayerID:
+		if loc, ok := parseLocation(p); ok {
+			obj.InstallLocation = loc
 		}
 		return true
 	case MeasuredRoomTemperature:
-		if p.Len != 1 {
-			logger.Printf("[Error] MeasuredRoomTemperature invalid length: %d", p.Len)
-			return true
-		}
-		temp := int(p.Data[0])
-		obj.InternalTemp = float64(temp)
-		logger.Printf("室温:%d℃\n", temp)
+		obj.InternalTemp = parseTemperature(p)
 		return true
 	case MeasuredOutdoorTemperature:
-		if p.Len != 1 {
-			logger.Printf("[Error] MeasuredOutdoorTemperature invalid length: %d", p.Len)
-			return true
-		}
-		temp := int(p.Data[0])
-		obj.OuterTemp = float64(temp)
-		logger.Printf("外気温:%d℃\n", temp)
+		obj.OuterTemp = parseTemperature(p)
 		return true
 	}
 	return false
 }
 
+// parseTemperature decodes 1 byte temperature (signed char, ℃) including special codes
+func parseTemperature(p Property) *Number {
+	n, err := DecodeNumber(SignedChar, p.Data)
+	if err != nil {
+		logger.Printf("[Error] %02x invalid temperature: %v", p.Code, err)
+		return nil
+	}
+	logger.Printf("%02x 温度:%s℃\n", p.Code, n)
+	return &n
+}
+
 // SrcObj returns src Object
 func (f Frame) SrcObj() Object {
 	return f.SEOJ
@@ -382,6 +411,7 @@ func CreateGetFrame(transID uint16) *Frame {
 	props := []Property{}
 	props = append(props, Property{Code: byte(OperationStatus), Len: 0, Data: []byte{}})
 	props = append(props, Property{Code: byte(SpecVersion), Len: 0, Data: []byte{}})
+	props = append(props, Property{Code: byte(ID), Len: 0, Data: []byte{}})
 	props = append(props, Property{Code: byte(NumOfInstances), Len: 0, Data: []byte{}})
 	props = append(props, Property{Code: byte(NumOfClasses), Len: 0, Data: []byte{}})
 	props = append(props, Property{Code: byte(InstanceListNotification), Len: 0, Data: []byte{}})
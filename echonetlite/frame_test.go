@@ -175,8 +175,8 @@ func TestFrame_PerseProperties(t *testing.T) {
 
 	want := AirconObject{
 		SuperObject:  SuperObject{InstallLocation: Location{Code: Room, Number: 1}},
-		InternalTemp: 28,
-		OuterTemp:    25,
+		InternalTemp: &Number{Value: 28},
+		OuterTemp:    &Number{Value: 25},
 	}
 
 	got, err := parseProperties(input.SrcObj(), input.Properties)
@@ -189,6 +189,27 @@ func TestFrame_PerseProperties(t *testing.T) {
 	}
 }
 
+func TestFrame_ParseProperties_SpecialTemperature(t *testing.T) {
+	props := []Property{
+		{Code: 0xbb, Len: 1, Data: toData(t, "7e")},
+		{Code: 0xbe, Len: 1, Data: toData(t, "fb")},
+	}
+
+	want := AirconObject{
+		InternalTemp: &Number{Value: 126, Status: NumberNoData},
+		OuterTemp:    &Number{Value: -5},
+	}
+
+	got, err := parseProperties(NewObject(AirConditionerGroup, HomeAirConditioner, 1), props)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseProperties differs: (-want +got)\n%s", diff)
+	}
+}
+
 func TestLocationCode(t *testing.T) {
 
 	want := LocationCode(0x1)
@@ -259,9 +280,10 @@ func TestCreateGetFrame(t *testing.T) {
 		0x05, 0xff, 0x01, // SEOJ
 		0x0e, 0xf0, 0x01, // DEOJ
 		0x62,
-		0x08,
+		0x09,
 		0x80, 0x00,
 		0x82, 0x00,
+		0x83, 0x00,
 		0xd3, 0x00,
 		0xd4, 0x00,
 		0xd5, 0x00,
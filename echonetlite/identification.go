@@ -0,0 +1,155 @@
+package echonetlite
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Manufacturer is manufacturer code (0x8A) assigned by ECHONET Consortium
+type Manufacturer uint32
+
+// ManufacturerExperimental is manufacturer code for experimental use
+const ManufacturerExperimental Manufacturer = 0xFFFFFF
+
+var (
+	manufacturersMu sync.RWMutex
+	// manufacturers is partial list of manufacturer codes, use LoadManufacturers or RegisterManufacturer to add more
+	manufacturers = map[Manufacturer]string{
+		0x000005:                 "Sharp",
+		0x000006:                 "Mitsubishi Electric",
+		0x000008:                 "Daikin",
+		0x00000B:                 "Panasonic",
+		0x000016:                 "Toshiba",
+		ManufacturerExperimental: "Experimental",
+	}
+)
+
+// RegisterManufacturer adds name of manufacturer code c
+func RegisterManufacturer(c Manufacturer, name string) {
+	manufacturersMu.Lock()
+	defer manufacturersMu.Unlock()
+	manufacturers[c] = name
+}
+
+// LoadManufacturers registers manufacturer codes in CSV file such as the list published by ECHONET Consortium.
+// Each row has manufacturer code in hex (e.g. 000005, 0x000005 or "00 00 05") and its name in the first two columns,
+// and rows without valid code such as header are skipped.
+func LoadManufacturers(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	loaded := map[Manufacturer]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(record) < 2 {
+			continue
+		}
+		code := strings.Replace(strings.TrimPrefix(strings.TrimSpace(record[0]), "0x"), " ", "", -1)
+		n, err := strconv.ParseUint(code, 16, 32)
+		name := strings.TrimSpace(record[1])
+		if err != nil || len(code) != 6 || name == "" {
+			continue
+		}
+		loaded[Manufacturer(n)] = name
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("%s: no manufacturer code is found", path)
+	}
+
+	manufacturersMu.Lock()
+	defer manufacturersMu.Unlock()
+	for c, name := range loaded {
+		manufacturers[c] = name
+	}
+	return nil
+}
+
+// Name returns name of the manufacturer, or false if it is not in the table
+func (c Manufacturer) Name() (string, bool) {
+	manufacturersMu.RLock()
+	defer manufacturersMu.RUnlock()
+	s, ok := manufacturers[c]
+	return s, ok
+}
+
+func (c Manufacturer) String() string {
+	if s, ok := c.Name(); ok {
+		return s
+	}
+	return fmt.Sprintf("%06X", uint32(c))
+}
+
+// ParseManufacturer parses manufacturer code (0x8A)
+func ParseManufacturer(d []byte) (Manufacturer, error) {
+	if len(d) != 3 {
+		return 0, fmt.Errorf("invalid length of manufacturer code: %d", len(d))
+	}
+	return Manufacturer(d[0])<<16 | Manufacturer(d[1])<<8 | Manufacturer(d[2]), nil
+}
+
+// definition of lower-layer communication ID field of identification number
+const (
+	LowerLayerManufacturer byte = 0xFE // メーカ独自
+	LowerLayerRandom       byte = 0xFF // 下位通信層IDが生成した乱数
+)
+
+// identificationSize is size of identification number (0x83), and shortIdentificationSize is size of its short form
+// whose unique ID is 5 bytes after manufacturer code (0xFE) or 8 bytes of random number (0xFF)
+const (
+	identificationSize      = 17
+	shortIdentificationSize = 9
+)
+
+// Identification is identification number (0x83) which identifies an object regardless of its address
+type Identification struct {
+	LowerLayer   byte         // 下位通信層IDフィールド
+	Manufacturer Manufacturer // メーカコード (LowerLayerManufacturer only)
+	Unique       []byte       // 固有ID
+}
+
+// ParseIdentification parses identification number (0x83).
+// Error is returned if it is not set (lower-layer communication ID field is 0x00).
+func ParseIdentification(d []byte) (Identification, error) {
+	if len(d) != identificationSize && len(d) != shortIdentificationSize {
+		return Identification{}, fmt.Errorf("invalid length of identification number: %d", len(d))
+	}
+	switch d[0] {
+	case 0x00:
+		return Identification{}, fmt.Errorf("identification number is not set")
+	case LowerLayerManufacturer:
+		m, _ := ParseManufacturer(d[1:4])
+		return Identification{LowerLayer: d[0], Manufacturer: m, Unique: append([]byte{}, d[4:]...)}, nil
+	}
+	return Identification{LowerLayer: d[0], Unique: append([]byte{}, d[1:]...)}, nil
+}
+
+// Data returns serialized identification number
+func (id Identification) Data() Data {
+	d := Data{id.LowerLayer}
+	if id.LowerLayer == LowerLayerManufacturer {
+		d = append(d, byte(id.Manufacturer>>16), byte(id.Manufacturer>>8), byte(id.Manufacturer))
+	}
+	return append(d, id.Unique...)
+}
+
+// String returns hex of the identification number, which is used as stable identity in metric labels
+func (id Identification) String() string {
+	return hex.EncodeToString(id.Data())
+}
@@ -0,0 +1,138 @@
+package echonetlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseIdentification(t *testing.T) {
+	t.Parallel()
+
+	d := toData(t, "fe00000860f189306df500000000000000")
+	got, err := ParseIdentification(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identification{
+		LowerLayer:   LowerLayerManufacturer,
+		Manufacturer: 0x000008,
+		Unique:       toData(t, "60f189306df500000000000000"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if got.Manufacturer.String() != "Daikin" {
+		t.Errorf("manufacturer differs: %s", got.Manufacturer)
+	}
+	if got.String() != "fe00000860f189306df500000000000000" {
+		t.Errorf("identity differs: %s", got)
+	}
+
+	lower := toData(t, "0102030405060708090a0b0c0d0e0f1011")
+	got, err = ParseIdentification(lower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Manufacturer != 0 || got.String() != "0102030405060708090a0b0c0d0e0f1011" {
+		t.Errorf("identification of lower-layer ID differs: %+v", got)
+	}
+
+	short := toData(t, "fe0000050102030405")
+	got, err = ParseIdentification(short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Identification{
+		LowerLayer:   LowerLayerManufacturer,
+		Manufacturer: 0x000005,
+		Unique:       toData(t, "0102030405"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("short form (-want +got):\n%s", diff)
+	}
+	if got.String() != "fe0000050102030405" {
+		t.Errorf("identity of short form differs: %s", got)
+	}
+
+	random := toData(t, "ff0102030405060708")
+	got, err = ParseIdentification(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LowerLayer != LowerLayerRandom || got.Manufacturer != 0 || got.String() != "ff0102030405060708" {
+		t.Errorf("short form of random number differs: %+v", got)
+	}
+
+	for _, s := range []string{"", "fe000008", "fe00000801020304", "000000000000000000", "0000000000000000000000000000000000"} {
+		if _, err := ParseIdentification(toData(t, s)); err == nil {
+			t.Errorf("%s: error expected", s)
+		}
+	}
+}
+
+func unregisterManufacturers(codes ...Manufacturer) {
+	manufacturersMu.Lock()
+	defer manufacturersMu.Unlock()
+	for _, c := range codes {
+		delete(manufacturers, c)
+	}
+}
+
+func TestManufacturer(t *testing.T) {
+	m, err := ParseManufacturer([]byte{0x00, 0x12, 0x34})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.String() != "001234" {
+		t.Errorf("unknown manufacturer differs: %s", m)
+	}
+	defer unregisterManufacturers(m)
+	RegisterManufacturer(m, "Example")
+	if m.String() != "Example" {
+		t.Errorf("registered manufacturer differs: %s", m)
+	}
+	if _, err := ParseManufacturer([]byte{0x00}); err == nil {
+		t.Errorf("error expected")
+	}
+}
+
+func TestLoadManufacturers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manufacturers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manufacturers.csv")
+	list := "メーカコード,会社名\n" +
+		"0x00FFF0,Example Electric\n" +
+		"\"00 FF F1\",\"Example Home, Inc.\"\n" +
+		"00FFF2\n" +
+		"FFF3,Short Code\n"
+	if err := ioutil.WriteFile(path, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterManufacturers(0x00FFF0, 0x00FFF1)
+	if err := LoadManufacturers(path); err != nil {
+		t.Fatal(err)
+	}
+	for c, want := range map[Manufacturer]string{0x00FFF0: "Example Electric", 0x00FFF1: "Example Home, Inc.", 0x00FFF3: "00FFF3", 0x000008: "Daikin"} {
+		if got := c.String(); got != want {
+			t.Errorf("%06X: want:%s got:%s", uint32(c), want, got)
+		}
+	}
+
+	empty := filepath.Join(dir, "empty.csv")
+	if err := ioutil.WriteFile(empty, []byte("メーカコード,会社名\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{empty, filepath.Join(dir, "missing.csv")} {
+		if err := LoadManufacturers(p); err == nil {
+			t.Errorf("%s: error expected", p)
+		}
+	}
+}
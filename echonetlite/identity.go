@@ -0,0 +1,310 @@
+package echonetlite
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultStaleTimeout is time until a silent node is considered stale if StaleTimeout is not set
+const DefaultStaleTimeout = 10 * time.Minute
+
+// macLookupInterval is interval to retry looking up MAC address of a node after failure
+const macLookupInterval = time.Minute
+
+var (
+	deviceInfo = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "device",
+			Name:      "info",
+			Help:      "identification number of object and its current address",
+		},
+		[]string{"id", "ip", "eoj", "manufacturer"},
+	)
+	nodeStale = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "node",
+			Name:      "stale",
+			Help:      "1 if nothing is received from the node within stale timeout",
+		},
+		[]string{"id", "ip"},
+	)
+)
+
+func deviceInfoLabels(addr string, obj Object, id Identification) prometheus.Labels {
+	return prometheus.Labels{"id": id.String(), "ip": addr, "eoj": fmt.Sprintf("%x", obj.Data()), "manufacturer": id.Manufacturer.String()}
+}
+
+// ID returns stable identity of the node, which is identification number (0x83) of node profile,
+// MAC address ("mac:xx:xx:xx:xx:xx:xx") or IP address in order of preference
+func (n Node) ID() string {
+	for obj, id := range n.Identities {
+		if obj.isNodeProfile() {
+			return id.String()
+		}
+	}
+	if len(n.MAC) > 0 {
+		return "mac:" + n.MAC.String()
+	}
+	return n.Address
+}
+
+// DeviceID returns stable identity of obj at addr, which is identification number (0x83) of the object if known,
+// or identity of the node
+func (elc *ControllerNode) DeviceID(addr string, obj Object) string {
+	addr = hostOf(addr)
+
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+	node, ok := elc.nodeList[addr]
+	if !ok {
+		return addr
+	}
+	if id, ok := node.Identities[obj]; ok {
+		return id.String()
+	}
+	return node.ID()
+}
+
+// LookupDevice returns current address of obj whose DeviceID is id.
+// IP address of the node is also accepted as id.
+func (elc *ControllerNode) LookupDevice(id string, obj Object) (string, bool) {
+	for _, n := range elc.Nodes() {
+		for _, o := range n.Devices {
+			if o != obj {
+				continue
+			}
+			did := n.ID()
+			if ident, ok := n.Identities[obj]; ok {
+				did = ident.String()
+			}
+			if did == id || n.Address == id {
+				return n.Address, true
+			}
+		}
+	}
+	return "", false
+}
+
+// setIdentification registers identification number of obj at addr, and rebinds the node if it was known at other address
+func (elc *ControllerNode) setIdentification(addr string, obj Object, id Identification) {
+	elc.mu.Lock()
+	if elc.nodeList == nil {
+		elc.nodeList = make(NodeList)
+	}
+	elc.nodeList.Add(addr, obj)
+	node := elc.nodeList[addr]
+	if node.Identities == nil {
+		node.Identities = map[Object]Identification{}
+	}
+	node.Identities[obj] = id
+	elc.mu.Unlock()
+
+	elc.rebind(addr)
+}
+
+// seen records that a frame is received from addr.
+// MAC address is looked up in background for the node without identification number of node profile,
+// and it is retried at most once per macLookupInterval.
+func (elc *ControllerNode) seen(addr string) {
+	now := time.Now()
+	elc.mu.Lock()
+	node, ok := elc.nodeList[addr]
+	if !ok {
+		elc.mu.Unlock()
+		return
+	}
+	node.LastSeen = now
+	lookup := len(node.MAC) == 0 && node.ID() == addr && !now.Before(elc.macLookups[addr])
+	if lookup {
+		if elc.macLookups == nil {
+			elc.macLookups = map[string]time.Time{}
+		}
+		// 実行中と失敗後は次の時刻まで引かない
+		elc.macLookups[addr] = now.Add(macLookupInterval)
+		elc.lookups.Add(1)
+	}
+	elc.mu.Unlock()
+
+	if lookup {
+		go elc.lookupMAC(addr)
+	}
+}
+
+// lookupMAC looks up MAC address of the node at addr and rebinds it
+func (elc *ControllerNode) lookupMAC(addr string) {
+	defer elc.lookups.Done()
+	resolve := elc.ResolveMAC
+	if resolve == nil {
+		resolve = LookupMAC
+	}
+	mac, err := resolve(addr)
+	if err != nil {
+		clogger.Printf("MAC address of %s is not found: %s", addr, err)
+		return
+	}
+
+	elc.mu.Lock()
+	node, ok := elc.nodeList[addr]
+	if !ok {
+		elc.mu.Unlock()
+		return
+	}
+	node.MAC = mac
+	delete(elc.macLookups, addr)
+	elc.mu.Unlock()
+	elc.rebind(addr)
+}
+
+// rebind merges nodes which have the same identity as the node at addr into it.
+// It happens when a known device gets new address by DHCP, so that its metrics keep the same identity.
+func (elc *ControllerNode) rebind(addr string) {
+	elc.mu.Lock()
+	node, ok := elc.nodeList[addr]
+	if !ok || node.ID() == addr {
+		elc.mu.Unlock()
+		return
+	}
+	id := node.ID()
+	moved := []*Node{}
+	for a, other := range elc.nodeList {
+		if a == addr || other.ID() != id {
+			continue
+		}
+		for _, obj := range other.Devices {
+			elc.nodeList.Add(addr, obj)
+		}
+		for obj, oid := range other.Identities {
+			if _, ok := node.Identities[obj]; !ok {
+				if node.Identities == nil {
+					node.Identities = map[Object]Identification{}
+				}
+				node.Identities[obj] = oid
+			}
+		}
+		if len(node.MAC) == 0 {
+			node.MAC = other.MAC
+		}
+		if other.LastSeen.After(node.LastSeen) {
+			node.LastSeen = other.LastSeen
+		}
+		delete(elc.nodeList, a)
+		elc.moveStates(a, addr)
+		moved = append(moved, other)
+	}
+	elc.mu.Unlock()
+
+	for _, other := range moved {
+		clogger.Printf("node %s moved from %s to %s", id, other.Address, addr)
+	}
+}
+
+// SetStaleTimeout changes StaleTimeout while the controller is running
+func (elc *ControllerNode) SetStaleTimeout(timeout time.Duration) {
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+	elc.StaleTimeout = timeout
+}
+
+func (elc *ControllerNode) isStale(lastSeen time.Time) bool {
+	timeout := elc.StaleTimeout
+	if timeout == 0 {
+		timeout = DefaultStaleTimeout
+	}
+	return !lastSeen.IsZero() && time.Since(lastSeen) > timeout
+}
+
+// CheckStale returns nodes which are stale
+func (elc *ControllerNode) CheckStale() []Node {
+	stale := []Node{}
+	for _, n := range elc.Nodes() {
+		if n.Stale {
+			stale = append(stale, n)
+		}
+	}
+	return stale
+}
+
+// LookupMAC returns MAC address of ip from ARP (IPv4) or NDP (IPv6) table of the OS.
+// /proc/net/arp is read for IPv4 and "ip neigh" is used for IPv6 on Linux.
+func LookupMAC(ip string) (net.HardwareAddr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+	if addr.To4() != nil {
+		f, err := os.Open("/proc/net/arp")
+		if err == nil {
+			defer f.Close()
+			return parseARPTable(f, addr)
+		}
+	}
+	out, err := exec.Command("ip", "neigh", "show", addr.String()).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read neighbor table: %w", err)
+	}
+	return parseNeighbors(bytes.NewReader(out), addr)
+}
+
+// parseARPTable finds ip in /proc/net/arp format
+func parseARPTable(r io.Reader, ip net.IP) (net.HardwareAddr, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// IP address       HW type     Flags       HW address            Mask     Device
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		// Flags 0x0 is incomplete entry
+		if fields[2] == "0x0" {
+			continue
+		}
+		return parseMAC(fields[3])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s is not in ARP table", ip)
+}
+
+// parseNeighbors finds ip in output of "ip neigh"
+func parseNeighbors(r io.Reader, ip net.IP) (net.HardwareAddr, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// fe80::1 dev eth0 lladdr 00:11:22:33:44:55 REACHABLE
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		for i := 1; i < len(fields)-1; i++ {
+			if fields[i] == "lladdr" {
+				return parseMAC(fields[i+1])
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s is not in neighbor table", ip)
+}
+
+func parseMAC(s string) (net.HardwareAddr, error) {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(mac, make([]byte, len(mac))) {
+		return nil, fmt.Errorf("incomplete MAC address: %s", s)
+	}
+	return mac, nil
+}
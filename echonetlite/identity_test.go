@@ -0,0 +1,179 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/matsuu/go-el-controller/transport"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// receive delivers frame to c as if it is received from addr
+func receive(t *testing.T, c *ControllerNode, addr string, src Object, props []Property) {
+	t.Helper()
+	f := NewFrame(1, src, NewObject(ControllerGroup, Controller, 0x01), GetRes, props)
+	err := c.onReceive(context.Background(), transport.ReceiveResult{Data: f.Serialize(), Address: addr + ":3610"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MACアドレスの検索を待つ
+	c.lookups.Wait()
+}
+
+func TestParseARPTable(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.10     0x1         0x2         00:11:22:33:44:55     *        eth0
+192.168.1.11     0x1         0x0         00:00:00:00:00:00     *        eth0
+`
+	mac, err := parseARPTable(strings.NewReader(table), net.ParseIP("192.168.1.10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac.String() != "00:11:22:33:44:55" {
+		t.Errorf("MAC address differs: %s", mac)
+	}
+	if _, err := parseARPTable(strings.NewReader(table), net.ParseIP("192.168.1.11")); err == nil {
+		t.Errorf("error expected for incomplete entry")
+	}
+
+	neigh := "fe80::1 dev eth0 lladdr 66:77:88:99:aa:bb REACHABLE\nfe80::2 dev eth0 FAILED\n"
+	mac, err = parseNeighbors(strings.NewReader(neigh), net.ParseIP("fe80::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac.String() != "66:77:88:99:aa:bb" {
+		t.Errorf("MAC address differs: %s", mac)
+	}
+	if _, err := parseNeighbors(strings.NewReader(neigh), net.ParseIP("fe80::2")); err == nil {
+		t.Errorf("error expected for failed entry")
+	}
+}
+
+func TestControllerNode_Rebind_NodeProfileID(t *testing.T) {
+	t.Parallel()
+
+	c := &ControllerNode{ResolveMAC: func(ip string) (net.HardwareAddr, error) {
+		return nil, fmt.Errorf("%s is not in neighbor table", ip)
+	}}
+	profile := NewObject(ProfileGroup, Profile, 0x01)
+	battery := NewObject(HomeEquipmentGroup, StorageBattery, 0x01)
+	props := []Property{
+		{Code: 0x83, Len: 17, Data: toData(t, "fe00000b00000000000000000000000040")},
+		{Code: 0xd6, Len: 4, Data: Data{0x01, 0x02, 0x7d, 0x01}},
+	}
+	const id = "fe00000b00000000000000000000000040"
+
+	receive(t, c, "192.168.1.40", profile, props)
+	if got := c.DeviceID("192.168.1.40", battery); got != id {
+		t.Errorf("device ID differs: want:%s got:%s", id, got)
+	}
+
+	// DHCPでアドレスが変わった
+	receive(t, c, "192.168.1.41", profile, props)
+	nodes := c.Nodes()
+	if len(nodes) != 1 || nodes[0].Address != "192.168.1.41" {
+		t.Fatalf("node is not rebound: %+v", nodes)
+	}
+	if diff := cmp.Diff([]Object{battery}, nodes[0].Devices); diff != "" {
+		t.Errorf("devices differ: (-want +got)\n%s", diff)
+	}
+	if got := c.DeviceID("192.168.1.41", battery); got != id {
+		t.Errorf("device ID differs: want:%s got:%s", id, got)
+	}
+	if addr, ok := c.LookupDevice(id, battery); !ok || addr != "192.168.1.41" {
+		t.Errorf("device is not found by ID: %s %v", addr, ok)
+	}
+	if _, ok := c.LookupDevice(id, NewObject(HomeEquipmentGroup, StorageBattery, 0x02)); ok {
+		t.Errorf("unknown object should not be found")
+	}
+	info := prometheus.Labels{"id": id, "ip": "192.168.1.40", "eoj": "0ef001", "manufacturer": "Panasonic"}
+	if hasGauge(t, c.Collector(), deviceInfo, info) {
+		t.Errorf("device info of old address should be removed")
+	}
+	info["ip"] = "192.168.1.41"
+	if got := gaugeValue(t, c.Collector(), deviceInfo, info); got != 1 {
+		t.Errorf("device info differs: want:1 got:%v", got)
+	}
+}
+
+func TestControllerNode_Rebind_MAC(t *testing.T) {
+	t.Parallel()
+
+	c := &ControllerNode{ResolveMAC: func(ip string) (net.HardwareAddr, error) {
+		switch ip {
+		case "192.168.1.50", "192.168.1.51":
+			return net.ParseMAC("00:11:22:33:44:50")
+		}
+		return nil, fmt.Errorf("%s is not in neighbor table", ip)
+	}}
+	battery := NewObject(HomeEquipmentGroup, StorageBattery, 0x01)
+
+	receive(t, c, "192.168.1.50", battery, []Property{{Code: 0xe4, Len: 1, Data: Data{0x50}}})
+	receive(t, c, "192.168.1.51", battery, []Property{{Code: 0xe4, Len: 1, Data: Data{0x51}}})
+
+	nodes := c.Nodes()
+	if len(nodes) != 1 || nodes[0].Address != "192.168.1.51" {
+		t.Fatalf("node is not rebound: %+v", nodes)
+	}
+	labels := prometheus.Labels{"id": "mac:00:11:22:33:44:50", "instance": "1"}
+	if got := gaugeValue(t, c.Collector(), batteryRemainingCapacityPercent, labels); got != 0x51 {
+		t.Errorf("remaining capacity differs: want:%d got:%v", 0x51, got)
+	}
+}
+
+func TestControllerNode_LookupMAC_Backoff(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	calls := 0
+	c := &ControllerNode{ResolveMAC: func(ip string) (net.HardwareAddr, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil, fmt.Errorf("%s is not in neighbor table", ip)
+	}}
+	sensor := NewObject(SensorGroup, TemperatureSensor, 1)
+	for i := 0; i < 3; i++ {
+		receive(t, c, "192.168.1.70", sensor, nil)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("failed lookup must not be retried until interval passes: %d calls", calls)
+	}
+}
+
+func TestControllerNode_CheckStale(t *testing.T) {
+	t.Parallel()
+
+	c := &ControllerNode{
+		ResolveMAC: func(ip string) (net.HardwareAddr, error) {
+			return nil, fmt.Errorf("%s is not in neighbor table", ip)
+		},
+		StaleTimeout: 50 * time.Millisecond,
+	}
+	receive(t, c, "192.168.1.60", NewObject(SensorGroup, TemperatureSensor, 1), nil)
+
+	labels := prometheus.Labels{"id": "192.168.1.60", "ip": "192.168.1.60"}
+	if stale := c.CheckStale(); len(stale) != 0 {
+		t.Errorf("node should not be stale: %+v", stale)
+	}
+	if got := gaugeValue(t, c.Collector(), nodeStale, labels); got != 0 {
+		t.Errorf("stale differs: want:0 got:%v", got)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	stale := c.CheckStale()
+	if len(stale) != 1 || !stale[0].Stale {
+		t.Errorf("node should be stale: %+v", stale)
+	}
+	if got := gaugeValue(t, c.Collector(), nodeStale, labels); got != 1 {
+		t.Errorf("stale differs: want:1 got:%v", got)
+	}
+}
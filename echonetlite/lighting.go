@@ -0,0 +1,292 @@
+package echonetlite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// LightColor is light color setting of lighting (0xB1)
+type LightColor byte
+
+// definition of light colors
+const (
+	LightColorOther         LightColor = 0x40
+	LightColorIncandescent  LightColor = 0x41 // 電球色
+	LightColorWhite         LightColor = 0x42 // 白色
+	LightColorDaylightWhite LightColor = 0x43 // 昼白色
+	LightColorDaylight      LightColor = 0x44 // 昼光色
+)
+
+var lightColorNames = map[LightColor]string{
+	LightColorOther:         "other",
+	LightColorIncandescent:  "incandescent",
+	LightColorWhite:         "white",
+	LightColorDaylightWhite: "daylight_white",
+	LightColorDaylight:      "daylight",
+}
+
+func (c LightColor) String() string {
+	if s, ok := lightColorNames[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(c))
+}
+
+// ParseLightColor returns light color from its name
+func ParseLightColor(s string) (LightColor, error) {
+	for c, name := range lightColorNames {
+		if name == s {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown light color: %s", s)
+}
+
+// LightingState is state of general lighting or single function lighting.
+// Values are nil or zero if they are not available.
+type LightingState struct {
+	Address string
+	Object  Object
+	On      *bool
+	Level   *float64   // 照度レベル設定 [%]
+	Color   LightColor // 光色設定
+	Scene   int        // シーン制御設定 (0: 未設定)
+}
+
+// lightingProperties is properties requested to lighting
+var lightingProperties = []PropertyCode{OperationStatus, IlluminanceLevel, LightColorSetting, SceneControlSetting}
+
+func parseLightingProperty(p Property, s *LightingState) bool {
+	if len(p.Data) != 1 {
+		return false
+	}
+	switch PropertyCode(p.Code) {
+	case OperationStatus:
+		on := p.Data[0] == 0x30
+		s.On = &on
+		return true
+	case IlluminanceLevel:
+		s.Level = measured(UnsignedChar, p.Data, 1)
+		return true
+	case LightColorSetting:
+		s.Color = LightColor(p.Data[0])
+		return true
+	case SceneControlSetting:
+		s.Scene = int(p.Data[0])
+		return true
+	}
+	return false
+}
+
+// LevelProperty returns property to set illuminance level (0-100%)
+func LevelProperty(level int) (Property, error) {
+	if level < 0 || level > 100 {
+		return Property{}, fmt.Errorf("illuminance level out of range [0-100]: %d", level)
+	}
+	return byteProperty(IlluminanceLevel, byte(level)), nil
+}
+
+// ColorProperty returns property to set light color
+func ColorProperty(c LightColor) (Property, error) {
+	if _, ok := lightColorNames[c]; !ok {
+		return Property{}, fmt.Errorf("invalid light color: %s", c)
+	}
+	return byteProperty(LightColorSetting, byte(c)), nil
+}
+
+// SceneProperty returns property to set scene (1-253)
+func SceneProperty(scene int) (Property, error) {
+	if scene < 1 || scene > 0xFD {
+		return Property{}, fmt.Errorf("scene out of range [1-253]: %d", scene)
+	}
+	return byteProperty(SceneControlSetting, byte(scene)), nil
+}
+
+// LightingClient is client to read and control general lighting (0x0290) or single function lighting (0x0291)
+type LightingClient struct {
+	*Device
+}
+
+// NewLightingClient returns LightingClient for the instance of class (GeneralLighting or SingleFuncLighting) at addr
+func NewLightingClient(elc *ControllerNode, addr string, class ClassCode, instance int) *LightingClient {
+	return &LightingClient{NewDevice(elc, addr, NewObject(HomeEquipmentGroup, class, instance))}
+}
+
+// State reads current state of the lighting
+func (l *LightingClient) State(ctx context.Context) (LightingState, error) {
+	codes := lightingProperties
+	if l.Object.Class == SingleFuncLighting {
+		codes = codes[:2]
+	}
+	props, err := l.Get(ctx, codes...)
+	if err != nil {
+		return LightingState{}, err
+	}
+	s := LightingState{Address: l.Address, Object: l.Object}
+	for _, p := range props {
+		parseLightingProperty(p, &s)
+	}
+	return s, nil
+}
+
+// SetPower turns on/off the lighting
+func (l *LightingClient) SetPower(ctx context.Context, on bool) error {
+	return l.Set(ctx, PowerProperty(on))
+}
+
+// SetLevel changes illuminance level
+func (l *LightingClient) SetLevel(ctx context.Context, level int) error {
+	p, err := LevelProperty(level)
+	if err != nil {
+		return err
+	}
+	return l.Set(ctx, p)
+}
+
+// SetColor changes light color
+func (l *LightingClient) SetColor(ctx context.Context, c LightColor) error {
+	p, err := ColorProperty(c)
+	if err != nil {
+		return err
+	}
+	return l.Set(ctx, p)
+}
+
+// SetScene changes scene
+func (l *LightingClient) SetScene(ctx context.Context, scene int) error {
+	p, err := SceneProperty(scene)
+	if err != nil {
+		return err
+	}
+	return l.Set(ctx, p)
+}
+
+// GroupSettleTime is time to wait before confirming group control
+var GroupSettleTime = 500 * time.Millisecond
+
+// GroupTimeout is time to collect responses of group control if ctx has no deadline
+var GroupTimeout = 3 * time.Second
+
+// GroupControlError is returned when some of objects didn't reflect group control
+type GroupControlError struct {
+	Unconfirmed []LightingState
+	NoResponse  []LightingState // known instances which didn't respond, only Address and Object are set
+}
+
+func (e *GroupControlError) Error() string {
+	objs := []string{}
+	for _, s := range e.Unconfirmed {
+		objs = append(objs, fmt.Sprintf("%s[%s]", s.Address, s.Object))
+	}
+	silent := []string{}
+	for _, s := range e.NoResponse {
+		silent = append(silent, fmt.Sprintf("%s[%s]", s.Address, s.Object))
+	}
+	return fmt.Sprintf("group control not confirmed: %v, no response: %v", objs, silent)
+}
+
+// SetLightingGroup sends props to all instances of class with one multicast SetI,
+// then reads them back with multicast Get until ctx is done, or for GroupTimeout if ctx has no deadline.
+// States of responded objects are returned, with *GroupControlError if some of them don't have requested values
+// or instances found by discovery don't respond.
+func (elc *ControllerNode) SetLightingGroup(ctx context.Context, class ClassCode, props ...Property) ([]LightingState, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, GroupTimeout)
+		defer cancel()
+	}
+	all := NewObject(HomeEquipmentGroup, class, 0)
+	src := NewObject(ControllerGroup, Controller, 0x01)
+
+	f := NewFrame(elc.nextTID(), src, all, SetI, props)
+	elc.sendFrame(&f)
+
+	select {
+	case <-time.After(GroupSettleTime):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	codes := []Property{}
+	for _, p := range props {
+		codes = append(codes, Property{Code: p.Code, Len: 0, Data: []byte{}})
+	}
+	get := NewFrame(0, src, all, Get, codes)
+	messages := elc.Broadcast(ctx, &get)
+
+	type instance struct {
+		addr string
+		obj  Object
+	}
+	responded := map[instance]bool{}
+	states := []LightingState{}
+	unconfirmed := []LightingState{}
+	for _, m := range messages {
+		s := LightingState{Address: hostOf(m.Address), Object: m.Frame.SEOJ}
+		responded[instance{s.Address, s.Object}] = true
+		confirmed := true
+		for _, want := range props {
+			p, ok := m.Frame.Property(PropertyCode(want.Code))
+			if !ok || !bytes.Equal(p.Data, want.Data) {
+				confirmed = false
+			}
+			parseLightingProperty(p, &s)
+		}
+		states = append(states, s)
+		if !confirmed {
+			unconfirmed = append(unconfirmed, s)
+		}
+	}
+	noResponse := []LightingState{}
+	for _, n := range elc.Nodes() {
+		for _, obj := range n.Devices {
+			if obj.ClassGroup == all.ClassGroup && obj.Class == class && !responded[instance{n.Address, obj}] {
+				noResponse = append(noResponse, LightingState{Address: n.Address, Object: obj})
+			}
+		}
+	}
+	if len(unconfirmed) > 0 || len(noResponse) > 0 {
+		return states, &GroupControlError{Unconfirmed: unconfirmed, NoResponse: noResponse}
+	}
+	return states, nil
+}
+
+// SetAllLights turns on/off all general lighting and single function lighting.
+// Deadline of ctx, or GroupTimeout if it has no deadline, bounds the time to confirm.
+func (elc *ControllerNode) SetAllLights(ctx context.Context, on bool) ([]LightingState, error) {
+	type result struct {
+		states []LightingState
+		err    error
+	}
+	classes := []ClassCode{GeneralLighting, SingleFuncLighting}
+	ch := make(chan result, len(classes))
+	for _, class := range classes {
+		go func(class ClassCode) {
+			s, err := elc.SetLightingGroup(ctx, class, PowerProperty(on))
+			ch <- result{s, err}
+		}(class)
+	}
+
+	states := []LightingState{}
+	groupErr := &GroupControlError{}
+	var err error
+	for range classes {
+		r := <-ch
+		states = append(states, r.states...)
+		if gerr, ok := r.err.(*GroupControlError); ok {
+			groupErr.Unconfirmed = append(groupErr.Unconfirmed, gerr.Unconfirmed...)
+			groupErr.NoResponse = append(groupErr.NoResponse, gerr.NoResponse...)
+		} else if r.err != nil {
+			err = r.err
+		}
+	}
+	if err != nil {
+		return states, err
+	}
+	if len(groupErr.Unconfirmed) > 0 || len(groupErr.NoResponse) > 0 {
+		return states, groupErr
+	}
+	return states, nil
+}
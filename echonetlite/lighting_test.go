@@ -0,0 +1,98 @@
+package echonetlite
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/matsuu/go-el-controller/transport"
+)
+
+func TestSetAllLights(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	type light struct {
+		addr   string
+		obj    Object
+		status byte
+		broken bool // ignores SetI
+	}
+	var mu sync.Mutex
+	lights := []*light{
+		{addr: "192.168.1.50:3610", obj: NewObject(HomeEquipmentGroup, GeneralLighting, 1), status: 0x30},
+		{addr: "192.168.1.51:3610", obj: NewObject(HomeEquipmentGroup, SingleFuncLighting, 1), status: 0x30, broken: true},
+		{addr: "192.168.1.52:3610", obj: NewObject(AirConditionerGroup, HomeAirConditioner, 1), status: 0x30},
+	}
+
+	c := newTestNetwork(t, ctx, func(req Frame) []byte { return nil }, func(req Frame) []transport.ReceiveResult {
+		mu.Lock()
+		defer mu.Unlock()
+
+		results := []transport.ReceiveResult{}
+		for _, l := range lights {
+			if req.DEOJ.ClassGroup != l.obj.ClassGroup || req.DEOJ.Class != l.obj.Class {
+				continue
+			}
+			switch req.ESV {
+			case SetI:
+				if !l.broken {
+					l.status = req.Properties[0].Data[0]
+				}
+			case Get:
+				res := NewFrame(req.TransactionID(), l.obj, req.SEOJ, GetRes, []Property{{Code: 0x80, Len: 1, Data: Data{l.status}}})
+				results = append(results, transport.ReceiveResult{Data: res.Serialize(), Address: l.addr})
+			}
+		}
+		return results
+	})
+
+	// 発見済みで応答しない照明
+	silent := NewObject(HomeEquipmentGroup, GeneralLighting, 1)
+	c.addNode("192.168.1.53", silent)
+
+	// 期限がなければGroupTimeoutまで応答を待つ
+	states, err := c.SetAllLights(context.Background(), false)
+	gerr, ok := err.(*GroupControlError)
+	if !ok {
+		t.Fatalf("GroupControlError expected: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("number of states differs: want:2 got:%d", len(states))
+	}
+
+	on := true
+	want := []LightingState{{Address: "192.168.1.51", Object: NewObject(HomeEquipmentGroup, SingleFuncLighting, 1), On: &on}}
+	if diff := cmp.Diff(want, gerr.Unconfirmed); diff != "" {
+		t.Errorf("unconfirmed differs: (-want +got)\n%s", diff)
+	}
+	want = []LightingState{{Address: "192.168.1.53", Object: silent}}
+	if diff := cmp.Diff(want, gerr.NoResponse); diff != "" {
+		t.Errorf("no response differs: (-want +got)\n%s", diff)
+	}
+	if lights[0].status != 0x31 {
+		t.Errorf("light is not turned off: %#x", lights[0].status)
+	}
+}
+
+func TestLightingProperties(t *testing.T) {
+	t.Parallel()
+
+	if _, err := LevelProperty(101); err == nil {
+		t.Errorf("error expected for out of range level")
+	}
+	if _, err := SceneProperty(0); err == nil {
+		t.Errorf("error expected for scene 0")
+	}
+	if _, err := ColorProperty(LightColor(0x50)); err == nil {
+		t.Errorf("error expected for unknown color")
+	}
+	c, err := ParseLightColor("daylight_white")
+	if err != nil || c != LightColorDaylightWhite {
+		t.Errorf("ParseLightColor differs: %v %v", c, err)
+	}
+}
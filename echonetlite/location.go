@@ -1,12 +1,21 @@
 package echonetlite
 
+import (
+	"context"
+	"fmt"
+)
+
 // LocationCode represents location code
 type LocationCode int32
 
-// Location represents location
+// Location represents installation location (0x81).
+// Number is location number (0-7) for Living to Other, and user defined value (0x00-0x7E) for FreeDefinition.
+// Position is 16 bytes position information for PositionInformation.
+// Zero value means location is not set.
 type Location struct {
-	Code   LocationCode
-	Number int32
+	Code     LocationCode
+	Number   int32
+	Position []byte
 }
 
 // LocationCodes
@@ -28,37 +37,145 @@ const (
 	Other
 )
 
+// LocationCodes which are not in the bit field form
+const (
+	NotSet              LocationCode = 0x000 // 設置場所未設定 (0x00)
+	FreeDefinition      LocationCode = 0x080 // フリー定義 (0x80-0xFE)
+	Undetermined        LocationCode = 0x0FF // 設置場所不定 (0xFF)
+	PositionInformation LocationCode = 0x100 // 位置情報 (0x01 + 16 bytes)
+)
+
+// positionInformationSize is size of position information without the leading 0x01
+const positionInformationSize = 16
+
+var locationCodeNames = map[LocationCode]string{
+	Living:              "Living",
+	Dining:              "Dining",
+	Kitchen:             "Kitchen",
+	Bathroom:            "Bathroom",
+	Lavatory:            "Lavatory",
+	Washroom:            "Washroom",
+	Corridor:            "Corridor",
+	Room:                "Room",
+	Stairs:              "Stairs",
+	Entrance:            "Entrance",
+	Closet:              "Closet",
+	Garden:              "Garden",
+	Garage:              "Garage",
+	Balcony:             "Balcony",
+	Other:               "Other",
+	NotSet:              "NotSet",
+	FreeDefinition:      "Free",
+	Undetermined:        "Undetermined",
+	PositionInformation: "Position",
+}
+
 func (l LocationCode) String() string {
-	switch l {
-	case Living:
-		return "Living"
-	case Dining:
-		return "Dining"
-	case Kitchen:
-		return "Kitchen"
-	case Bathroom:
-		return "Bathroom"
-	case Lavatory:
-		return "Lavatory"
-	case Corridor:
-		return "Corridor"
-	case Room:
-		return "Room"
-	case Stairs:
-		return "Stairs"
-	case Entrance:
-		return "Entrance"
-	case Closet:
-		return "Closet"
-	case Garden:
-		return "Garden"
-	case Garage:
-		return "Garage"
-	case Balcony:
-		return "Balcony"
-	case Other:
-		return "Other"
+	if s, ok := locationCodeNames[l]; ok {
+		return s
+	}
+	return "unknown"
+}
+
+// Label returns location name with its number (e.g. "Room1", "Free5") for metric labels.
+// Position information is rendered in hex (e.g. "Position0102...").
+func (l Location) Label() string {
+	switch l.Code {
+	case NotSet, Undetermined:
+		return l.Code.String()
+	case PositionInformation:
+		return fmt.Sprintf("%s%x", l.Code, l.Position)
+	}
+	if l.Number != 0 || l.Code == FreeDefinition {
+		return fmt.Sprintf("%s%d", l.Code, l.Number)
+	}
+	return l.Code.String()
+}
+
+func (l Location) String() string {
+	return l.Label()
+}
+
+// ParseLocation parses data of installation location (0x81) in 1 byte or 17 bytes form
+func ParseLocation(d []byte) (Location, error) {
+	switch len(d) {
+	case 1:
+	case 1 + positionInformationSize:
+		if d[0] != 0x01 {
+			return Location{}, fmt.Errorf("invalid position information: %x", d)
+		}
+		return Location{Code: PositionInformation, Position: append([]byte{}, d[1:]...)}, nil
 	default:
-		return "unknown"
+		return Location{}, fmt.Errorf("invalid length of installation location: %d", len(d))
+	}
+
+	b := d[0]
+	switch {
+	case b == 0x00:
+		return Location{Code: NotSet}, nil
+	case b == 0xFF:
+		return Location{Code: Undetermined}, nil
+	case b>>7 == 1:
+		return Location{Code: FreeDefinition, Number: int32(b & 0x7F)}, nil
+	case b>>3 == 0:
+		// 0x01-0x07 are reserved (0x01 is used only for position information)
+		return Location{}, fmt.Errorf("reserved installation location: %#x", b)
+	}
+	return Location{Code: LocationCode((b >> 3) & 0x0F), Number: int32(b & 0x07)}, nil
+}
+
+// Data returns serialized data of installation location
+func (l Location) Data() (Data, error) {
+	switch l.Code {
+	case NotSet:
+		return Data{0x00}, nil
+	case Undetermined:
+		return Data{0xFF}, nil
+	case FreeDefinition:
+		if l.Number < 0 || l.Number > 0x7E {
+			return nil, fmt.Errorf("free definition out of range [0-126]: %d", l.Number)
+		}
+		return Data{0x80 | byte(l.Number)}, nil
+	case PositionInformation:
+		if len(l.Position) != positionInformationSize {
+			return nil, fmt.Errorf("invalid length of position information: %d", len(l.Position))
+		}
+		return append(Data{0x01}, l.Position...), nil
+	}
+	if l.Code < Living || l.Code > Other {
+		return nil, fmt.Errorf("invalid location code: %d", l.Code)
+	}
+	if l.Number < 0 || l.Number > 7 {
+		return nil, fmt.Errorf("location number out of range [0-7]: %d", l.Number)
+	}
+	return Data{byte(l.Code)<<3 | byte(l.Number)}, nil
+}
+
+// LocationProperty returns property to set installation location
+func LocationProperty(l Location) (Property, error) {
+	d, err := l.Data()
+	if err != nil {
+		return Property{}, err
+	}
+	return Property{Code: byte(InstallationLocation), Len: len(d), Data: d}, nil
+}
+
+// SetInstallLocation changes installation location of the device
+func (d *Device) SetInstallLocation(ctx context.Context, l Location) error {
+	p, err := LocationProperty(l)
+	if err != nil {
+		return err
+	}
+	return d.Set(ctx, p)
+}
+
+// parseLocation parses installation location (0x81)
+func parseLocation(p Property) (Location, bool) {
+	loc, err := ParseLocation(p.Data)
+	if err != nil {
+		logger.Printf("[Error] InstallationLocation: %s", err)
+		return Location{}, false
 	}
+	logger.Printf("InstallationLocation: %s", loc)
+	return loc, true
 }
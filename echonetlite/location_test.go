@@ -0,0 +1,106 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var position = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+
+func TestParseLocation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		data  []byte
+		want  Location
+		label string
+	}{
+		{[]byte{0x00}, Location{Code: NotSet}, "NotSet"},
+		{[]byte{0x08}, Location{Code: Living}, "Living"},
+		{[]byte{0x31}, Location{Code: Washroom, Number: 1}, "Washroom1"},
+		{[]byte{0x7f}, Location{Code: Other, Number: 7}, "Other7"},
+		{[]byte{0x85}, Location{Code: FreeDefinition, Number: 5}, "Free5"},
+		{[]byte{0xff}, Location{Code: Undetermined}, "Undetermined"},
+		{append([]byte{0x01}, position...), Location{Code: PositionInformation, Position: position}, "Position0102030405060708090a0b0c0d0e0f10"},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocation(tt.data)
+		if err != nil {
+			t.Errorf("%x: %v", tt.data, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%x: (-want +got):\n%s", tt.data, diff)
+		}
+		if got.Label() != tt.label {
+			t.Errorf("%x: label differs: want:%s got:%s", tt.data, tt.label, got.Label())
+		}
+
+		d, err := got.Data()
+		if err != nil {
+			t.Errorf("%x: %v", tt.data, err)
+			continue
+		}
+		if diff := cmp.Diff(Data(tt.data), d); diff != "" {
+			t.Errorf("%x: serialized data differs: (-want +got):\n%s", tt.data, diff)
+		}
+	}
+
+	for _, d := range [][]byte{{}, {0x01}, {0x07}, {0x02, 0x00}, append([]byte{0x02}, position...)} {
+		if _, err := ParseLocation(d); err == nil {
+			t.Errorf("%x: error expected", d)
+		}
+	}
+}
+
+func TestLocation_Data_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, l := range []Location{
+		{Code: Room, Number: 8},
+		{Code: FreeDefinition, Number: 0x7f},
+		{Code: PositionInformation, Position: position[:15]},
+		{Code: LocationCode(0x10)},
+	} {
+		if _, err := l.Data(); err == nil {
+			t.Errorf("%+v: error expected", l)
+		}
+	}
+}
+
+func TestDevice_SetInstallLocation(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var got Data
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		esv := GetRes
+		switch req.ESV {
+		case Get:
+			props = append(props, Property{Code: 0x9e, Len: 2, Data: Data{0x01, 0x81}})
+		case SetC:
+			esv = SetRes
+			got = req.Properties[0].Data
+			props = append(props, Property{Code: 0x81, Len: 0, Data: Data{}})
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, esv, props)
+		return res.Serialize()
+	})
+
+	d := NewDevice(c, "192.168.1.15", NewObject(SensorGroup, TemperatureSensor, 1))
+	if err := d.SetInstallLocation(ctx, Location{Code: Room, Number: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Data{0x42}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if err := d.SetInstallLocation(ctx, Location{Code: Room, Number: 9}); err == nil {
+		t.Errorf("error expected for invalid location")
+	}
+}
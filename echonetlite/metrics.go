@@ -0,0 +1,113 @@
+package echonetlite
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gauge is description of gauge exported by collectors in this package.
+// Values are not kept in it but written on each scrape from the last states of devices.
+type gauge struct {
+	name   string
+	desc   *prometheus.Desc
+	labels []string
+}
+
+// deviceGauges is gauges of devices, which are described by collector of ControllerNode
+var deviceGauges []*gauge
+
+// newGauge returns gauge with opts and variable labels
+func newGauge(opts prometheus.GaugeOpts, labels []string) *gauge {
+	name := prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name)
+	return &gauge{
+		name:   name,
+		desc:   prometheus.NewDesc(name, opts.Help, labels, opts.ConstLabels),
+		labels: labels,
+	}
+}
+
+// newDeviceGauge returns gauge for devices found by ControllerNode
+func newDeviceGauge(opts prometheus.GaugeOpts, labels []string) *gauge {
+	g := newGauge(opts, labels)
+	deviceGauges = append(deviceGauges, g)
+	return g
+}
+
+// metricWriter writes metrics to ch with timestamp of the last update.
+// Metrics with the same labels are written only once.
+type metricWriter struct {
+	ch      chan<- prometheus.Metric
+	updated time.Time
+	written map[string]bool
+}
+
+func newMetricWriter(ch chan<- prometheus.Metric) *metricWriter {
+	return &metricWriter{ch: ch, written: map[string]bool{}}
+}
+
+// withLabel returns copy of labels with name=value
+func withLabel(labels prometheus.Labels, name, value string) prometheus.Labels {
+	l := prometheus.Labels{name: value}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return l
+}
+
+// setGauge writes v to the gauge, or nothing if v is nil
+func (w *metricWriter) setGauge(g *gauge, labels prometheus.Labels, v *float64) {
+	if v == nil {
+		return
+	}
+	values := make([]string, len(g.labels))
+	for i, name := range g.labels {
+		values[i] = labels[name]
+	}
+	key := g.desc.String() + "\xff" + strings.Join(values, "\xff")
+	if w.written[key] {
+		return
+	}
+	w.written[key] = true
+
+	m, err := prometheus.NewConstMetric(g.desc, prometheus.GaugeValue, *v, values...)
+	if err != nil {
+		w.ch <- prometheus.NewInvalidMetric(g.desc, err)
+		return
+	}
+	if !w.updated.IsZero() {
+		m = prometheus.NewMetricWithTimestamp(w.updated, m)
+	}
+	w.ch <- m
+}
+
+// setStateSet writes 1 to the gauge labeled current and 0 to the other states
+func (w *metricWriter) setStateSet(g *gauge, labels prometheus.Labels, name string, states []string, current string) {
+	for _, s := range states {
+		v := 0.0
+		if s == current {
+			v = 1
+		}
+		w.setGauge(g, withLabel(labels, name, s), &v)
+	}
+}
+
+// describeGauges sends descriptions of gauges to ch
+func describeGauges(ch chan<- *prometheus.Desc, gauges []*gauge) {
+	for _, g := range gauges {
+		ch <- g.desc
+	}
+}
+
+// boolValue returns 1 for true, 0 for false and nil for nil
+func boolValue(b *bool) *float64 {
+	if b == nil {
+		return nil
+	}
+	v := 0.0
+	if *b {
+		v = 1
+	}
+	return &v
+}
@@ -0,0 +1,101 @@
+package echonetlite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// testCollector is collector of gauges written by collect
+type testCollector struct {
+	gauges  []*gauge
+	collect func(w *metricWriter)
+}
+
+func (c testCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeGauges(ch, c.gauges)
+}
+
+func (c testCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(newMetricWriter(ch))
+}
+
+// collectFunc returns collector of device gauges written by f
+func collectFunc(f func(w *metricWriter)) prometheus.Collector {
+	return testCollector{gauges: deviceGauges, collect: f}
+}
+
+// gatherMetric returns metric of g with exactly labels collected by c, or nil if it is not found
+func gatherMetric(t *testing.T, c prometheus.Collector, g *gauge, labels prometheus.Labels) *dto.Metric {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("failed to register collector: %s", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather: %s", err)
+	}
+	for _, f := range families {
+		if f.GetName() != g.name {
+			continue
+		}
+	next:
+		for _, m := range f.GetMetric() {
+			if len(m.GetLabel()) != len(labels) {
+				continue
+			}
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; !ok || v != l.GetValue() {
+					continue next
+				}
+			}
+			return m
+		}
+	}
+	return nil
+}
+
+// gaugeValue returns value of g with exactly labels collected by c, or NaN if it is not found
+func gaugeValue(t *testing.T, c prometheus.Collector, g *gauge, labels prometheus.Labels) float64 {
+	t.Helper()
+	m := gatherMetric(t, c, g, labels)
+	if m == nil {
+		return math.NaN()
+	}
+	return m.GetGauge().GetValue()
+}
+
+// hasGauge returns true if g with exactly labels is collected by c
+func hasGauge(t *testing.T, c prometheus.Collector, g *gauge, labels prometheus.Labels) bool {
+	t.Helper()
+	return gatherMetric(t, c, g, labels) != nil
+}
+
+func TestMetricWriter(t *testing.T) {
+	g := newGauge(prometheus.GaugeOpts{Namespace: "test", Name: "value"}, []string{"id", "state"})
+	c := testCollector{gauges: []*gauge{g}, collect: func(w *metricWriter) {
+		v := 1.0
+		w.setGauge(g, prometheus.Labels{"id": "a", "state": "x"}, &v)
+		// 同じラベルは最初の値だけ
+		v2 := 2.0
+		w.setGauge(g, prometheus.Labels{"id": "a", "state": "x"}, &v2)
+		w.setGauge(g, prometheus.Labels{"id": "b", "state": "x"}, nil)
+		w.setStateSet(g, prometheus.Labels{"id": "c"}, "state", []string{"on", "off"}, "off")
+	}}
+
+	if v := gaugeValue(t, c, g, prometheus.Labels{"id": "a", "state": "x"}); v != 1 {
+		t.Errorf("value differs: want:1 got:%v", v)
+	}
+	if hasGauge(t, c, g, prometheus.Labels{"id": "b", "state": "x"}) {
+		t.Errorf("nil value should not be collected")
+	}
+	if v := gaugeValue(t, c, g, prometheus.Labels{"id": "c", "state": "on"}); v != 0 {
+		t.Errorf("state set differs: want:0 got:%v", v)
+	}
+	if m := gatherMetric(t, c, g, prometheus.Labels{"id": "c", "state": "off"}); m == nil || m.TimestampMs != nil {
+		t.Errorf("metric without update time should not have timestamp: %v", m)
+	}
+}
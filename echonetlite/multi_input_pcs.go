@@ -0,0 +1,105 @@
+package echonetlite
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MultiInputPCSObject is object for multiple-input PCS.
+// Interconnection is nil if the PCS doesn't provide it,
+// and power and cumulative energies are nil if they are not available or out of range.
+type MultiInputPCSObject struct {
+	Instance            int
+	Interconnection     *Interconnection // 系統連系状態
+	InstantPower        *float64         // 瞬時電力計測値 [W] (出力:+ 入力:-)
+	CumulativeEnergy    *float64         // 積算電力量計測値(正方向) [kWh]
+	CumulativeEnergyRev *float64         // 積算電力量計測値(逆方向) [kWh]
+}
+
+// multiInputPCSProperties is properties requested to multiple-input PCS
+var multiInputPCSProperties = []PropertyCode{
+	ID,
+	PCSInterconnectionType,
+	PCSInstantPower,
+	PCSCumulativeEnergy,
+	PCSCumulativeEnergyRev,
+}
+
+func parseMultiInputPCSProperty(p Property, obj *MultiInputPCSObject) bool {
+	switch PropertyCode(p.Code) {
+	case PCSInterconnectionType:
+		if len(p.Data) == 1 {
+			i := Interconnection(p.Data[0])
+			obj.Interconnection = &i
+		}
+		return true
+	case PCSInstantPower:
+		obj.InstantPower = measured(SignedLong, p.Data, 1)
+		return true
+	case PCSCumulativeEnergy:
+		obj.CumulativeEnergy = measured(UnsignedLong, p.Data, 0.001)
+		return true
+	case PCSCumulativeEnergyRev:
+		obj.CumulativeEnergyRev = measured(UnsignedLong, p.Data, 0.001)
+		return true
+	}
+	return false
+}
+
+// RequestMultiInputPCSState sends request to each multiple-input PCS found in instance lists
+func (elc *ControllerNode) RequestMultiInputPCSState() {
+	elc.requestClass(HomeEquipmentGroup, MultiInputPCS, multiInputPCSProperties)
+}
+
+var (
+	pcsPower = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "pcs",
+			Name:      "power_watts",
+			Help:      "instantaneous output (+) / input (-) electric power [W]",
+		},
+		[]string{"id", "instance"},
+	)
+	pcsEnergy = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "pcs",
+			Name:      "cumulative_energy_kwh",
+			Help:      "cumulative electric energy [kWh]",
+		},
+		[]string{"id", "instance", "direction"},
+	)
+)
+
+func collectMultiInputPCSMetrics(w *metricWriter, base prometheus.Labels, o MultiInputPCSObject) {
+	labels := withLabel(base, "instance", strconv.Itoa(o.Instance))
+	w.setGauge(pcsPower, labels, o.InstantPower)
+	w.setGauge(pcsEnergy, withLabel(labels, "direction", "normal"), o.CumulativeEnergy)
+	w.setGauge(pcsEnergy, withLabel(labels, "direction", "reverse"), o.CumulativeEnergyRev)
+}
+
+// MultiInputPCSClient is client to read multiple-input PCS (0x02A5)
+type MultiInputPCSClient struct {
+	*Device
+}
+
+// NewMultiInputPCSClient returns MultiInputPCSClient for the instance at addr
+func NewMultiInputPCSClient(elc *ControllerNode, addr string, instance int) *MultiInputPCSClient {
+	return &MultiInputPCSClient{NewDevice(elc, addr, NewObject(HomeEquipmentGroup, MultiInputPCS, instance))}
+}
+
+// State reads current state of the multiple-input PCS
+func (m *MultiInputPCSClient) State(ctx context.Context) (MultiInputPCSObject, error) {
+	props, err := m.Get(ctx, multiInputPCSProperties...)
+	if err != nil {
+		return MultiInputPCSObject{}, err
+	}
+	obj := MultiInputPCSObject{Instance: m.Object.Num}
+	for _, p := range props {
+		parseMultiInputPCSProperty(p, &obj)
+	}
+	return obj, nil
+}
@@ -0,0 +1,40 @@
+package echonetlite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMultiInputPCS(t *testing.T) {
+	props := []Property{
+		{Code: 0xd0, Len: 1, Data: Data{0x00}},
+		{Code: 0xe7, Len: 4, Data: Data{0xff, 0xff, 0xf8, 0x30}},
+		{Code: 0xe0, Len: 4, Data: Data{0x00, 0x01, 0xe2, 0x40}},
+		{Code: 0xe3, Len: 0, Data: Data{}},
+	}
+	got, err := parseProperties(NewObject(HomeEquipmentGroup, MultiInputPCS, 1), props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := InterconnectionReverseFlow
+	want := MultiInputPCSObject{
+		Instance:         1,
+		Interconnection:  &i,
+		InstantPower:     float64Ptr(-2000),
+		CumulativeEnergy: float64Ptr(123.456),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	c := collectFunc(func(w *metricWriter) { collectMultiInputPCSMetrics(w, prometheus.Labels{"id": "192.168.1.23"}, want) })
+	labels := prometheus.Labels{"id": "192.168.1.23", "instance": "1"}
+	if got := gaugeValue(t, c, pcsPower, labels); got != -2000 {
+		t.Errorf("power differs: want:-2000 got:%v", got)
+	}
+	if hasGauge(t, c, pcsEnergy, withLabel(labels, "direction", "reverse")) {
+		t.Errorf("gauge for unavailable property should not exist")
+	}
+}
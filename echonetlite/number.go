@@ -0,0 +1,184 @@
+package echonetlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// NumberType is numeric data type of property
+type NumberType int
+
+// definition of numeric data types
+const (
+	UnsignedChar NumberType = iota
+	SignedChar
+	UnsignedShort
+	SignedShort
+	UnsignedLong
+	SignedLong
+)
+
+// Size returns byte length of t
+func (t NumberType) Size() int {
+	switch t {
+	case UnsignedChar, SignedChar:
+		return 1
+	case UnsignedShort, SignedShort:
+		return 2
+	}
+	return 4
+}
+
+func (t NumberType) signed() bool {
+	return t == SignedChar || t == SignedShort || t == SignedLong
+}
+
+func (t NumberType) String() string {
+	s := "unsigned"
+	if t.signed() {
+		s = "signed"
+	}
+	switch t.Size() {
+	case 1:
+		return s + " char"
+	case 2:
+		return s + " short"
+	}
+	return s + " long"
+}
+
+// ParseNumberType returns NumberType from data type written in class dictionary (e.g. "signed char")
+func ParseNumberType(s string) (NumberType, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, t := range []NumberType{UnsignedChar, SignedChar, UnsignedShort, SignedShort, UnsignedLong, SignedLong} {
+		if s == t.String() {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// NumberStatus represents whether the value is measured or one of special codes
+type NumberStatus int
+
+// definition of number status
+const (
+	NumberValid     NumberStatus = iota
+	NumberNoData                 // 計測不能 (signed: max-1)
+	NumberOverflow               // オーバーフローコード (max)
+	NumberUnderflow              // アンダーフローコード (signed: min, unsigned: max-1)
+)
+
+func (s NumberStatus) String() string {
+	switch s {
+	case NumberValid:
+		return "valid"
+	case NumberNoData:
+		return "no data"
+	case NumberOverflow:
+		return "overflow"
+	case NumberUnderflow:
+		return "underflow"
+	}
+	return "unknown"
+}
+
+// Number is decoded numeric property
+type Number struct {
+	Value  int64
+	Status NumberStatus
+}
+
+// Valid returns true if n is not a special code
+func (n Number) Valid() bool {
+	return n.Status == NumberValid
+}
+
+// Scaled returns value multiplied by scale (e.g. 0.1 for 0.1℃ unit), false if n is not valid
+func (n Number) Scaled(scale float64) (float64, bool) {
+	if !n.Valid() {
+		return 0, false
+	}
+	return float64(n.Value) * scale, true
+}
+
+func (n Number) String() string {
+	if !n.Valid() {
+		return n.Status.String()
+	}
+	return fmt.Sprint(n.Value)
+}
+
+// DecodeNumber decodes big endian data as t and detects special codes
+func DecodeNumber(t NumberType, d []byte) (Number, error) {
+	if len(d) != t.Size() {
+		return Number{}, fmt.Errorf("invalid length for %s: %d", t, len(d))
+	}
+
+	var raw uint64
+	switch t.Size() {
+	case 1:
+		raw = uint64(d[0])
+	case 2:
+		raw = uint64(binary.BigEndian.Uint16(d))
+	default:
+		raw = uint64(binary.BigEndian.Uint32(d))
+	}
+
+	bits := uint(t.Size() * 8)
+	if !t.signed() {
+		max := uint64(1)<<bits - 1
+		switch raw {
+		case max:
+			return Number{Value: int64(raw), Status: NumberOverflow}, nil
+		case max - 1:
+			return Number{Value: int64(raw), Status: NumberUnderflow}, nil
+		}
+		return Number{Value: int64(raw)}, nil
+	}
+
+	// sign extension
+	v := int64(raw)
+	if raw&(1<<(bits-1)) != 0 {
+		v -= 1 << bits
+	}
+	max := int64(1)<<(bits-1) - 1
+	switch v {
+	case max:
+		return Number{Value: v, Status: NumberOverflow}, nil
+	case max - 1:
+		return Number{Value: v, Status: NumberNoData}, nil
+	case -max - 1:
+		return Number{Value: v, Status: NumberUnderflow}, nil
+	}
+	return Number{Value: v}, nil
+}
+
+// measured decodes d as t multiplied by scale, and returns nil if it is invalid or a special code
+func measured(t NumberType, d []byte, scale float64) *float64 {
+	n, err := DecodeNumber(t, d)
+	if err != nil {
+		return nil
+	}
+	v, ok := n.Scaled(scale)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// EncodeNumber encodes v as t in big endian, and returns error if v is out of range of t
+func EncodeNumber(t NumberType, v int64) ([]byte, error) {
+	bits := uint(t.Size() * 8)
+	min, max := int64(0), int64(1)<<bits-1
+	if t.signed() {
+		min, max = -(int64(1) << (bits - 1)), int64(1)<<(bits-1)-1
+	}
+	if v < min || v > max {
+		return nil, fmt.Errorf("%d is out of range of %s", v, t)
+	}
+	d := make([]byte, 8)
+	binary.BigEndian.PutUint64(d, uint64(v))
+	return d[8-t.Size():], nil
+}
@@ -0,0 +1,98 @@
+package echonetlite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeNumber(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name  string
+		t     NumberType
+		input Data
+		want  Number
+	}{
+		{name: "unsigned char", t: UnsignedChar, input: Data{0xfb}, want: Number{Value: 251}},
+		{name: "unsigned char overflow", t: UnsignedChar, input: Data{0xff}, want: Number{Value: 255, Status: NumberOverflow}},
+		{name: "unsigned char underflow", t: UnsignedChar, input: Data{0xfe}, want: Number{Value: 254, Status: NumberUnderflow}},
+		{name: "signed char negative", t: SignedChar, input: Data{0xfb}, want: Number{Value: -5}},
+		{name: "signed char no data", t: SignedChar, input: Data{0x7e}, want: Number{Value: 126, Status: NumberNoData}},
+		{name: "signed char overflow", t: SignedChar, input: Data{0x7f}, want: Number{Value: 127, Status: NumberOverflow}},
+		{name: "signed char underflow", t: SignedChar, input: Data{0x80}, want: Number{Value: -128, Status: NumberUnderflow}},
+		{name: "signed short", t: SignedShort, input: Data{0xff, 0x9c}, want: Number{Value: -100}},
+		{name: "signed short no data", t: SignedShort, input: Data{0x7f, 0xfe}, want: Number{Value: 32766, Status: NumberNoData}},
+		{name: "unsigned short", t: UnsignedShort, input: Data{0x01, 0xf8}, want: Number{Value: 504}},
+		{name: "signed long", t: SignedLong, input: Data{0xff, 0xff, 0xff, 0xfe}, want: Number{Value: -2}},
+		{name: "unsigned long underflow", t: UnsignedLong, input: Data{0xff, 0xff, 0xff, 0xfe}, want: Number{Value: 0xfffffffe, Status: NumberUnderflow}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecodeNumber(tc.t, tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := DecodeNumber(SignedShort, Data{0x01}); err == nil {
+		t.Errorf("error expected for invalid length")
+	}
+}
+
+func TestParseNumberType(t *testing.T) {
+	t.Parallel()
+
+	got, ok := ParseNumberType("Signed short")
+	if !ok || got != SignedShort {
+		t.Errorf("want:%v, got:%v %v", SignedShort, got, ok)
+	}
+	if _, ok := ParseNumberType("unsigned char×2"); ok {
+		t.Errorf("array type is not a number")
+	}
+}
+
+func TestEncodeNumber(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		t    NumberType
+		v    int64
+		want Data
+		err  bool
+	}{
+		{t: UnsignedChar, v: 26, want: Data{0x1a}},
+		{t: SignedChar, v: -2, want: Data{0xfe}},
+		{t: SignedShort, v: -100, want: Data{0xff, 0x9c}},
+		{t: UnsignedLong, v: 504, want: Data{0x00, 0x00, 0x01, 0xf8}},
+		{t: UnsignedChar, v: 256, err: true},
+		{t: SignedChar, v: -129, err: true},
+		{t: UnsignedShort, v: -1, err: true},
+	}
+	for _, tc := range testcases {
+		got, err := EncodeNumber(tc.t, tc.v)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s %d: error expected", tc.t, tc.v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %d: %s", tc.t, tc.v, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, Data(got)); diff != "" {
+			t.Errorf("%s %d: (-want +got):\n%s", tc.t, tc.v, diff)
+		}
+	}
+}
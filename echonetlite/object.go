@@ -1,8 +1,10 @@
 package echonetlite
 
 import (
+	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 )
 
 // Object is object
@@ -49,3 +51,50 @@ func (o Object) String() string {
 func (o Object) Data() []byte {
 	return []byte{byte(o.ClassGroup), byte(o.Class), byte(o.Num)}
 }
+
+// ParseObject parses EOJ in hex such as "013001" or "0x013001"
+func ParseObject(s string) (Object, error) {
+	d, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(d) != 3 {
+		return Object{}, fmt.Errorf("invalid EOJ %q, class group, class and instance in hex such as 013001 is expected", s)
+	}
+	return NewObjectFromData(d), nil
+}
+
+// ParseClass parses class group and class code in hex such as "0130" or "0x0130"
+func ParseClass(s string) (ClassGroupCode, ClassCode, error) {
+	d, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(d) != 2 {
+		return 0, 0, fmt.Errorf("invalid class %q, class group and class code in hex such as 0130 is expected", s)
+	}
+	return ClassGroupCode(d[0]), ClassCode(d[1]), nil
+}
+
+// ParseObjectList parses instance list (0xD5, 0xD6) or class list (0xD7) data.
+// Class list has 2 bytes for each entry and instance number is set to 0.
+func ParseObjectList(d Data) ([]Object, error) {
+	if len(d) == 0 {
+		return nil, fmt.Errorf("empty object list")
+	}
+	num := int(d[0])
+	body := d[1:]
+
+	size := 3
+	if num > 0 && len(body) == num*2 {
+		size = 2
+	}
+	if len(body) < num*size {
+		return nil, fmt.Errorf("invalid object list length: %d for %d objects", len(body), num)
+	}
+
+	objs := make([]Object, 0, num)
+	for i := 0; i < num; i++ {
+		e := body[i*size : (i+1)*size]
+		obj := Object{ClassGroup: ClassGroupCode(e[0]), Class: ClassCode(e[1])}
+		if size == 3 {
+			obj.Num = int(e[2])
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
@@ -45,3 +45,39 @@ func TestObject_ClassCode(t *testing.T) {
 		t.Errorf("ClassCode differs: (-want +got)\n%s", diff)
 	}
 }
+
+func TestParseObject(t *testing.T) {
+	for _, s := range []string{"013001", "0x013001", " 013001"} {
+		got, err := ParseObject(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+		if want := NewObject(AirConditionerGroup, HomeAirConditioner, 1); got != want {
+			t.Errorf("%q: want:%s got:%s", s, want, got)
+		}
+	}
+	for _, s := range []string{"", "0130", "01300101", "zz3001"} {
+		if _, err := ParseObject(s); err == nil {
+			t.Errorf("%q: error expected", s)
+		}
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	for _, s := range []string{"0130", "0x0130", " 0130"} {
+		group, class, err := ParseClass(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+		if group != AirConditionerGroup || class != HomeAirConditioner {
+			t.Errorf("%q: want:0130 got:%02x%02x", s, byte(group), byte(class))
+		}
+	}
+	for _, s := range []string{"", "01", "013001", "zz30"} {
+		if _, _, err := ParseClass(s); err == nil {
+			t.Errorf("%q: error expected", s)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Probe reads properties of obj at addr synchronously, and returns collector of them decoded by dict
+// in the same metrics as PropertyExporter. Properties in Get property map (0x9F) are read if codes is empty.
+// It is for multi-target exporter whose metrics are only for a scrape,
+// and error is returned if none of the properties is available, e.g. the device responds with Get_SNA to all of them.
+func Probe(ctx context.Context, elc *ControllerNode, dict ClassDictionary, addr string, obj Object, codes []PropertyCode) (prometheus.Collector, error) {
+	e := NewPropertyExporter(elc, dict, PropertyFilter{})
+	key := stateKey{addr: addr, obj: obj}
+	d := NewDevice(elc, addr, obj)
+
+	var err error
+	if len(codes) == 0 {
+		err = e.poll(ctx, key, d)
+	} else {
+		err = e.read(ctx, key, d, codes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	n := len(e.values[key])
+	e.mu.Unlock()
+	if n == 0 {
+		return nil, fmt.Errorf("no property is available from %s [%s]", addr, obj)
+	}
+	return e, nil
+}
@@ -0,0 +1,72 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		// 存在しないデバイスは応答しない
+		if req.DEOJ != aircon {
+			return nil
+		}
+		props := []Property{}
+		for _, p := range req.Properties {
+			switch PropertyCode(p.Code) {
+			case 0xbb:
+				props = append(props, Property{Code: 0xbb, Len: 1, Data: Data{0x19}})
+			default:
+				t.Errorf("unexpected EPC: %02x", p.Code)
+			}
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, props)
+		return res.Serialize()
+	})
+
+	collector, err := Probe(ctx, c, testAirconDictionary(), "192.168.1.71", aircon, []PropertyCode{0xbb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := prometheus.Labels{"class": "0130", "epc": "bb", "name": "室内温度計測値", "unit": "℃", "instance": "1", "device": "192.168.1.71"}
+	if got := gaugeValue(t, collector, propertyValueGauge, labels); got != 25 {
+		t.Errorf("value differs: want:25 got:%v", got)
+	}
+
+	ctx, cancel = context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := Probe(ctx, c, testAirconDictionary(), "192.168.1.72", NewObject(SensorGroup, TemperatureSensor, 1), []PropertyCode{0xe0}); err == nil {
+		t.Errorf("error expected for no response")
+	}
+}
+
+func TestProbe_NoProperty(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// 全てのプロパティに Get_SNA で応答する
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		for _, p := range req.Properties {
+			props = append(props, Property{Code: p.Code, Len: 0, Data: Data{}})
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetSNA, props)
+		return res.Serialize()
+	})
+
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	if _, err := Probe(ctx, c, testAirconDictionary(), "192.168.1.71", aircon, []PropertyCode{0xbb}); err == nil {
+		t.Errorf("error expected for no available property")
+	}
+}
@@ -1,6 +1,10 @@
 package echonetlite
 
-import "fmt"
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
 
 // PropertyCode represents property code
 type PropertyCode byte
@@ -43,13 +47,99 @@ const (
 	InstanceListS            PropertyCode = 0xD6 // 自ノードインスタンスリストS
 	ClassListS               PropertyCode = 0xD7 // 自ノードクラスリストS
 
+	// センサ関連機器クラスグループ
+	// Class Group Code: 0x00
+	MeasuredValue           PropertyCode = 0xE0 // 計測値 (温度, 湿度, CO2濃度, 照度[lx])
+	MeasuredIlluminanceKlux PropertyCode = 0xE1 // 照度計測値[klx]
+
 	// 家庭用エアコンクラス
-	MeasuredRoomTemperature    PropertyCode = 0xBB
-	MeasuredOutdoorTemperature PropertyCode = 0xBE
+	// Class Group Code: 0x01, Class Code: 0x30
+	AirFlowRateSetting         PropertyCode = 0xA0 // 風量設定
+	OperationModeSetting       PropertyCode = 0xB0 // 運転モード設定
+	TemperatureSetting         PropertyCode = 0xB3 // 温度設定値
+	DehumidifyingSetting       PropertyCode = 0xB4 // 除湿モード時相対湿度設定値
+	MeasuredRoomHumidity       PropertyCode = 0xBA // 室内相対湿度計測値
+	MeasuredRoomTemperature    PropertyCode = 0xBB // 室内温度計測値
+	MeasuredOutdoorTemperature PropertyCode = 0xBE // 外気温度計測値
+
+	// 蓄電池クラス
+	// Class Group Code: 0x02, Class Code: 0x7D
+	BatteryACChargedEnergy        PropertyCode = 0xA8 // AC積算充電電力量計測値
+	BatteryACDischargedEnergy     PropertyCode = 0xA9 // AC積算放電電力量計測値
+	BatteryWorkingOperationStatus PropertyCode = 0xCF // 運転動作状態
+	BatteryInstantPower           PropertyCode = 0xD3 // 瞬時充放電電力計測値
+	BatteryOperationMode          PropertyCode = 0xDA // 運転モード設定
+	BatteryRemainingCapacity1     PropertyCode = 0xE2 // 蓄電残量1
+	BatteryRemainingCapacity3     PropertyCode = 0xE4 // 蓄電残量3
+
+	// 電気自動車充放電器クラス
+	// Class Group Code: 0x02, Class Code: 0x7E
+	EVDischargeableCapacity1          PropertyCode = 0xC0 // 車載電池の放電可能容量値1
+	EVRemainingDischargeableCapacity1 PropertyCode = 0xC2 // 車載電池の放電可能残容量1
+	EVRemainingDischargeableCapacity3 PropertyCode = 0xC4 // 車載電池の放電可能残容量3
+	EVRatedChargePower                PropertyCode = 0xC5 // 定格充電能力
+	EVRatedDischargePower             PropertyCode = 0xC6 // 定格放電能力
+	EVConnectionStatus                PropertyCode = 0xC7 // 車両接続・充放電可否状態
+	EVChargeableCapacity              PropertyCode = 0xCE // 車載電池の充電可能容量値
+	EVRemainingChargeableCapacity     PropertyCode = 0xCF // 車載電池の充電可能残容量値
+	EVInstantPower                    PropertyCode = 0xD3 // 瞬時充放電電力計測値
+	EVOperationMode                   PropertyCode = 0xDA // 運転モード設定
+	EVRemainingCapacity1              PropertyCode = 0xE2 // 車載電池の電池残容量1
+	EVRemainingCapacity3              PropertyCode = 0xE4 // 車載電池の電池残容量3
+	EVChargingPowerSetting            PropertyCode = 0xEB // 充電電力設定値
+	EVDischargingPowerSetting         PropertyCode = 0xEC // 放電電力設定値
+
+	// 電気温水器クラス
+	// Class Group Code: 0x02, Class Code: 0x6B
+	WaterHeatingSetting        PropertyCode = 0xB0 // 沸き上げ自動設定
+	WaterHeatingStatus         PropertyCode = 0xB2 // 沸き上げ中状態
+	WaterHeatingTemperature    PropertyCode = 0xB3 // 沸き上げ湯温設定値
+	DaytimeReheatingPermission PropertyCode = 0xC0 // 昼間沸き増し許可設定
+	HotWaterSupplyTemperature  PropertyCode = 0xD1 // 給湯温度設定値
+	BathWaterTemperature       PropertyCode = 0xD3 // 風呂温度設定値
+	RemainingHotWater          PropertyCode = 0xE1 // 残湯量計測値
+	TankCapacity               PropertyCode = 0xE2 // タンク容量値
+	BathAutoMode               PropertyCode = 0xE3 // 風呂自動モード設定
+
+	// 一般照明クラス, 単機能照明クラス
+	// Class Group Code: 0x02, Class Code: 0x90, 0x91
+	IlluminanceLevel    PropertyCode = 0xB0 // 照度レベル設定
+	LightColorSetting   PropertyCode = 0xB1 // 光色設定
+	SceneControlSetting PropertyCode = 0xC0 // シーン制御設定
+	SceneControlNumber  PropertyCode = 0xC1 // シーン制御設定可能数
+
+	// 住宅用太陽光発電クラス
+	// Class Group Code: 0x02, Class Code: 0x79
+	SolarInterconnectionType   PropertyCode = 0xD0 // 系統連系状態
+	SolarOutputRestraintStatus PropertyCode = 0xD1 // 出力抑制状態
+	SolarInstantGeneration     PropertyCode = 0xE0 // 瞬時発電電力計測値
+	SolarCumulativeGeneration  PropertyCode = 0xE1 // 積算発電電力量計測値
+	SolarCumulativeSold        PropertyCode = 0xE3 // 積算売電電力量計測値
+
+	// 分電盤メータリングクラス
+	// Class Group Code: 0x02, Class Code: 0x87
+	BoardChannels             PropertyCode = 0xB1 // 計測チャンネル数(片方向)
+	BoardEnergyChannelRange   PropertyCode = 0xB2 // 積算電力量計測値リスト範囲指定(片方向)
+	BoardEnergyList           PropertyCode = 0xB3 // 積算電力量計測値リスト(片方向)
+	BoardPowerChannelRange    PropertyCode = 0xB6 // 瞬時電力計測値リスト範囲指定(片方向)
+	BoardPowerList            PropertyCode = 0xB7 // 瞬時電力計測値リスト(片方向)
+	BoardCumulativeEnergy     PropertyCode = 0xC0 // 主幹積算電力量計測値(正方向)
+	BoardCumulativeEnergyRev  PropertyCode = 0xC1 // 主幹積算電力量計測値(逆方向)
+	BoardCumulativeEnergyUnit PropertyCode = 0xC2 // 主幹積算電力量単位
+	BoardInstantPower         PropertyCode = 0xC6 // 主幹瞬時電力計測値
+	BoardInstantCurrent       PropertyCode = 0xC7 // 主幹瞬時電流計測値
+	BoardInstantVoltage       PropertyCode = 0xC8 // 主幹瞬時電圧計測値
+
+	// マルチ入力PCSクラス
+	// Class Group Code: 0x02, Class Code: 0xA5
+	PCSInterconnectionType PropertyCode = 0xD0 // 系統連系状態
+	PCSCumulativeEnergy    PropertyCode = 0xE0 // 積算電力量計測値(正方向)
+	PCSCumulativeEnergyRev PropertyCode = 0xE3 // 積算電力量計測値(逆方向)
+	PCSInstantPower        PropertyCode = 0xE7 // 瞬時電力計測値
 
 	// 低圧スマート電力量メータクラス
 	// Class Group Code: 0x02, Class Code: 0x88
-	Coefficient                           PropertyCode = 0xD7 // 係数
+	Coefficient                           PropertyCode = 0xD3 // 係数
 	IntegralPowerConsumptionValidDigits   PropertyCode = 0xD7 // 積算電力量有効桁数
 	IntegralPowerConsumption              PropertyCode = 0xE0 // 積算電力量計測値(正方向計測値)
 	IntegralPowerConsumptionUnit          PropertyCode = 0xE1 // 積算電力量単位(正方向、逆方向計測値)
@@ -85,3 +175,62 @@ func (p Property) Serialize() Data {
 func (p Property) String() string {
 	return fmt.Sprintf("EPC[%x] PDC[%d] EDT[%s]", p.Code, p.Len, p.Data)
 }
+
+// ParsePropertyMap parses property map (0x9D, 0x9E, 0x9F) data
+func ParsePropertyMap(d Data) ([]PropertyCode, error) {
+	if len(d) == 0 {
+		return nil, fmt.Errorf("empty property map")
+	}
+	num := int(d[0])
+
+	if num < 16 {
+		if len(d) < 1+num {
+			return nil, fmt.Errorf("invalid property map length: %d for %d properties", len(d), num)
+		}
+		codes := make([]PropertyCode, 0, num)
+		for _, c := range d[1 : 1+num] {
+			codes = append(codes, PropertyCode(c))
+		}
+		return codes, nil
+	}
+
+	// 16 or more properties are described as bitmap.
+	// Bit b of byte i represents EPC 0x80 + i + 0x10*b
+	if len(d) < 17 {
+		return nil, fmt.Errorf("invalid property map length: %d for %d properties", len(d), num)
+	}
+	codes := make([]PropertyCode, 0, num)
+	for b := uint(0); b < 8; b++ {
+		for i := 0; i < 16; i++ {
+			if d[1+i]&(1<<b) != 0 {
+				codes = append(codes, PropertyCode(0x80+i+0x10*int(b)))
+			}
+		}
+	}
+	return codes, nil
+}
+
+// ParsePropertyCode parses EPC in hex such as "80" or "0x80"
+func ParsePropertyCode(s string) (PropertyCode, error) {
+	d, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(d) != 1 {
+		return 0, fmt.Errorf("invalid EPC %q, property code in hex such as 80 is expected", s)
+	}
+	return PropertyCode(d[0]), nil
+}
+
+// ParsePropertyCodes parses comma separated EPCs such as "80,bb", and returns empty list for empty string
+func ParsePropertyCodes(s string) ([]PropertyCode, error) {
+	codes := []PropertyCode{}
+	if s == "" {
+		return codes, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		c, err := ParsePropertyCode(f)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, nil
+}
@@ -0,0 +1,356 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MaxPropertiesPerGet is number of properties requested in a Get frame, which devices are expected to accept
+const MaxPropertiesPerGet = 16
+
+// PropertyRule matches properties by class and EPC.
+// Any class is matched if AnyClass is true, and any property of the class is matched if Codes is empty.
+type PropertyRule struct {
+	AnyClass   bool
+	ClassGroup ClassGroupCode
+	Class      ClassCode
+	Codes      []PropertyCode
+}
+
+// ParsePropertyRule parses rule in format "<class>[:<epc>,...]" such as "0130:bb,be", "0288" or "*:e0".
+// Class and EPCs are parsed by ParseClass and ParsePropertyCode.
+func ParsePropertyRule(s string) (PropertyRule, error) {
+	r := PropertyRule{}
+	tokens := strings.SplitN(strings.TrimSpace(s), ":", 2)
+
+	if tokens[0] == "*" {
+		r.AnyClass = true
+	} else {
+		group, class, err := ParseClass(tokens[0])
+		if err != nil {
+			return PropertyRule{}, fmt.Errorf("invalid rule %q: %w", s, err)
+		}
+		r.ClassGroup, r.Class = group, class
+	}
+
+	if len(tokens) == 2 {
+		for _, epc := range strings.Split(tokens[1], ",") {
+			c, err := ParsePropertyCode(epc)
+			if err != nil {
+				return PropertyRule{}, fmt.Errorf("invalid rule %q: %w", s, err)
+			}
+			r.Codes = append(r.Codes, c)
+		}
+	}
+	return r, nil
+}
+
+func (r PropertyRule) String() string {
+	s := "*"
+	if !r.AnyClass {
+		s = fmt.Sprintf("%02x%02x", byte(r.ClassGroup), byte(r.Class))
+	}
+	if len(r.Codes) == 0 {
+		return s
+	}
+	codes := make([]string, 0, len(r.Codes))
+	for _, c := range r.Codes {
+		codes = append(codes, fmt.Sprintf("%02x", byte(c)))
+	}
+	return s + ":" + strings.Join(codes, ",")
+}
+
+// Match returns true if property code of obj matches r
+func (r PropertyRule) Match(obj Object, code PropertyCode) bool {
+	if !r.AnyClass && (obj.ClassGroup != r.ClassGroup || obj.Class != r.Class) {
+		return false
+	}
+	if len(r.Codes) == 0 {
+		return true
+	}
+	for _, c := range r.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// PropertyFilter selects properties exported by PropertyExporter.
+// All properties are selected if Include is empty, and properties matched by Exclude are never selected.
+type PropertyFilter struct {
+	Include []PropertyRule
+	Exclude []PropertyRule
+}
+
+// Match returns true if property code of obj is selected by f
+func (f PropertyFilter) Match(obj Object, code PropertyCode) bool {
+	for _, r := range f.Exclude {
+		if r.Match(obj, code) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, r := range f.Include {
+		if r.Match(obj, code) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	propertyValueGauge = newGauge(
+		prometheus.GaugeOpts{
+			Namespace: "echonet",
+			Subsystem: "property",
+			Name:      "value",
+			Help:      "numeric property value decoded by the class dictionary",
+		},
+		[]string{"class", "epc", "name", "unit", "instance", "device"},
+	)
+	propertyStateGauge = newGauge(
+		prometheus.GaugeOpts{
+			Namespace: "echonet",
+			Subsystem: "property",
+			Name:      "state",
+			Help:      "1 for the current value of enumerated property and 0 for the others",
+		},
+		[]string{"class", "epc", "name", "instance", "device", "state"},
+	)
+)
+
+// propertyValue is data of a property and the time it is read
+type propertyValue struct {
+	data    Data
+	updated time.Time
+}
+
+// PropertyExporter polls properties in Get property map (0x9F) of all devices found by the controller,
+// and exports numbers and enumerations which are decoded by the class dictionary.
+// It is prometheus.Collector to be registered to the registry of the program.
+type PropertyExporter struct {
+	controller *ControllerNode
+	dict       ClassDictionary
+	filter     PropertyFilter
+
+	mu      sync.Mutex
+	devices map[stateKey]*Device
+	values  map[stateKey]map[PropertyCode]propertyValue
+}
+
+// NewPropertyExporter returns PropertyExporter for devices found by elc
+func NewPropertyExporter(elc *ControllerNode, dict ClassDictionary, filter PropertyFilter) *PropertyExporter {
+	return &PropertyExporter{
+		controller: elc,
+		dict:       dict,
+		filter:     filter,
+		devices:    map[stateKey]*Device{},
+		values:     map[stateKey]map[PropertyCode]propertyValue{},
+	}
+}
+
+// SetFilter replaces filter used from the next Poll.
+// Values of properties which are no longer selected are dropped.
+func (e *PropertyExporter) SetFilter(filter PropertyFilter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.filter = filter
+	for key, values := range e.values {
+		for c := range values {
+			if !filter.Match(key.obj, c) {
+				delete(values, c)
+			}
+		}
+	}
+}
+
+// property returns dictionary of property if it is number or enumeration
+func (e *PropertyExporter) property(obj Object, code PropertyCode) (PropertyInfo, bool) {
+	info, ok := e.dict.Property(obj.ClassGroup, obj.Class, code)
+	if !ok {
+		return info, false
+	}
+	if len(info.Values) > 0 {
+		return info, true
+	}
+	_, ok = ParseNumberType(info.DataType)
+	return info, ok
+}
+
+// Poll reads properties of all devices found by the controller.
+// Errors are logged and the other devices are polled.
+func (e *PropertyExporter) Poll(ctx context.Context) {
+	found := map[stateKey]bool{}
+	for _, n := range e.controller.Nodes() {
+		for _, obj := range n.Devices {
+			key := stateKey{addr: n.Address, obj: obj}
+			found[key] = true
+
+			e.mu.Lock()
+			d, ok := e.devices[key]
+			if !ok {
+				d = NewDevice(e.controller, n.Address, obj)
+				e.devices[key] = d
+			}
+			e.mu.Unlock()
+
+			if err := e.poll(ctx, key, d); err != nil {
+				clogger.Printf("[Error] failed to poll properties of %s [%s]: %s", n.Address, obj, err)
+			}
+		}
+	}
+
+	// 消えたデバイスやアドレスが変わったデバイスは削除する
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key := range e.devices {
+		if !found[key] {
+			delete(e.devices, key)
+			delete(e.values, key)
+		}
+	}
+}
+
+func (e *PropertyExporter) poll(ctx context.Context, key stateKey, d *Device) error {
+	getMap, err := d.GettableProperties(ctx)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	filter := e.filter
+	e.mu.Unlock()
+
+	codes := []PropertyCode{}
+	for c := range getMap {
+		if _, ok := e.property(d.Object, c); ok && filter.Match(d.Object, c) {
+			codes = append(codes, c)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return e.read(ctx, key, d, codes)
+}
+
+// read gets properties of codes from d and keeps them to be collected
+func (e *PropertyExporter) read(ctx context.Context, key stateKey, d *Device, codes []PropertyCode) error {
+	for start := 0; start < len(codes); start += MaxPropertiesPerGet {
+		end := start + MaxPropertiesPerGet
+		if end > len(codes) {
+			end = len(codes)
+		}
+		props, err := d.Get(ctx, codes[start:end]...)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		e.mu.Lock()
+		values, ok := e.values[key]
+		if !ok {
+			values = map[PropertyCode]propertyValue{}
+			e.values[key] = values
+		}
+		for _, p := range props {
+			if len(p.Data) == 0 {
+				delete(values, PropertyCode(p.Code))
+				continue
+			}
+			values[PropertyCode(p.Code)] = propertyValue{data: p.Data, updated: now}
+		}
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector
+func (e *PropertyExporter) Describe(ch chan<- *prometheus.Desc) {
+	describeGauges(ch, []*gauge{propertyValueGauge, propertyStateGauge})
+}
+
+// Collect implements prometheus.Collector
+func (e *PropertyExporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	keys := make([]stateKey, 0, len(e.values))
+	values := make(map[stateKey]map[PropertyCode]propertyValue, len(e.values))
+	for k, v := range e.values {
+		keys = append(keys, k)
+		values[k] = map[PropertyCode]propertyValue{}
+		for c, p := range v {
+			values[k][c] = p
+		}
+	}
+	e.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].addr != keys[j].addr {
+			return keys[i].addr < keys[j].addr
+		}
+		return keys[i].obj.String() < keys[j].obj.String()
+	})
+
+	w := newMetricWriter(ch)
+	for _, k := range keys {
+		device := e.controller.DeviceID(k.addr, k.obj)
+		codes := make([]PropertyCode, 0, len(values[k]))
+		for c := range values[k] {
+			codes = append(codes, c)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+		for _, c := range codes {
+			info, ok := e.property(k.obj, c)
+			if !ok {
+				continue
+			}
+			v := values[k][c]
+			w.updated = v.updated
+			labels := prometheus.Labels{
+				"class":    fmt.Sprintf("%02x%02x", byte(k.obj.ClassGroup), byte(k.obj.Class)),
+				"epc":      fmt.Sprintf("%02x", byte(c)),
+				"name":     info.Detail,
+				"instance": strconv.Itoa(k.obj.Num),
+				"device":   device,
+			}
+			collectPropertyValue(w, labels, info, v.data)
+		}
+	}
+}
+
+// collectPropertyValue writes data as state set if the property is enumeration, or as number
+func collectPropertyValue(w *metricWriter, labels prometheus.Labels, info PropertyInfo, data Data) {
+	if len(info.Values) > 0 {
+		if len(data) != 1 {
+			return
+		}
+		names := map[string]bool{}
+		for _, name := range info.Values {
+			names[name] = true
+		}
+		states := make([]string, 0, len(names))
+		for name := range names {
+			states = append(states, name)
+		}
+		sort.Strings(states)
+		w.setStateSet(propertyStateGauge, labels, "state", states, info.Values[data[0]])
+		return
+	}
+
+	t, ok := ParseNumberType(info.DataType)
+	if !ok {
+		return
+	}
+	n, err := DecodeNumber(t, data)
+	if err != nil || !n.Valid() {
+		return
+	}
+	v := float64(n.Value)
+	w.setGauge(propertyValueGauge, withLabel(labels, "unit", info.Unit), &v)
+}
@@ -0,0 +1,151 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParsePropertyRule(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want PropertyRule
+		err  bool
+	}{
+		{in: "0130:bb,be", want: PropertyRule{ClassGroup: AirConditionerGroup, Class: HomeAirConditioner, Codes: []PropertyCode{0xbb, 0xbe}}},
+		{in: "0x0288", want: PropertyRule{ClassGroup: HomeEquipmentGroup, Class: LowVoltageSmartMeter}},
+		{in: "*:e0", want: PropertyRule{AnyClass: true, Codes: []PropertyCode{0xe0}}},
+		{in: "0x0130:0xbb, be", want: PropertyRule{ClassGroup: AirConditionerGroup, Class: HomeAirConditioner, Codes: []PropertyCode{0xbb, 0xbe}}},
+		{in: "01", err: true},
+		{in: "0130:", err: true},
+		{in: "0130:bbb", err: true},
+	}
+	for _, tc := range testcases {
+		got, err := ParsePropertyRule(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: error expected", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%s: (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
+
+func TestPropertyFilter_Match(t *testing.T) {
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	battery := NewObject(HomeEquipmentGroup, StorageBattery, 1)
+	f := PropertyFilter{
+		Include: []PropertyRule{{ClassGroup: AirConditionerGroup, Class: HomeAirConditioner}, {AnyClass: true, Codes: []PropertyCode{0xe4}}},
+		Exclude: []PropertyRule{{ClassGroup: AirConditionerGroup, Class: HomeAirConditioner, Codes: []PropertyCode{0x9f}}},
+	}
+	if !f.Match(aircon, 0xbb) {
+		t.Errorf("included class should match")
+	}
+	if f.Match(aircon, 0x9f) {
+		t.Errorf("excluded EPC should not match")
+	}
+	if !f.Match(battery, 0xe4) || f.Match(battery, 0xd3) {
+		t.Errorf("only included EPC of other class should match")
+	}
+	if !(PropertyFilter{}).Match(battery, 0xd3) {
+		t.Errorf("empty filter should match all")
+	}
+}
+
+// testAirconDictionary returns class dictionary of air conditioner for tests
+func testAirconDictionary() ClassDictionary {
+	dict := ClassDictionary{}
+	dict.add(AirConditionerGroup, HomeAirConditioner, ClassInfo{
+		ClassGroup: AirConditionerGroup,
+		Class:      HomeAirConditioner,
+		Properties: PropertyDictionary{
+			0x80: {Code: 0x80, Detail: "動作状態", DataType: "unsigned char", Values: map[byte]string{0x30: "ON", 0x31: "OFF"}},
+			0xb3: {Code: 0xb3, Detail: "温度設定値", Unit: "℃", DataType: "unsigned char"},
+			0xbb: {Code: 0xbb, Detail: "室内温度計測値", Unit: "℃", DataType: "signed char"},
+			0x88: {Code: 0x88, Detail: "異常発生状態", DataType: "unsigned char"},
+			0x8a: {Code: 0x8a, Detail: "メーカコード", DataType: "unsigned char×3"},
+		},
+	})
+	return dict
+}
+
+func TestPropertyExporter(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	dict := testAirconDictionary()
+
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		for _, p := range req.Properties {
+			switch PropertyCode(p.Code) {
+			case GetPropertyMap:
+				props = append(props, Property{Code: 0x9f, Len: 6, Data: Data{0x05, 0x80, 0x88, 0x8a, 0xb3, 0xbb}})
+			case 0x80:
+				props = append(props, Property{Code: 0x80, Len: 1, Data: Data{0x30}})
+			case 0xb3:
+				props = append(props, Property{Code: 0xb3, Len: 1, Data: Data{0x1a}})
+			case 0xbb:
+				props = append(props, Property{Code: 0xbb, Len: 1, Data: Data{0xfe}})
+			default:
+				t.Errorf("unexpected EPC: %02x", p.Code)
+			}
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, props)
+		return res.Serialize()
+	})
+	c.addNode("192.168.1.70", aircon)
+
+	filter := PropertyFilter{Exclude: []PropertyRule{{AnyClass: true, Codes: []PropertyCode{0x88}}}}
+	e := NewPropertyExporter(c, dict, filter)
+	e.Poll(ctx)
+
+	labels := prometheus.Labels{"class": "0130", "epc": "b3", "name": "温度設定値", "unit": "℃", "instance": "1", "device": "192.168.1.70"}
+	if got := gaugeValue(t, e, propertyValueGauge, labels); got != 26 {
+		t.Errorf("value differs: want:26 got:%v", got)
+	}
+	labels = prometheus.Labels{"class": "0130", "epc": "bb", "name": "室内温度計測値", "unit": "℃", "instance": "1", "device": "192.168.1.70"}
+	if got := gaugeValue(t, e, propertyValueGauge, labels); got != -2 {
+		t.Errorf("signed value differs: want:-2 got:%v", got)
+	}
+	state := prometheus.Labels{"class": "0130", "epc": "80", "name": "動作状態", "instance": "1", "device": "192.168.1.70", "state": "ON"}
+	if got := gaugeValue(t, e, propertyStateGauge, state); got != 1 {
+		t.Errorf("state differs: want:1 got:%v", got)
+	}
+	state["state"] = "OFF"
+	if got := gaugeValue(t, e, propertyStateGauge, state); got != 0 {
+		t.Errorf("state differs: want:0 got:%v", got)
+	}
+}
+
+func TestPropertyExporter_SetFilter(t *testing.T) {
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	key := stateKey{addr: "192.168.1.70", obj: aircon}
+	e := NewPropertyExporter(&ControllerNode{}, testAirconDictionary(), PropertyFilter{})
+	e.values[key] = map[PropertyCode]propertyValue{
+		0xb3: {data: Data{0x1a}, updated: time.Now()},
+		0xbb: {data: Data{0xfe}, updated: time.Now()},
+	}
+
+	e.SetFilter(PropertyFilter{Exclude: []PropertyRule{{AnyClass: true, Codes: []PropertyCode{0xbb}}}})
+
+	if _, ok := e.values[key][0xbb]; ok {
+		t.Errorf("excluded property should be dropped")
+	}
+	if _, ok := e.values[key][0xb3]; !ok {
+		t.Errorf("selected property should be kept")
+	}
+}
@@ -17,3 +17,21 @@ func TestPropertySerialize(t *testing.T) {
 	}
 
 }
+
+func TestParsePropertyCodes(t *testing.T) {
+	got, err := ParsePropertyCodes("80, 0xbb,b3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]PropertyCode{OperationStatus, 0xbb, 0xb3}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if got, err := ParsePropertyCodes(""); err != nil || len(got) != 0 {
+		t.Errorf("empty list expected: %v %v", got, err)
+	}
+	for _, s := range []string{"8", "80,", "8080", "zz"} {
+		if _, err := ParsePropertyCodes(s); err == nil {
+			t.Errorf("%q: error expected", s)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package echonetlite
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PropertyValue is property decoded by class dictionary.
+// Number is set for numeric property and State is set for enumerated property.
+type PropertyValue struct {
+	Code   PropertyCode
+	Name   string
+	Unit   string
+	Number *float64
+	State  string
+	Raw    Data
+}
+
+// MarshalJSON encodes v with EPC and raw data in hex
+func (v PropertyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EPC   string   `json:"epc"`
+		Name  string   `json:"name,omitempty"`
+		Unit  string   `json:"unit,omitempty"`
+		Value *float64 `json:"value,omitempty"`
+		State string   `json:"state,omitempty"`
+		Raw   string   `json:"raw"`
+	}{
+		EPC:   fmt.Sprintf("%02x", byte(v.Code)),
+		Name:  v.Name,
+		Unit:  v.Unit,
+		Value: v.Number,
+		State: v.State,
+		Raw:   hex.EncodeToString(v.Raw),
+	})
+}
+
+// DecodeProperty decodes p of obj by dict.
+// Only raw data is set if the property is not in dict or it is neither number nor enumeration.
+func (dict ClassDictionary) DecodeProperty(obj Object, p Property) PropertyValue {
+	v := PropertyValue{Code: PropertyCode(p.Code), Raw: p.Data}
+	info, ok := dict.Property(obj.ClassGroup, obj.Class, v.Code)
+	if !ok {
+		return v
+	}
+	v.Name = info.Detail
+	if len(info.Values) > 0 {
+		if len(p.Data) == 1 {
+			v.State = info.Values[p.Data[0]]
+		}
+		return v
+	}
+	if t, ok := ParseNumberType(info.DataType); ok {
+		v.Unit = info.Unit
+		if n, err := DecodeNumber(t, p.Data); err == nil && n.Valid() {
+			f := float64(n.Value)
+			v.Number = &f
+		}
+	}
+	return v
+}
+
+// EncodeProperty returns property of obj with value s validated by dict.
+// s is name of enumerated value (e.g. ON), decimal number for numeric property, or raw data in hex with 0x prefix.
+func (dict ClassDictionary) EncodeProperty(obj Object, code PropertyCode, s string) (Property, error) {
+	info, ok := dict.Property(obj.ClassGroup, obj.Class, code)
+	if !ok {
+		return Property{}, fmt.Errorf("EPC %02x is not defined for class %02x%02x", byte(code), byte(obj.ClassGroup), byte(obj.Class))
+	}
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "0x") {
+		d, err := hex.DecodeString(s[2:])
+		if err != nil || len(d) == 0 {
+			return Property{}, fmt.Errorf("invalid hex data for %s: %q", info.Detail, s)
+		}
+		if info.Size > 0 && len(d) != info.Size {
+			return Property{}, fmt.Errorf("%s needs %d bytes: %q", info.Detail, info.Size, s)
+		}
+		return Property{Code: byte(code), Len: len(d), Data: d}, nil
+	}
+
+	if len(info.Values) > 0 {
+		names := []string{}
+		for b, name := range info.Values {
+			if strings.EqualFold(name, s) {
+				return Property{Code: byte(code), Len: 1, Data: Data{b}}, nil
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Property{}, fmt.Errorf("invalid value for %s: %q, one of %s is expected", info.Detail, s, strings.Join(names, ", "))
+	}
+
+	if t, ok := ParseNumberType(info.DataType); ok {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return Property{}, fmt.Errorf("invalid value for %s: %q, %s is expected", info.Detail, s, t)
+		}
+		d, err := EncodeNumber(t, n)
+		if err != nil {
+			return Property{}, fmt.Errorf("invalid value for %s: %w", info.Detail, err)
+		}
+		return Property{Code: byte(code), Len: len(d), Data: d}, nil
+	}
+
+	return Property{}, fmt.Errorf("value of %s (%s) must be hex data with 0x prefix: %q", info.Detail, info.DataType, s)
+}
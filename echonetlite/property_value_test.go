@@ -0,0 +1,73 @@
+package echonetlite
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClassDictionary_DecodeProperty(t *testing.T) {
+	dict := testAirconDictionary()
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+
+	v := dict.DecodeProperty(aircon, Property{Code: 0xbb, Len: 1, Data: Data{0xfe}})
+	if v.Number == nil || *v.Number != -2 || v.Unit != "℃" || v.Name != "室内温度計測値" {
+		t.Errorf("number differs: %+v", v)
+	}
+	v = dict.DecodeProperty(aircon, Property{Code: 0x80, Len: 1, Data: Data{0x30}})
+	if v.State != "ON" || v.Number != nil {
+		t.Errorf("state differs: %+v", v)
+	}
+	v = dict.DecodeProperty(aircon, Property{Code: 0xbb, Len: 1, Data: Data{0x7e}})
+	if v.Number != nil {
+		t.Errorf("special code should not be number: %v", *v.Number)
+	}
+
+	b, err := json.Marshal(dict.DecodeProperty(aircon, Property{Code: 0xb3, Len: 1, Data: Data{0x1a}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"epc":"b3","name":"温度設定値","unit":"℃","value":26,"raw":"1a"}`
+	if string(b) != want {
+		t.Errorf("JSON differs: want:%s got:%s", want, b)
+	}
+}
+
+func TestClassDictionary_EncodeProperty(t *testing.T) {
+	dict := testAirconDictionary()
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+
+	testcases := []struct {
+		code PropertyCode
+		in   string
+		want Data
+		err  bool
+	}{
+		{code: 0x80, in: "off", want: Data{0x31}},
+		{code: 0xb3, in: "26", want: Data{0x1a}},
+		{code: 0xb3, in: "0x1b", want: Data{0x1b}},
+		{code: 0x80, in: "STANDBY", err: true},
+		{code: 0xb3, in: "300", err: true},
+		{code: 0xb3, in: "warm", err: true},
+		{code: 0x8a, in: "1", err: true},
+		{code: 0xe0, in: "0x00", err: true},
+	}
+	for _, tc := range testcases {
+		got, err := dict.EncodeProperty(aircon, tc.code, tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%02x %s: error expected", byte(tc.code), tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%02x %s: %s", byte(tc.code), tc.in, err)
+			continue
+		}
+		want := Property{Code: byte(tc.code), Len: len(tc.want), Data: tc.want}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%02x %s: (-want +got):\n%s", byte(tc.code), tc.in, diff)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package echonetlite
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Message is a frame received from other node
+type Message struct {
+	Address string
+	Frame   Frame
+}
+
+// Host returns IP address of the sender without port
+func (m Message) Host() string {
+	return hostOf(m.Address)
+}
+
+// ServiceError is returned when a node responds with *_SNA
+type ServiceError struct {
+	Address string
+	Frame   Frame
+}
+
+func (e *ServiceError) Error() string {
+	codes := []string{}
+	for _, c := range e.Rejected() {
+		codes = append(codes, fmt.Sprintf("%02x", byte(c)))
+	}
+	return fmt.Sprintf("%s from %s %s: rejected EPC[%s]", e.Frame.ESV, e.Address, e.Frame.SEOJ, strings.Join(codes, " "))
+}
+
+// Rejected returns codes of properties which were not accepted
+func (e *ServiceError) Rejected() []PropertyCode {
+	codes := []PropertyCode{}
+	for _, p := range e.Frame.Properties {
+		// Set: accepted properties are returned with no data
+		// Get: rejected properties are returned with no data
+		rejected := p.Len == 0
+		if e.Frame.ESV == SetISNA || e.Frame.ESV == SetCSNA {
+			rejected = p.Len != 0
+		}
+		if rejected {
+			codes = append(codes, PropertyCode(p.Code))
+		}
+	}
+	return codes
+}
+
+// TransactionID returns TID as number
+func (f Frame) TransactionID() uint16 {
+	if len(f.TID) != 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(f.TID)
+}
+
+// Property returns property with code and true if f has it
+func (f Frame) Property(code PropertyCode) (Property, bool) {
+	for _, p := range f.Properties {
+		if PropertyCode(p.Code) == code {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// Subscribe returns channel which receives all frames from other nodes and function to stop subscription.
+// Frames are dropped if the channel is not read.
+func (elc *ControllerNode) Subscribe() (<-chan Message, func()) {
+	ch := make(chan Message, 32)
+
+	elc.mu.Lock()
+	if elc.subscribers == nil {
+		elc.subscribers = map[chan Message]struct{}{}
+	}
+	elc.subscribers[ch] = struct{}{}
+	elc.mu.Unlock()
+
+	return ch, func() {
+		elc.mu.Lock()
+		defer elc.mu.Unlock()
+		if _, ok := elc.subscribers[ch]; ok {
+			delete(elc.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// waiter collects responses to a request.
+// Responses are kept without limit so that none of them is dropped while many nodes respond to a broadcast.
+type waiter struct {
+	host     string // host which the request is sent to, responses from any host are accepted if empty
+	messages []Message
+	received chan struct{} // signaled when a response is added
+}
+
+// dispatch passes m to the waiting request and subscribers
+func (elc *ControllerNode) dispatch(m Message) {
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+
+	if m.Frame.ESV.isResponseOrNotification() {
+		// ユニキャストの要求には宛先からの応答だけを渡す
+		if w, ok := elc.waiters[m.Frame.TransactionID()]; ok && (w.host == "" || w.host == hostOf(m.Address)) {
+			w.messages = append(w.messages, m)
+			select {
+			case w.received <- struct{}{}:
+			default:
+			}
+		}
+	}
+	for ch := range elc.subscribers {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// prepare assigns transaction ID to f and registers waiter for its responses from addr, or from any node if addr is empty
+func (elc *ControllerNode) prepare(f *Frame, addr string) (uint16, *waiter) {
+	tid := elc.nextTID()
+	f.TID = Data{byte(tid >> 8), byte(tid)}
+
+	w := &waiter{received: make(chan struct{}, 1)}
+	if addr != "" {
+		w.host = hostOf(addr)
+	}
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+	if elc.waiters == nil {
+		elc.waiters = map[uint16]*waiter{}
+	}
+	elc.waiters[tid] = w
+	return tid, w
+}
+
+// release unregisters waiter of tid and returns responses it received
+func (elc *ControllerNode) release(tid uint16) []Message {
+	elc.mu.Lock()
+	defer elc.mu.Unlock()
+	w, ok := elc.waiters[tid]
+	if !ok {
+		return nil
+	}
+	delete(elc.waiters, tid)
+	return w.messages
+}
+
+// send sends f to addr, or to multicast address if addr is empty
+func (elc *ControllerNode) send(addr string, f *Frame) error {
+	if addr == "" {
+		elc.sendFrame(f)
+		return nil
+	}
+	if elc.UnicastSender == nil {
+		return fmt.Errorf("unicast sender is not available")
+	}
+	clogger.Printf(">>>>>>>> SEND [%s]: %s\n", addr, f)
+	return elc.UnicastSender.SendTo(f.Serialize(), hostOf(addr))
+}
+
+// Request sends f to addr and waits for the response from addr which has the same transaction ID.
+// f is sent to multicast address if addr is empty, and the first response from any node is returned.
+// If the response is *_SNA, it is returned with *ServiceError.
+func (elc *ControllerNode) Request(ctx context.Context, addr string, f *Frame) (Message, error) {
+	tid, w := elc.prepare(f, addr)
+
+	err := elc.send(addr, f)
+	if err != nil {
+		elc.release(tid)
+		return Message{}, fmt.Errorf("failed to send: %w", err)
+	}
+
+	select {
+	case <-w.received:
+		m := elc.release(tid)[0]
+		if m.Frame.ESV.isSNA() {
+			return m, &ServiceError{Address: m.Address, Frame: m.Frame}
+		}
+		return m, nil
+	case <-ctx.Done():
+		elc.release(tid)
+		return Message{}, fmt.Errorf("no response from [%s]: %w", addr, ctx.Err())
+	}
+}
+
+// Broadcast sends f to multicast address and collects responses until ctx is done
+func (elc *ControllerNode) Broadcast(ctx context.Context, f *Frame) []Message {
+	tid, _ := elc.prepare(f, "")
+
+	elc.sendFrame(f)
+
+	<-ctx.Done()
+	messages := elc.release(tid)
+	if messages == nil {
+		return []Message{}
+	}
+	return messages
+}
+
+// newGetFrame returns Get frame of codes to obj
+func newGetFrame(tid uint16, obj Object, codes []PropertyCode) Frame {
+	props := make([]Property, 0, len(codes))
+	for _, c := range codes {
+		props = append(props, Property{Code: byte(c), Len: 0, Data: []byte{}})
+	}
+	return NewFrame(tid, NewObject(ControllerGroup, Controller, 0x01), obj, Get, props)
+}
+
+// requestClass sends Get of codes to each object of the class found in instance lists.
+// Responses are not waited, and metrics are updated when they are received.
+func (elc *ControllerNode) requestClass(group ClassGroupCode, class ClassCode, codes []PropertyCode) {
+	for _, n := range elc.Nodes() {
+		for _, obj := range n.Devices {
+			if obj.ClassGroup != group || obj.Class != class {
+				continue
+			}
+			f := newGetFrame(elc.nextTID(), obj, codes)
+			if err := elc.send(n.Address, &f); err != nil {
+				clogger.Printf("[Error] failed to request %s [%s]: %s", n.Address, obj, err)
+			}
+		}
+	}
+}
+
+// Get reads properties of obj at addr.
+// If some of properties are not available, available ones are returned with *ServiceError.
+func (elc *ControllerNode) Get(ctx context.Context, addr string, obj Object, codes ...PropertyCode) ([]Property, error) {
+	f := newGetFrame(0, obj, codes)
+
+	m, err := elc.Request(ctx, addr, &f)
+	if serr, ok := err.(*ServiceError); ok {
+		available := []Property{}
+		for _, p := range m.Frame.Properties {
+			if p.Len != 0 {
+				available = append(available, p)
+			}
+		}
+		return available, serr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.Frame.Properties, nil
+}
+
+// Set writes properties of obj at addr with SetC.
+// Set to EV charger/discharger returns *EVSetNotPermittedError without sending if it is not permitted by EVPermission.
+func (elc *ControllerNode) Set(ctx context.Context, addr string, obj Object, props ...Property) error {
+	if obj.ClassGroup == HomeEquipmentGroup && obj.Class == EVChargerDischarger {
+		if err := CheckEVChargerSet(elc.EVPermission, props...); err != nil {
+			return err
+		}
+	}
+	f := NewFrame(0, NewObject(ControllerGroup, Controller, 0x01), obj, SetC, props)
+	_, err := elc.Request(ctx, addr, &f)
+	return err
+}
+
+// instanceList returns objects notified by instance list properties of node profile
+func instanceList(f Frame) []Object {
+	if !f.SEOJ.isNodeProfile() {
+		return nil
+	}
+	objs := []Object{}
+	for _, p := range f.Properties {
+		switch PropertyCode(p.Code) {
+		case InstanceListNotification, InstanceListS:
+			list, err := ParseObjectList(p.Data)
+			if err != nil {
+				logger.Println(err)
+				continue
+			}
+			objs = append(objs, list...)
+		}
+	}
+	return objs
+}
@@ -0,0 +1,241 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/matsuu/go-el-controller/transport"
+)
+
+// newTestController returns ControllerNode listening on mock receivers.
+// respond is called for each unicast frame and its result is delivered as response.
+func newTestController(t *testing.T, ctx context.Context, respond func(req Frame) []byte) *ControllerNode {
+	t.Helper()
+	return newTestNetwork(t, ctx, respond, func(req Frame) []transport.ReceiveResult { return nil })
+}
+
+// newTestNetwork returns ControllerNode listening on mock receivers.
+// unicast and multicast are called for each frame sent to them and their results are delivered as responses.
+func newTestNetwork(t *testing.T, ctx context.Context, unicast func(req Frame) []byte, multicast func(req Frame) []transport.ReceiveResult) *ControllerNode {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+
+	mr := transport.NewMockMulticastReceiver(ctrl)
+	ur := transport.NewMockUnicastReceiver(ctrl)
+	ms := transport.NewMockMulticastSender(ctrl)
+	us := transport.NewMockUnicastSender(ctrl)
+
+	mch := make(chan transport.ReceiveResult, 16)
+	uch := make(chan transport.ReceiveResult, 1)
+	mr.EXPECT().Start(gomock.Any(), MulticastIP, Port).Return(mch)
+	ur.EXPECT().Start(gomock.Any(), Port).Return(uch)
+
+	parse := func(data []byte) Frame {
+		req, err := ParseFrame(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	ms.EXPECT().Send(gomock.Any()).DoAndReturn(func(data []byte) error {
+		for _, res := range multicast(parse(data)) {
+			mch <- res
+		}
+		return nil
+	}).AnyTimes()
+	us.EXPECT().SendTo(gomock.Any(), gomock.Any()).DoAndReturn(func(data []byte, ip string) error {
+		if res := unicast(parse(data)); res != nil {
+			uch <- transport.ReceiveResult{Data: res, Address: ip + ":3610"}
+		}
+		return nil
+	}).AnyTimes()
+
+	c := &ControllerNode{
+		MulticastReceiver: mr,
+		UnicastReceiver:   ur,
+		MulticastSender:   ms,
+		UnicastSender:     us,
+		ResolveMAC: func(ip string) (net.HardwareAddr, error) {
+			return nil, fmt.Errorf("%s is not in neighbor table", ip)
+		},
+	}
+	c.Listen(ctx)
+	return c
+}
+
+func TestControllerNode_Get(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, []Property{
+			{Code: 0xbb, Len: 1, Data: Data{0x1b}},
+			{Code: 0xbe, Len: 1, Data: Data{0x16}},
+		})
+		return res.Serialize()
+	})
+
+	got, err := c.Get(ctx, "192.168.1.15", NewObject(AirConditionerGroup, HomeAirConditioner, 1), MeasuredRoomTemperature, MeasuredOutdoorTemperature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Property{
+		{Code: 0xbb, Len: 1, Data: Data{0x1b}},
+		{Code: 0xbe, Len: 1, Data: Data{0x16}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("properties differ: (-want +got)\n%s", diff)
+	}
+
+	nodes := c.Nodes()
+	wantNodes := []Node{{Address: "192.168.1.15", Devices: []Object{NewObject(AirConditionerGroup, HomeAirConditioner, 1)}}}
+	if diff := cmp.Diff(wantNodes, nodes, cmpopts.IgnoreFields(Node{}, "LastSeen")); diff != "" {
+		t.Errorf("nodes differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestControllerNode_Set_SNA(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, SetCSNA, []Property{
+			{Code: 0x80, Len: 0, Data: Data{}},
+			{Code: 0xb3, Len: 1, Data: Data{0x64}},
+		})
+		return res.Serialize()
+	})
+
+	err := c.Set(ctx, "192.168.1.15", NewObject(AirConditionerGroup, HomeAirConditioner, 1),
+		Property{Code: 0x80, Len: 1, Data: Data{0x30}},
+		Property{Code: 0xb3, Len: 1, Data: Data{0x64}},
+	)
+	serr, ok := err.(*ServiceError)
+	if !ok {
+		t.Fatalf("ServiceError expected: %v", err)
+	}
+	if diff := cmp.Diff([]PropertyCode{0xb3}, serr.Rejected()); diff != "" {
+		t.Errorf("rejected properties differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestControllerNode_Request_Timeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := newTestController(t, ctx, func(req Frame) []byte { return nil })
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer reqCancel()
+	_, err := c.Get(reqCtx, "192.168.1.15", NewObject(AirConditionerGroup, HomeAirConditioner, 1), OperationStatus)
+	if err == nil {
+		t.Errorf("error expected")
+	}
+}
+
+func TestControllerNode_Dispatch_ManyResponses(t *testing.T) {
+	t.Parallel()
+
+	c := &ControllerNode{}
+	f := NewFrame(0, NewObject(ControllerGroup, Controller, 1), NewObject(HomeEquipmentGroup, GeneralLighting, 0), Get, nil)
+	tid, _ := c.prepare(&f, "")
+
+	// 読み出す前に多数の応答が届いても捨てない
+	const n = 40
+	for i := 0; i < n; i++ {
+		res := NewFrame(tid, NewObject(HomeEquipmentGroup, GeneralLighting, 1), f.SEOJ, GetRes, nil)
+		c.dispatch(Message{Address: fmt.Sprintf("192.168.2.%d:3610", i+1), Frame: res})
+	}
+	if messages := c.release(tid); len(messages) != n {
+		t.Errorf("responses are dropped: want:%d got:%d", n, len(messages))
+	}
+}
+
+func TestControllerNode_Dispatch_Source(t *testing.T) {
+	t.Parallel()
+
+	c := &ControllerNode{}
+	aircon := NewObject(AirConditionerGroup, HomeAirConditioner, 1)
+	f := NewFrame(0, NewObject(ControllerGroup, Controller, 1), aircon, Get, nil)
+	tid, _ := c.prepare(&f, "192.168.1.15")
+
+	// 同じTIDでも宛先以外からの応答は受け取らない
+	for _, addr := range []string{"192.168.1.16:3610", "192.168.1.15:3610"} {
+		res := NewFrame(tid, aircon, f.SEOJ, GetRes, nil)
+		c.dispatch(Message{Address: addr, Frame: res})
+	}
+	messages := c.release(tid)
+	if len(messages) != 1 || messages[0].Address != "192.168.1.15:3610" {
+		t.Errorf("only response from destination is expected: %+v", messages)
+	}
+}
+
+func TestParsePropertyMap(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name  string
+		input Data
+		want  []PropertyCode
+	}{
+		{
+			name:  "list",
+			input: Data{0x03, 0x80, 0x81, 0x9f},
+			want:  []PropertyCode{0x80, 0x81, 0x9f},
+		},
+		{
+			name:  "bitmap",
+			input: Data{0x10, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x03},
+			want: []PropertyCode{
+				0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+				0x9f,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePropertyMap(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseObjectList(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseObjectList(Data{0x02, 0x01, 0x30, 0x01, 0x02, 0x88, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Object{{0x01, 0x30, 0x01}, {0x02, 0x88, 0x01}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseObjectList(Data{0x02, 0x01, 0x30}); err == nil {
+		t.Errorf("error expected")
+	}
+}
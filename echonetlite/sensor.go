@@ -0,0 +1,129 @@
+package echonetlite
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SensorObject is object for sensor classes.
+// Value is converted to the unit of the class, and nil if it is not available.
+type SensorObject struct {
+	SuperObject
+	Object Object
+	Value  *float64
+}
+
+// sensorClass describes how to read a sensor class
+type sensorClass struct {
+	properties []PropertyCode
+	gauge      *gauge
+	decode     func(props []Property) *float64
+}
+
+// decodeMeasuredValue returns decoder for 0xE0 of t multiplied by scale
+func decodeMeasuredValue(t NumberType, scale float64) func(props []Property) *float64 {
+	return func(props []Property) *float64 {
+		p, ok := Frame{Properties: props}.Property(MeasuredValue)
+		if !ok {
+			return nil
+		}
+		return measured(t, p.Data, scale)
+	}
+}
+
+// decodeIlluminance uses lux (0xE0), or klux (0xE1) if lux is not available or overflows
+func decodeIlluminance(props []Property) *float64 {
+	f := Frame{Properties: props}
+	if p, ok := f.Property(MeasuredValue); ok {
+		if v := measured(UnsignedShort, p.Data, 1); v != nil {
+			return v
+		}
+	}
+	if p, ok := f.Property(MeasuredIlluminanceKlux); ok {
+		return measured(UnsignedShort, p.Data, 1000)
+	}
+	return nil
+}
+
+func newSensorGauge(name, help string) *gauge {
+	return newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "sensor",
+			Name:      name,
+			Help:      help,
+		},
+		[]string{"id", "instance", "location"},
+	)
+}
+
+var sensorClasses = map[ClassCode]sensorClass{
+	TemperatureSensor: {
+		properties: []PropertyCode{InstallationLocation, ID, MeasuredValue},
+		gauge:      newSensorGauge("temperature_celsius", "measured temperature [℃]"),
+		decode:     decodeMeasuredValue(SignedShort, 0.1),
+	},
+	HumiditySensor: {
+		properties: []PropertyCode{InstallationLocation, ID, MeasuredValue},
+		gauge:      newSensorGauge("humidity_percent", "measured relative humidity [%]"),
+		decode:     decodeMeasuredValue(UnsignedChar, 1),
+	},
+	CO2Sensor: {
+		properties: []PropertyCode{InstallationLocation, ID, MeasuredValue},
+		gauge:      newSensorGauge("co2_ppm", "measured CO2 concentration [ppm]"),
+		decode:     decodeMeasuredValue(UnsignedShort, 1),
+	},
+	IlluminanceSensor: {
+		properties: []PropertyCode{InstallationLocation, ID, MeasuredValue, MeasuredIlluminanceKlux},
+		gauge:      newSensorGauge("illuminance_lux", "measured illuminance [lx]"),
+		decode:     decodeIlluminance,
+	},
+}
+
+// isSensor returns true if obj is one of supported sensor classes
+func isSensor(obj Object) bool {
+	if obj.ClassGroup != SensorGroup {
+		return false
+	}
+	_, ok := sensorClasses[obj.Class]
+	return ok
+}
+
+func parseSensorProperties(obj Object, properties []Property) SensorObject {
+	o := SensorObject{Object: obj}
+	for _, p := range properties {
+		if PropertyCode(p.Code) == InstallationLocation {
+			if loc, ok := parseLocation(p); ok {
+				o.InstallLocation = loc
+			}
+		}
+	}
+	o.Value = sensorClasses[obj.Class].decode(properties)
+	return o
+}
+
+func collectSensorMetrics(w *metricWriter, base prometheus.Labels, o SensorObject) {
+	c, ok := sensorClasses[o.Object.Class]
+	if !ok {
+		return
+	}
+	labels := withLabel(withLabel(base, "instance", strconv.Itoa(o.Object.Num)), "location", o.InstallLocation.Label())
+	w.setGauge(c.gauge, labels, o.Value)
+}
+
+// RequestSensorStates sends request to each sensor object found in instance lists
+func (elc *ControllerNode) RequestSensorStates() {
+	for _, n := range elc.Nodes() {
+		for _, obj := range n.Devices {
+			if !isSensor(obj) {
+				continue
+			}
+			f := newGetFrame(elc.nextTID(), obj, sensorClasses[obj.Class].properties)
+			err := elc.send(n.Address, &f)
+			if err != nil {
+				clogger.Printf("[Error] failed to request sensor %s [%s]: %s", n.Address, obj, err)
+			}
+		}
+	}
+}
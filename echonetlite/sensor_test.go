@@ -0,0 +1,104 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseSensorProperties(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		obj   Object
+		props []Property
+		want  *float64
+	}{
+		{
+			name:  "temperature",
+			obj:   NewObject(SensorGroup, TemperatureSensor, 1),
+			props: []Property{{Code: 0xe0, Len: 2, Data: Data{0xff, 0x9c}}},
+			want:  float64Ptr(-10),
+		},
+		{
+			name:  "temperature overflow",
+			obj:   NewObject(SensorGroup, TemperatureSensor, 1),
+			props: []Property{{Code: 0xe0, Len: 2, Data: Data{0x7f, 0xff}}},
+			want:  nil,
+		},
+		{
+			name:  "humidity",
+			obj:   NewObject(SensorGroup, HumiditySensor, 1),
+			props: []Property{{Code: 0xe0, Len: 1, Data: Data{0x37}}},
+			want:  float64Ptr(55),
+		},
+		{
+			name:  "co2",
+			obj:   NewObject(SensorGroup, CO2Sensor, 1),
+			props: []Property{{Code: 0xe0, Len: 2, Data: Data{0x01, 0xf4}}},
+			want:  float64Ptr(500),
+		},
+		{
+			name: "illuminance klux fallback",
+			obj:  NewObject(SensorGroup, IlluminanceSensor, 1),
+			props: []Property{
+				{Code: 0xe0, Len: 2, Data: Data{0xff, 0xff}},
+				{Code: 0xe1, Len: 2, Data: Data{0x00, 0x64}},
+			},
+			want: float64Ptr(100000),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			props := append([]Property{{Code: 0x81, Len: 1, Data: Data{0x42}}}, tt.props...)
+			got, err := parseProperties(tt.obj, props)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := SensorObject{
+				SuperObject: SuperObject{InstallLocation: Location{Code: Room, Number: 2}},
+				Object:      tt.obj,
+				Value:       tt.want,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequestSensorStates(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		if req.DEOJ != NewObject(SensorGroup, CO2Sensor, 2) {
+			t.Errorf("unexpected destination: %s", req.DEOJ)
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, []Property{
+			{Code: 0x81, Len: 1, Data: Data{0x08}},
+			{Code: 0xe0, Len: 2, Data: Data{0x03, 0x20}},
+		})
+		return res.Serialize()
+	})
+	c.addNode("192.168.1.30", NewObject(AirConditionerGroup, HomeAirConditioner, 1), NewObject(SensorGroup, CO2Sensor, 2))
+
+	c.RequestSensorStates()
+
+	labels := prometheus.Labels{"id": "192.168.1.30", "instance": "2", "location": "Living"}
+	gauge := sensorClasses[CO2Sensor].gauge
+	for gaugeValue(t, c.Collector(), gauge, labels) != 800 {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("co2 gauge is not updated: %v", gaugeValue(t, c.Collector(), gauge, labels))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -98,3 +98,15 @@ func (t ESVType) isResponseOrNotification() bool {
 	}
 	return false
 }
+
+func (t ESVType) isSNA() bool {
+	switch t {
+	case SetISNA,
+		SetCSNA,
+		GetSNA,
+		InfSNA,
+		SetGetSNA:
+		return true
+	}
+	return false
+}
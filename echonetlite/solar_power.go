@@ -0,0 +1,213 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OutputRestraint is output power restraint status of solar power generation (0xD1)
+type OutputRestraint byte
+
+// definition of output power restraint status
+const (
+	RestraintOutputControl OutputRestraint = 0x41 // 抑制中(出力制御)
+	RestraintOther         OutputRestraint = 0x42 // 抑制中(出力制御以外)
+	RestraintUnknownReason OutputRestraint = 0x43 // 抑制中(抑制要因不明)
+	RestraintNone          OutputRestraint = 0x44 // 抑制未実施
+	RestraintUnknown       OutputRestraint = 0x45 // 不明
+)
+
+var outputRestraintNames = map[OutputRestraint]string{
+	RestraintOutputControl: "output_control",
+	RestraintOther:         "other",
+	RestraintUnknownReason: "unknown_reason",
+	RestraintNone:          "none",
+	RestraintUnknown:       "unknown",
+}
+
+func (r OutputRestraint) String() string {
+	if s, ok := outputRestraintNames[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(r))
+}
+
+// Restrained returns true if output is restrained
+func (r OutputRestraint) Restrained() bool {
+	return r == RestraintOutputControl || r == RestraintOther || r == RestraintUnknownReason
+}
+
+// Interconnection is system-interconnected type of solar power generation (0xD0)
+type Interconnection byte
+
+// definition of system-interconnected types
+const (
+	InterconnectionReverseFlow   Interconnection = 0x00 // 系統連系(逆潮流可)
+	InterconnectionIndependent   Interconnection = 0x01 // 独立
+	InterconnectionNoReverseFlow Interconnection = 0x02 // 系統連系(逆潮流不可)
+)
+
+var interconnectionNames = map[Interconnection]string{
+	InterconnectionReverseFlow:   "reverse_power_flow",
+	InterconnectionIndependent:   "independent",
+	InterconnectionNoReverseFlow: "no_reverse_power_flow",
+}
+
+func (i Interconnection) String() string {
+	if s, ok := interconnectionNames[i]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(i))
+}
+
+// SolarPowerObject is object for household solar power generation.
+// Status values are nil if the device doesn't provide them,
+// and generated power and energies are nil if they are not available or out of range.
+type SolarPowerObject struct {
+	Instance        int
+	Interconnection *Interconnection // 系統連系状態
+	OutputRestraint *OutputRestraint // 出力抑制状態
+	InstantPower    *float64         // 瞬時発電電力計測値 [W]
+	GeneratedEnergy *float64         // 積算発電電力量計測値 [kWh]
+	SoldEnergy      *float64         // 積算売電電力量計測値 [kWh]
+}
+
+// solarPowerProperties is properties requested to solar power generation
+var solarPowerProperties = []PropertyCode{
+	ID,
+	SolarInterconnectionType,
+	SolarOutputRestraintStatus,
+	SolarInstantGeneration,
+	SolarCumulativeGeneration,
+	SolarCumulativeSold,
+}
+
+func parseSolarPowerProperty(p Property, obj *SolarPowerObject) bool {
+	switch PropertyCode(p.Code) {
+	case SolarInterconnectionType:
+		if len(p.Data) == 1 {
+			i := Interconnection(p.Data[0])
+			obj.Interconnection = &i
+		}
+		return true
+	case SolarOutputRestraintStatus:
+		if len(p.Data) == 1 {
+			r := OutputRestraint(p.Data[0])
+			obj.OutputRestraint = &r
+		}
+		return true
+	case SolarInstantGeneration:
+		obj.InstantPower = measured(UnsignedShort, p.Data, 1)
+		return true
+	case SolarCumulativeGeneration:
+		obj.GeneratedEnergy = measured(UnsignedLong, p.Data, 0.001)
+		return true
+	case SolarCumulativeSold:
+		obj.SoldEnergy = measured(UnsignedLong, p.Data, 0.001)
+		return true
+	}
+	return false
+}
+
+// RequestSolarPowerState sends request to each household solar power generation found in instance lists
+func (elc *ControllerNode) RequestSolarPowerState() {
+	elc.requestClass(HomeEquipmentGroup, HomeSolarPower, solarPowerProperties)
+}
+
+var (
+	solarLabels = []string{"id", "instance"}
+
+	solarPower = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "solar",
+			Name:      "power_watts",
+			Help:      "instantaneous generated electric power [W]",
+		},
+		solarLabels,
+	)
+	solarGenerated = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "solar",
+			Name:      "generated_energy_kwh",
+			Help:      "cumulative generated electric energy [kWh]",
+		},
+		solarLabels,
+	)
+	solarSold = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "solar",
+			Name:      "sold_energy_kwh",
+			Help:      "cumulative sold electric energy [kWh]",
+		},
+		solarLabels,
+	)
+	solarOutputRestraint = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "solar",
+			Name:      "output_restraint_state",
+			Help:      "1 for the current output power restraint status",
+		},
+		append(solarLabels, "state"),
+	)
+	solarInterconnection = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "solar",
+			Name:      "interconnection",
+			Help:      "1 for the current system-interconnected type",
+		},
+		append(solarLabels, "type"),
+	)
+)
+
+func collectSolarPowerMetrics(w *metricWriter, base prometheus.Labels, o SolarPowerObject) {
+	labels := withLabel(base, "instance", strconv.Itoa(o.Instance))
+	w.setGauge(solarPower, labels, o.InstantPower)
+	w.setGauge(solarGenerated, labels, o.GeneratedEnergy)
+	w.setGauge(solarSold, labels, o.SoldEnergy)
+
+	if o.OutputRestraint != nil {
+		states := []string{}
+		for _, name := range outputRestraintNames {
+			states = append(states, name)
+		}
+		w.setStateSet(solarOutputRestraint, labels, "state", states, o.OutputRestraint.String())
+	}
+	if o.Interconnection != nil {
+		types := []string{}
+		for _, name := range interconnectionNames {
+			types = append(types, name)
+		}
+		w.setStateSet(solarInterconnection, labels, "type", types, o.Interconnection.String())
+	}
+}
+
+// SolarPowerClient is client to read household solar power generation (0x0279)
+type SolarPowerClient struct {
+	*Device
+}
+
+// NewSolarPowerClient returns SolarPowerClient for the instance at addr
+func NewSolarPowerClient(elc *ControllerNode, addr string, instance int) *SolarPowerClient {
+	return &SolarPowerClient{NewDevice(elc, addr, NewObject(HomeEquipmentGroup, HomeSolarPower, instance))}
+}
+
+// State reads current state of the solar power generation
+func (s *SolarPowerClient) State(ctx context.Context) (SolarPowerObject, error) {
+	props, err := s.Get(ctx, solarPowerProperties...)
+	if err != nil {
+		return SolarPowerObject{}, err
+	}
+	obj := SolarPowerObject{Instance: s.Object.Num}
+	for _, p := range props {
+		parseSolarPowerProperty(p, &obj)
+	}
+	return obj, nil
+}
@@ -0,0 +1,55 @@
+package echonetlite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSolarPower(t *testing.T) {
+	props := []Property{
+		{Code: 0xd0, Len: 1, Data: Data{0x00}},
+		{Code: 0xd1, Len: 1, Data: Data{0x41}},
+		{Code: 0xe0, Len: 2, Data: Data{0x0b, 0xb8}},
+		{Code: 0xe1, Len: 4, Data: Data{0x00, 0x12, 0xd6, 0x87}},
+		{Code: 0xe3, Len: 0, Data: Data{}},
+	}
+
+	got, err := parseProperties(NewObject(HomeEquipmentGroup, HomeSolarPower, 1), props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interconnection := InterconnectionReverseFlow
+	restraint := RestraintOutputControl
+	want := SolarPowerObject{
+		Instance:        1,
+		Interconnection: &interconnection,
+		OutputRestraint: &restraint,
+		InstantPower:    float64Ptr(3000),
+		GeneratedEnergy: float64Ptr(1234.567),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	c := collectFunc(func(w *metricWriter) {
+		collectSolarPowerMetrics(w, prometheus.Labels{"id": "192.168.1.30"}, got.(SolarPowerObject))
+	})
+
+	labels := prometheus.Labels{"id": "192.168.1.30", "instance": "1"}
+	if v := gaugeValue(t, c, solarPower, labels); v != 3000 {
+		t.Errorf("power differs: want:3000 got:%v", v)
+	}
+	if hasGauge(t, c, solarSold, labels) {
+		t.Errorf("gauge for unavailable property should not exist")
+	}
+	state := prometheus.Labels{"id": "192.168.1.30", "instance": "1", "state": "output_control"}
+	if v := gaugeValue(t, c, solarOutputRestraint, state); v != 1 {
+		t.Errorf("output restraint differs: want:1 got:%v", v)
+	}
+	typ := prometheus.Labels{"id": "192.168.1.30", "instance": "1", "type": "independent"}
+	if v := gaugeValue(t, c, solarInterconnection, typ); v != 0 {
+		t.Errorf("interconnection differs: want:0 got:%v", v)
+	}
+}
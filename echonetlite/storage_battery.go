@@ -0,0 +1,225 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StorageBatteryMode is operation mode of storage battery (0xDA, 0xCF)
+type StorageBatteryMode byte
+
+// definition of storage battery operation modes
+const (
+	BatteryModeOther         StorageBatteryMode = 0x40
+	BatteryModeRapidCharging StorageBatteryMode = 0x41
+	BatteryModeCharging      StorageBatteryMode = 0x42
+	BatteryModeDischarging   StorageBatteryMode = 0x43
+	BatteryModeStandby       StorageBatteryMode = 0x44
+	BatteryModeTest          StorageBatteryMode = 0x45
+	BatteryModeAuto          StorageBatteryMode = 0x46
+	BatteryModeRestart       StorageBatteryMode = 0x48
+	BatteryModeRecalculation StorageBatteryMode = 0x49
+)
+
+var storageBatteryModeNames = map[StorageBatteryMode]string{
+	BatteryModeOther:         "other",
+	BatteryModeRapidCharging: "rapid_charging",
+	BatteryModeCharging:      "charging",
+	BatteryModeDischarging:   "discharging",
+	BatteryModeStandby:       "standby",
+	BatteryModeTest:          "test",
+	BatteryModeAuto:          "auto",
+	BatteryModeRestart:       "restart",
+	BatteryModeRecalculation: "recalculation",
+}
+
+func (m StorageBatteryMode) String() string {
+	if s, ok := storageBatteryModeNames[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(m))
+}
+
+// ParseStorageBatteryMode returns mode from its name
+func ParseStorageBatteryMode(s string) (StorageBatteryMode, error) {
+	for m, name := range storageBatteryModeNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode: %s", s)
+}
+
+// StorageBatteryObject is object for storage battery.
+// Modes are zero if the battery doesn't provide them,
+// and power, remaining capacities and AC energies are nil if they are not available or out of range.
+type StorageBatteryObject struct {
+	Instance                 int
+	Mode                     StorageBatteryMode // 運転モード設定
+	WorkingOperationStatus   StorageBatteryMode // 運転動作状態
+	InstantPower             *float64           // 瞬時充放電電力計測値 [W] (充電:+ 放電:-)
+	RemainingCapacity        *float64           // 蓄電残量1 [Wh]
+	RemainingCapacityPercent *float64           // 蓄電残量3 [%]
+	ACChargedEnergy          *float64           // AC積算充電電力量計測値 [kWh]
+	ACDischargedEnergy       *float64           // AC積算放電電力量計測値 [kWh]
+}
+
+// storageBatteryProperties is properties requested to storage battery
+var storageBatteryProperties = []PropertyCode{
+	ID,
+	BatteryOperationMode,
+	BatteryWorkingOperationStatus,
+	BatteryInstantPower,
+	BatteryRemainingCapacity1,
+	BatteryRemainingCapacity3,
+	BatteryACChargedEnergy,
+	BatteryACDischargedEnergy,
+}
+
+func parseStorageBatteryProperty(p Property, obj *StorageBatteryObject) bool {
+	switch PropertyCode(p.Code) {
+	case BatteryOperationMode:
+		if len(p.Data) == 1 {
+			obj.Mode = StorageBatteryMode(p.Data[0])
+		}
+		return true
+	case BatteryWorkingOperationStatus:
+		if len(p.Data) == 1 {
+			obj.WorkingOperationStatus = StorageBatteryMode(p.Data[0])
+		}
+		return true
+	case BatteryInstantPower:
+		obj.InstantPower = measured(SignedLong, p.Data, 1)
+		return true
+	case BatteryRemainingCapacity1:
+		obj.RemainingCapacity = measured(UnsignedLong, p.Data, 1)
+		return true
+	case BatteryRemainingCapacity3:
+		obj.RemainingCapacityPercent = measured(UnsignedChar, p.Data, 1)
+		return true
+	case BatteryACChargedEnergy:
+		obj.ACChargedEnergy = measured(UnsignedLong, p.Data, 0.001)
+		return true
+	case BatteryACDischargedEnergy:
+		obj.ACDischargedEnergy = measured(UnsignedLong, p.Data, 0.001)
+		return true
+	}
+	return false
+}
+
+// RequestStorageBatteryState sends request to each storage battery found in instance lists
+func (elc *ControllerNode) RequestStorageBatteryState() {
+	elc.requestClass(HomeEquipmentGroup, StorageBattery, storageBatteryProperties)
+}
+
+var (
+	batteryLabels = []string{"id", "instance"}
+
+	batteryRemainingCapacity = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "storage_battery",
+			Name:      "remaining_capacity_wh",
+			Help:      "remaining stored electricity [Wh]",
+		},
+		batteryLabels,
+	)
+	batteryRemainingCapacityPercent = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "storage_battery",
+			Name:      "remaining_capacity_percent",
+			Help:      "remaining stored electricity [%]",
+		},
+		batteryLabels,
+	)
+	batteryPower = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "storage_battery",
+			Name:      "power_watts",
+			Help:      "instantaneous charging (+) / discharging (-) electric power [W]",
+		},
+		batteryLabels,
+	)
+	batteryACCharged = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "storage_battery",
+			Name:      "ac_charged_energy_kwh",
+			Help:      "cumulative AC charging electric energy [kWh]",
+		},
+		batteryLabels,
+	)
+	batteryACDischarged = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "storage_battery",
+			Name:      "ac_discharged_energy_kwh",
+			Help:      "cumulative AC discharging electric energy [kWh]",
+		},
+		batteryLabels,
+	)
+	batteryOperationState = newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "storage_battery",
+			Name:      "working_operation_state",
+			Help:      "1 for the current working operation state",
+		},
+		append(batteryLabels, "state"),
+	)
+)
+
+func collectStorageBatteryMetrics(w *metricWriter, base prometheus.Labels, o StorageBatteryObject) {
+	labels := withLabel(base, "instance", strconv.Itoa(o.Instance))
+	w.setGauge(batteryRemainingCapacity, labels, o.RemainingCapacity)
+	w.setGauge(batteryRemainingCapacityPercent, labels, o.RemainingCapacityPercent)
+	w.setGauge(batteryPower, labels, o.InstantPower)
+	w.setGauge(batteryACCharged, labels, o.ACChargedEnergy)
+	w.setGauge(batteryACDischarged, labels, o.ACDischargedEnergy)
+
+	if o.WorkingOperationStatus != 0 {
+		states := []string{}
+		for _, name := range storageBatteryModeNames {
+			states = append(states, name)
+		}
+		w.setStateSet(batteryOperationState, labels, "state", states, o.WorkingOperationStatus.String())
+	}
+}
+
+// StorageBatteryClient is client to read and control storage battery (0x027D)
+type StorageBatteryClient struct {
+	*Device
+}
+
+// NewStorageBatteryClient returns StorageBatteryClient for the instance at addr
+func NewStorageBatteryClient(elc *ControllerNode, addr string, instance int) *StorageBatteryClient {
+	return &StorageBatteryClient{NewDevice(elc, addr, NewObject(HomeEquipmentGroup, StorageBattery, instance))}
+}
+
+// State reads current state of the storage battery
+func (b *StorageBatteryClient) State(ctx context.Context) (StorageBatteryObject, error) {
+	props, err := b.Get(ctx, storageBatteryProperties...)
+	if err != nil {
+		return StorageBatteryObject{}, err
+	}
+	obj := StorageBatteryObject{Instance: b.Object.Num}
+	for _, p := range props {
+		parseStorageBatteryProperty(p, &obj)
+	}
+	return obj, nil
+}
+
+// SetMode changes operation mode to charging, discharging, standby or auto
+func (b *StorageBatteryClient) SetMode(ctx context.Context, mode StorageBatteryMode) error {
+	switch mode {
+	case BatteryModeRapidCharging, BatteryModeCharging, BatteryModeDischarging, BatteryModeStandby, BatteryModeAuto:
+	default:
+		return fmt.Errorf("mode %s can not be set", mode)
+	}
+	return b.Set(ctx, byteProperty(BatteryOperationMode, byte(mode)))
+}
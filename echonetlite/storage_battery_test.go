@@ -0,0 +1,145 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storageBatteryValues = []Property{
+	{Code: 0xda, Len: 1, Data: Data{0x46}},
+	{Code: 0xcf, Len: 1, Data: Data{0x43}},
+	{Code: 0xd3, Len: 4, Data: Data{0xff, 0xff, 0xfc, 0x18}},
+	{Code: 0xe2, Len: 4, Data: Data{0x00, 0x00, 0x13, 0x88}},
+	{Code: 0xe4, Len: 1, Data: Data{0x32}},
+	{Code: 0xa8, Len: 4, Data: Data{0x00, 0x01, 0xe2, 0x40}},
+	{Code: 0xa9, Len: 0, Data: Data{}},
+}
+
+func TestParseStorageBatteryProperties(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseProperties(NewObject(HomeEquipmentGroup, StorageBattery, 1), storageBatteryValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StorageBatteryObject{
+		Instance:                 1,
+		Mode:                     BatteryModeAuto,
+		WorkingOperationStatus:   BatteryModeDischarging,
+		InstantPower:             float64Ptr(-1000),
+		RemainingCapacity:        float64Ptr(5000),
+		RemainingCapacityPercent: float64Ptr(50),
+		ACChargedEnergy:          float64Ptr(123.456),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestCollectStorageBatteryMetrics(t *testing.T) {
+	obj, _ := parseProperties(NewObject(HomeEquipmentGroup, StorageBattery, 1), storageBatteryValues)
+	c := collectFunc(func(w *metricWriter) {
+		collectStorageBatteryMetrics(w, prometheus.Labels{"id": "192.168.1.20"}, obj.(StorageBatteryObject))
+	})
+
+	labels := prometheus.Labels{"id": "192.168.1.20", "instance": "1"}
+	if got := gaugeValue(t, c, batteryPower, labels); got != -1000 {
+		t.Errorf("power differs: want:-1000 got:%v", got)
+	}
+	if got := gaugeValue(t, c, batteryRemainingCapacityPercent, labels); got != 50 {
+		t.Errorf("remaining capacity differs: want:50 got:%v", got)
+	}
+	if hasGauge(t, c, batteryACDischarged, labels) {
+		t.Errorf("gauge for unavailable property should not exist")
+	}
+	state := prometheus.Labels{"id": "192.168.1.20", "instance": "1", "state": "discharging"}
+	if got := gaugeValue(t, c, batteryOperationState, state); got != 1 {
+		t.Errorf("working operation state differs: want:1 got:%v", got)
+	}
+	state["state"] = "charging"
+	if got := gaugeValue(t, c, batteryOperationState, state); got != 0 {
+		t.Errorf("working operation state differs: want:0 got:%v", got)
+	}
+}
+
+func TestStorageBatteryClient(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var mode byte
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		esv := GetRes
+		switch req.ESV {
+		case Get:
+			if _, ok := req.Property(SetPropertyMap); ok {
+				props = append(props, Property{Code: 0x9e, Len: 2, Data: Data{0x01, 0xda}})
+				break
+			}
+			esv = GetSNA
+			props = storageBatteryValues
+		case SetC:
+			esv = SetRes
+			mode = req.Properties[0].Data[0]
+			props = append(props, Property{Code: 0xda, Len: 0, Data: Data{}})
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, esv, props)
+		return res.Serialize()
+	})
+	b := NewStorageBatteryClient(c, "192.168.1.20", 1)
+
+	state, err := b.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.RemainingCapacity == nil || *state.RemainingCapacity != 5000 {
+		t.Errorf("remaining capacity differs: %v", state.RemainingCapacity)
+	}
+
+	if err := b.SetMode(ctx, BatteryModeCharging); err != nil {
+		t.Fatal(err)
+	}
+	if mode != byte(BatteryModeCharging) {
+		t.Errorf("mode differs: want:%#x got:%#x", BatteryModeCharging, mode)
+	}
+	if err := b.SetMode(ctx, BatteryModeTest); err == nil {
+		t.Errorf("error expected for test mode")
+	}
+}
+
+func TestRequestStorageBatteryState(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		if req.DEOJ.ClassGroup != HomeEquipmentGroup || req.DEOJ.Class != StorageBattery {
+			t.Errorf("unexpected destination: %s", req.DEOJ)
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, GetRes, storageBatteryValues)
+		return res.Serialize()
+	})
+	c.addNode("192.168.1.20", NewObject(AirConditionerGroup, HomeAirConditioner, 1),
+		NewObject(HomeEquipmentGroup, StorageBattery, 1), NewObject(HomeEquipmentGroup, StorageBattery, 2))
+
+	c.RequestStorageBatteryState()
+
+	// 2つのインスタンスそれぞれに要求する
+	for _, instance := range []string{"1", "2"} {
+		labels := prometheus.Labels{"id": "192.168.1.20", "instance": instance}
+		for gaugeValue(t, c.Collector(), batteryPower, labels) != -1000 {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("power gauge of instance %s is not updated", instance)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+}
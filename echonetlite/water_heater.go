@@ -0,0 +1,226 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WaterHeatingMode is automatic water heating setting of electric water heater (0xB0)
+type WaterHeatingMode byte
+
+// definition of automatic water heating settings
+const (
+	WaterHeatingAuto        WaterHeatingMode = 0x41 // 自動沸き上げ
+	WaterHeatingManualStart WaterHeatingMode = 0x42 // 手動沸き上げ
+	WaterHeatingManualStop  WaterHeatingMode = 0x43 // 手動沸き上げ停止
+)
+
+func (s WaterHeatingMode) String() string {
+	switch s {
+	case WaterHeatingAuto:
+		return "auto"
+	case WaterHeatingManualStart:
+		return "manual"
+	case WaterHeatingManualStop:
+		return "stop"
+	}
+	return fmt.Sprintf("unknown(%#x)", byte(s))
+}
+
+// WaterHeaterObject is object for electric water heater.
+// HeatingMode is zero if the heater doesn't provide it, flags are nil unless they are 0x41 or 0x42,
+// and temperatures and amounts of water are nil if they are not available or out of range.
+type WaterHeaterObject struct {
+	Instance                  int
+	HeatingMode               WaterHeatingMode // 沸き上げ自動設定
+	Heating                   *bool            // 沸き上げ中状態
+	HeatingTemperature        *float64         // 沸き上げ湯温設定値 [℃]
+	DaytimeReheatingPermitted *bool            // 昼間沸き増し許可設定
+	HotWaterSupplyTemperature *float64         // 給湯温度設定値 [℃]
+	BathTemperature           *float64         // 風呂温度設定値 [℃]
+	RemainingHotWater         *float64         // 残湯量計測値 [L]
+	TankCapacity              *float64         // タンク容量値 [L]
+	BathAuto                  *bool            // 風呂自動モード設定
+}
+
+// waterHeaterProperties is properties requested to electric water heater
+var waterHeaterProperties = []PropertyCode{
+	ID,
+	WaterHeatingSetting,
+	WaterHeatingStatus,
+	WaterHeatingTemperature,
+	DaytimeReheatingPermission,
+	HotWaterSupplyTemperature,
+	BathWaterTemperature,
+	RemainingHotWater,
+	TankCapacity,
+	BathAutoMode,
+}
+
+// flagValue decodes 0x41/0x42 property as true/false
+func flagValue(d []byte) *bool {
+	if len(d) != 1 || (d[0] != 0x41 && d[0] != 0x42) {
+		return nil
+	}
+	b := d[0] == 0x41
+	return &b
+}
+
+func flagProperty(code PropertyCode, on bool) Property {
+	if on {
+		return byteProperty(code, 0x41)
+	}
+	return byteProperty(code, 0x42)
+}
+
+func parseWaterHeaterProperty(p Property, obj *WaterHeaterObject) bool {
+	switch PropertyCode(p.Code) {
+	case WaterHeatingSetting:
+		if len(p.Data) == 1 {
+			obj.HeatingMode = WaterHeatingMode(p.Data[0])
+		}
+		return true
+	case WaterHeatingStatus:
+		obj.Heating = flagValue(p.Data)
+		return true
+	case WaterHeatingTemperature:
+		obj.HeatingTemperature = measured(UnsignedChar, p.Data, 1)
+		return true
+	case DaytimeReheatingPermission:
+		obj.DaytimeReheatingPermitted = flagValue(p.Data)
+		return true
+	case HotWaterSupplyTemperature:
+		obj.HotWaterSupplyTemperature = measured(UnsignedChar, p.Data, 1)
+		return true
+	case BathWaterTemperature:
+		obj.BathTemperature = measured(UnsignedChar, p.Data, 1)
+		return true
+	case RemainingHotWater:
+		obj.RemainingHotWater = measured(UnsignedShort, p.Data, 1)
+		return true
+	case TankCapacity:
+		obj.TankCapacity = measured(UnsignedShort, p.Data, 1)
+		return true
+	case BathAutoMode:
+		obj.BathAuto = flagValue(p.Data)
+		return true
+	}
+	return false
+}
+
+// RequestWaterHeaterState sends request to each electric water heater found in instance lists
+func (elc *ControllerNode) RequestWaterHeaterState() {
+	elc.requestClass(HomeEquipmentGroup, ElectricWaterHeater, waterHeaterProperties)
+}
+
+func newWaterHeaterGauge(name, help string) *gauge {
+	return newDeviceGauge(
+		prometheus.GaugeOpts{
+			Namespace: "home",
+			Subsystem: "water_heater",
+			Name:      name,
+			Help:      help,
+		},
+		[]string{"id", "instance"},
+	)
+}
+
+var (
+	waterHeaterRemaining          = newWaterHeaterGauge("remaining_hot_water_liters", "measured amount of remaining hot water [L]")
+	waterHeaterCapacity           = newWaterHeaterGauge("tank_capacity_liters", "tank capacity [L]")
+	waterHeaterHeating            = newWaterHeaterGauge("heating", "1 if water is being heated")
+	waterHeaterHeatingTemperature = newWaterHeaterGauge("heating_temperature_celsius", "water heating temperature setting [℃]")
+	waterHeaterSupplyTemperature  = newWaterHeaterGauge("supply_temperature_celsius", "hot water supply temperature setting [℃]")
+	waterHeaterBathTemperature    = newWaterHeaterGauge("bath_temperature_celsius", "bath water temperature setting [℃]")
+	waterHeaterDaytimeReheating   = newWaterHeaterGauge("daytime_reheating_permitted", "1 if daytime reheating is permitted")
+	waterHeaterBathAuto           = newWaterHeaterGauge("bath_auto", "1 if bath auto mode is on")
+)
+
+func collectWaterHeaterMetrics(w *metricWriter, base prometheus.Labels, o WaterHeaterObject) {
+	labels := withLabel(base, "instance", strconv.Itoa(o.Instance))
+	w.setGauge(waterHeaterRemaining, labels, o.RemainingHotWater)
+	w.setGauge(waterHeaterCapacity, labels, o.TankCapacity)
+	w.setGauge(waterHeaterHeating, labels, boolValue(o.Heating))
+	w.setGauge(waterHeaterHeatingTemperature, labels, o.HeatingTemperature)
+	w.setGauge(waterHeaterSupplyTemperature, labels, o.HotWaterSupplyTemperature)
+	w.setGauge(waterHeaterBathTemperature, labels, o.BathTemperature)
+	w.setGauge(waterHeaterDaytimeReheating, labels, boolValue(o.DaytimeReheatingPermitted))
+	w.setGauge(waterHeaterBathAuto, labels, boolValue(o.BathAuto))
+}
+
+// WaterHeaterClient is client to read and control electric water heater (0x026B)
+type WaterHeaterClient struct {
+	*Device
+}
+
+// NewWaterHeaterClient returns WaterHeaterClient for the instance at addr
+func NewWaterHeaterClient(elc *ControllerNode, addr string, instance int) *WaterHeaterClient {
+	return &WaterHeaterClient{NewDevice(elc, addr, NewObject(HomeEquipmentGroup, ElectricWaterHeater, instance))}
+}
+
+// State reads current state of the water heater
+func (w *WaterHeaterClient) State(ctx context.Context) (WaterHeaterObject, error) {
+	props, err := w.Get(ctx, waterHeaterProperties...)
+	if err != nil {
+		return WaterHeaterObject{}, err
+	}
+	obj := WaterHeaterObject{Instance: w.Object.Num}
+	for _, p := range props {
+		parseWaterHeaterProperty(p, &obj)
+	}
+	return obj, nil
+}
+
+// SetHeatingMode changes automatic water heating setting
+func (w *WaterHeaterClient) SetHeatingMode(ctx context.Context, s WaterHeatingMode) error {
+	if s < WaterHeatingAuto || s > WaterHeatingManualStop {
+		return fmt.Errorf("invalid water heating setting: %s", s)
+	}
+	return w.Set(ctx, byteProperty(WaterHeatingSetting, byte(s)))
+}
+
+// BoilNow starts manual water heating
+func (w *WaterHeaterClient) BoilNow(ctx context.Context) error {
+	return w.SetHeatingMode(ctx, WaterHeatingManualStart)
+}
+
+// StopBoiling stops manual water heating
+func (w *WaterHeaterClient) StopBoiling(ctx context.Context) error {
+	return w.SetHeatingMode(ctx, WaterHeatingManualStop)
+}
+
+// setTemperature writes temperature property after range check
+func (w *WaterHeaterClient) setTemperature(ctx context.Context, code PropertyCode, temp, min, max int) error {
+	if temp < min || temp > max {
+		return fmt.Errorf("temperature out of range [%d-%d]: %d", min, max, temp)
+	}
+	return w.Set(ctx, byteProperty(code, byte(temp)))
+}
+
+// SetHeatingTemperature changes water heating temperature (0-100℃)
+func (w *WaterHeaterClient) SetHeatingTemperature(ctx context.Context, temp int) error {
+	return w.setTemperature(ctx, WaterHeatingTemperature, temp, 0, 100)
+}
+
+// SetHotWaterSupplyTemperature changes hot water supply temperature (0-100℃)
+func (w *WaterHeaterClient) SetHotWaterSupplyTemperature(ctx context.Context, temp int) error {
+	return w.setTemperature(ctx, HotWaterSupplyTemperature, temp, 0, 100)
+}
+
+// SetBathTemperature changes bath water temperature (0-100℃)
+func (w *WaterHeaterClient) SetBathTemperature(ctx context.Context, temp int) error {
+	return w.setTemperature(ctx, BathWaterTemperature, temp, 0, 100)
+}
+
+// SetDaytimeReheating permits/prohibits daytime reheating
+func (w *WaterHeaterClient) SetDaytimeReheating(ctx context.Context, permitted bool) error {
+	return w.Set(ctx, flagProperty(DaytimeReheatingPermission, permitted))
+}
+
+// SetBathAuto turns on/off bath auto mode
+func (w *WaterHeaterClient) SetBathAuto(ctx context.Context, on bool) error {
+	return w.Set(ctx, flagProperty(BathAutoMode, on))
+}
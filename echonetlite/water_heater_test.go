@@ -0,0 +1,110 @@
+package echonetlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+var waterHeaterValues = []Property{
+	{Code: 0xb0, Len: 1, Data: Data{0x41}},
+	{Code: 0xb2, Len: 1, Data: Data{0x42}},
+	{Code: 0xb3, Len: 1, Data: Data{0x41}},
+	{Code: 0xc0, Len: 1, Data: Data{0x41}},
+	{Code: 0xd1, Len: 1, Data: Data{0x2a}},
+	{Code: 0xd3, Len: 1, Data: Data{0x28}},
+	{Code: 0xe1, Len: 2, Data: Data{0x01, 0x2c}},
+	{Code: 0xe2, Len: 2, Data: Data{0x01, 0xc2}},
+	{Code: 0xe3, Len: 1, Data: Data{0x42}},
+}
+
+func TestParseWaterHeaterProperties(t *testing.T) {
+	got, err := parseProperties(NewObject(HomeEquipmentGroup, ElectricWaterHeater, 1), waterHeaterValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WaterHeaterObject{
+		Instance:                  1,
+		HeatingMode:               WaterHeatingAuto,
+		Heating:                   boolPtr(false),
+		HeatingTemperature:        float64Ptr(65),
+		DaytimeReheatingPermitted: boolPtr(true),
+		HotWaterSupplyTemperature: float64Ptr(42),
+		BathTemperature:           float64Ptr(40),
+		RemainingHotWater:         float64Ptr(300),
+		TankCapacity:              float64Ptr(450),
+		BathAuto:                  boolPtr(false),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	c := collectFunc(func(w *metricWriter) {
+		collectWaterHeaterMetrics(w, prometheus.Labels{"id": "192.168.1.40"}, got.(WaterHeaterObject))
+	})
+	labels := prometheus.Labels{"id": "192.168.1.40", "instance": "1"}
+	if v := gaugeValue(t, c, waterHeaterRemaining, labels); v != 300 {
+		t.Errorf("remaining hot water differs: want:300 got:%v", v)
+	}
+	if v := gaugeValue(t, c, waterHeaterDaytimeReheating, labels); v != 1 {
+		t.Errorf("daytime reheating differs: want:1 got:%v", v)
+	}
+}
+
+func TestWaterHeaterClient_Set(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	sent := []Property{}
+	c := newTestController(t, ctx, func(req Frame) []byte {
+		props := []Property{}
+		esv := GetRes
+		switch req.ESV {
+		case Get:
+			props = append(props, Property{Code: 0x9e, Len: 4, Data: Data{0x03, 0xb0, 0xb3, 0xc0}})
+		case SetC:
+			esv = SetRes
+			sent = append(sent, req.Properties...)
+			for _, p := range req.Properties {
+				props = append(props, Property{Code: p.Code, Len: 0, Data: Data{}})
+			}
+		}
+		res := NewFrame(req.TransactionID(), req.DEOJ, req.SEOJ, esv, props)
+		return res.Serialize()
+	})
+	w := NewWaterHeaterClient(c, "192.168.1.40", 1)
+
+	if err := w.BoilNow(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetHeatingTemperature(ctx, 70); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetDaytimeReheating(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	want := []Property{
+		{Code: 0xb0, Len: 1, Data: Data{0x42}},
+		{Code: 0xb3, Len: 1, Data: Data{0x46}},
+		{Code: 0xc0, Len: 1, Data: Data{0x42}},
+	}
+	if diff := cmp.Diff(want, sent); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if _, ok := w.SetBathAuto(ctx, true).(*NotSettableError); !ok {
+		t.Errorf("NotSettableError expected")
+	}
+	if err := w.SetBathTemperature(ctx, 101); err == nil {
+		t.Errorf("error expected for out of range temperature")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/matsuu/go-el-controller/echonetlite"
+)
+
+var address = flag.String("address", "192.168.1.10", "IP address of air conditioner")
+var temperature = flag.Int("temperature", 26, "temperature to precool")
+
+func main() {
+	flag.Parse()
+	err := run()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func run() error {
+	elc, err := echonetlite.NewControllerNode()
+	if err != nil {
+		return err
+	}
+	defer elc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	elc.Listen(ctx)
+
+	aircon := echonetlite.NewAirConditioner(elc, *address, 1)
+
+	temp, err := echonetlite.TemperatureProperty(*temperature)
+	if err != nil {
+		return err
+	}
+	mode, err := echonetlite.ModeProperty(echonetlite.ModeCool)
+	if err != nil {
+		return err
+	}
+	// precool: mode, temperature and power at once
+	err = aircon.Set(ctx, mode, temp, echonetlite.PowerProperty(true))
+	if serr, ok := err.(*echonetlite.ServiceError); ok {
+		return fmt.Errorf("rejected by air conditioner: %v", serr.Rejected())
+	}
+	if err != nil {
+		return err
+	}
+
+	state, err := aircon.State(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%+v\n", state)
+	return nil
+}
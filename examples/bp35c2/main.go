@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/u-one/go-el-controller/echonetlite"
-	"github.com/u-one/go-el-controller/wisun"
+	"github.com/matsuu/go-el-controller/echonetlite"
+	"github.com/matsuu/go-el-controller/wisun"
 )
 
 var bRouteID = flag.String("brouteid", "0123456789AB", "B-route ID")
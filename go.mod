@@ -7,6 +7,7 @@ require (
 	github.com/golang/mock v1.5.0
 	github.com/google/go-cmp v0.5.4
 	github.com/prometheus/client_golang v1.9.0
-	github.com/u-one/go-el-controller v0.9.1-0.20210329184039-500bfa96aa6d
+	github.com/prometheus/client_model v0.2.0
 	golang.org/x/sys v0.0.0-20210309074719-68d13333faf2
+	gopkg.in/yaml.v2 v2.4.0
 )
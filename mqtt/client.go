@@ -0,0 +1,384 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultKeepAlive is keep alive interval if Options.KeepAlive is zero
+	DefaultKeepAlive = 60 * time.Second
+	// defaultConnectTimeout is timeout of CONNACK if ctx has no deadline
+	defaultConnectTimeout = 10 * time.Second
+)
+
+// ErrClosed is returned after the connection is closed
+var ErrClosed = errors.New("mqtt: connection closed")
+
+// ConnectError is returned when the broker refuses the connection
+type ConnectError struct {
+	Code byte
+}
+
+func (e *ConnectError) Error() string {
+	reasons := map[byte]string{
+		1: "unacceptable protocol version",
+		2: "identifier rejected",
+		3: "server unavailable",
+		4: "bad user name or password",
+		5: "not authorized",
+	}
+	if r, ok := reasons[e.Code]; ok {
+		return "mqtt: connection refused: " + r
+	}
+	return fmt.Sprintf("mqtt: connection refused: code %d", e.Code)
+}
+
+// Options is options to connect to the broker
+type Options struct {
+	Broker    string // host:port, tcp:// or mqtt:// prefix is allowed
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	Will      *Message // published by the broker when the connection is lost
+}
+
+// Handler is called with messages matched to subscription
+type Handler func(Message)
+
+type subscription struct {
+	filter  string
+	handler Handler
+}
+
+// Client is MQTT 3.1.1 client with clean session.
+// It doesn't reconnect, so Done should be watched to connect again.
+type Client struct {
+	conn      net.Conn
+	keepAlive time.Duration
+
+	wmu sync.Mutex // 書き込みの排他
+
+	mu            sync.Mutex
+	nextID        uint16
+	waiters       map[uint16]chan packet
+	subscriptions []subscription
+	err           error
+
+	messages chan Message
+	done     chan struct{}
+}
+
+// Connect connects to the broker and returns Client
+func Connect(ctx context.Context, opts Options) (*Client, error) {
+	addr := opts.Broker
+	for _, prefix := range []string{"tcp://", "mqtt://"} {
+		addr = strings.TrimPrefix(addr, prefix)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "1883")
+	}
+	keepAlive := opts.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: failed to connect to %s: %w", addr, err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultConnectTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c := &Client{
+		conn:      conn,
+		keepAlive: keepAlive,
+		waiters:   map[uint16]chan packet{},
+		messages:  make(chan Message, 64),
+		done:      make(chan struct{}),
+	}
+	if err := c.write(connectPacket(opts, keepAlive)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: failed to read CONNACK: %w", err)
+	}
+	if p.kind != typeConnack || len(p.body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: unexpected packet type %d instead of CONNACK", p.kind)
+	}
+	if p.body[1] != 0 {
+		conn.Close()
+		return nil, &ConnectError{Code: p.body[1]}
+	}
+	conn.SetDeadline(time.Time{})
+
+	go c.readLoop(r)
+	go c.dispatchLoop()
+	go c.pingLoop()
+	return c, nil
+}
+
+func connectPacket(opts Options, keepAlive time.Duration) packet {
+	flags := byte(0x02) // clean session
+	if opts.Will != nil {
+		flags |= 0x04 | opts.Will.QoS<<3
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 (3.1.1)
+	body = appendUint16(body, uint16(keepAlive/time.Second))
+	body = appendString(body, opts.ClientID)
+	if opts.Will != nil {
+		body = appendString(body, opts.Will.Topic)
+		body = appendString(body, string(opts.Will.Payload))
+	}
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+	return packet{kind: typeConnect, body: body}
+}
+
+func (c *Client) write(p packet) error {
+	b, err := p.encode()
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := c.conn.Write(b); err != nil {
+		c.closeWithError(err)
+		return fmt.Errorf("mqtt: failed to write: %w", err)
+	}
+	return nil
+}
+
+// readLoop reads packets until the connection is closed.
+// The connection is considered lost if nothing is received for 1.5 times of keep alive.
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		p, err := readPacket(r)
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+		switch p.kind {
+		case typePublish:
+			m, id, err := parsePublish(p)
+			if err != nil {
+				c.closeWithError(err)
+				return
+			}
+			if m.QoS == 1 {
+				c.write(packet{kind: typePuback, body: appendUint16(nil, id)})
+			}
+			select {
+			case c.messages <- m:
+			case <-c.done:
+				return
+			}
+		case typePuback, typeSuback, typeUnsuback:
+			id := (&reader{b: p.body}).uint16()
+			c.mu.Lock()
+			ch, ok := c.waiters[id]
+			delete(c.waiters, id)
+			c.mu.Unlock()
+			if ok {
+				ch <- p
+			}
+		case typePingresp:
+		default:
+			c.closeWithError(fmt.Errorf("unexpected packet type %d", p.kind))
+			return
+		}
+	}
+}
+
+// dispatchLoop calls handlers in order of messages, apart from readLoop so that handlers can publish
+func (c *Client) dispatchLoop() {
+	for {
+		select {
+		case m := <-c.messages:
+			c.mu.Lock()
+			handlers := []Handler{}
+			for _, s := range c.subscriptions {
+				if MatchTopic(s.filter, m.Topic) {
+					handlers = append(handlers, s.handler)
+				}
+			}
+			c.mu.Unlock()
+			for _, h := range handlers {
+				h(m)
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) pingLoop() {
+	t := time.NewTicker(c.keepAlive)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := c.write(packet{kind: typePingreq}); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// request sends p with new packet identifier made by build, and waits for the acknowledgement
+func (c *Client) request(ctx context.Context, build func(id uint16) packet) (packet, error) {
+	ch := make(chan packet, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return packet{}, c.err
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID++
+	}
+	id := c.nextID
+	c.waiters[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.waiters, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(build(id)); err != nil {
+		return packet{}, err
+	}
+	select {
+	case p := <-ch:
+		return p, nil
+	case <-c.done:
+		return packet{}, c.Err()
+	case <-ctx.Done():
+		return packet{}, ctx.Err()
+	}
+}
+
+// Publish sends message to topic. It waits for PUBACK if qos is 1.
+func (c *Client) Publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte) error {
+	if err := validTopic(topic); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	if qos > 1 {
+		return fmt.Errorf("mqtt: QoS %d is not supported", qos)
+	}
+	m := Message{Topic: topic, Payload: payload, QoS: qos, Retain: retain}
+	if qos == 0 {
+		if err := c.Err(); err != nil {
+			return err
+		}
+		return c.write(m.packet(0))
+	}
+	_, err := c.request(ctx, m.packet)
+	return err
+}
+
+// Subscribe subscribes filter with maximum qos, and handler is called for each message.
+// Handlers are called one by one in order of messages.
+func (c *Client) Subscribe(ctx context.Context, filter string, qos byte, handler Handler) error {
+	if err := validFilter(filter); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	if qos > 1 {
+		return fmt.Errorf("mqtt: QoS %d is not supported", qos)
+	}
+	// SUBACKより先にメッセージが届くことがあるので先に登録する
+	c.mu.Lock()
+	c.subscriptions = append(c.subscriptions, subscription{filter: filter, handler: handler})
+	c.mu.Unlock()
+
+	p, err := c.request(ctx, func(id uint16) packet {
+		body := appendUint16(nil, id)
+		body = appendString(body, filter)
+		body = append(body, qos)
+		return packet{kind: typeSubscribe, flags: 0x02, body: body}
+	})
+	if err == nil && (len(p.body) < 3 || p.body[2] == 0x80) {
+		err = fmt.Errorf("mqtt: subscription to %s is refused", filter)
+	}
+	if err != nil {
+		c.mu.Lock()
+		for i, s := range c.subscriptions {
+			if s.filter == filter {
+				c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+				break
+			}
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// Done returns channel which is closed when the connection is lost or closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason why the connection is closed, nil if it is alive
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) closeWithError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+	c.conn.Close()
+}
+
+// Close sends DISCONNECT and closes the connection
+func (c *Client) Close() error {
+	if c.Err() != nil {
+		return nil
+	}
+	err := c.write(packet{kind: typeDisconnect})
+	c.closeWithError(ErrClosed)
+	return err
+}
@@ -0,0 +1,140 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func connect(t *testing.T, ctx context.Context, s *Server, opts Options) *Client {
+	t.Helper()
+	opts.Broker = "tcp://" + s.Addr()
+	c, err := Connect(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// receive returns the next message or fails after timeout
+func receive(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(3 * time.Second):
+		t.Fatal("message is not received")
+	}
+	return Message{}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestServer(t)
+	defer s.Close()
+
+	sub := connect(t, ctx, s, Options{ClientID: "sub"})
+	defer sub.Close()
+	pub := connect(t, ctx, s, Options{ClientID: "pub"})
+	defer pub.Close()
+
+	if err := pub.Publish(ctx, "home/aircon/state", 1, true, []byte("ON")); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Message, 4)
+	if err := sub.Subscribe(ctx, "home/+/state", 1, func(m Message) { ch <- m }); err != nil {
+		t.Fatal(err)
+	}
+	m := receive(t, ch)
+	if m.Topic != "home/aircon/state" || string(m.Payload) != "ON" || !m.Retain {
+		t.Errorf("retained message differs: %+v", m)
+	}
+
+	if err := pub.Publish(ctx, "home/light/state", 0, false, []byte("OFF")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Publish(ctx, "home/light/other", 1, false, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	m = receive(t, ch)
+	if m.Topic != "home/light/state" || string(m.Payload) != "OFF" || m.Retain {
+		t.Errorf("message differs: %+v", m)
+	}
+	select {
+	case m := <-ch:
+		t.Errorf("unmatched message is received: %+v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := pub.Publish(ctx, "home/#", 0, false, nil); err == nil {
+		t.Errorf("wildcard topic should be error")
+	}
+}
+
+func TestClient_Will(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestServer(t)
+	defer s.Close()
+
+	will := &Message{Topic: "bridge/status", Payload: []byte("offline"), Retain: true}
+	c := connect(t, ctx, s, Options{ClientID: "bridge", Will: will})
+	if err := c.Publish(ctx, "bridge/status", 1, true, []byte("online")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 切断を検知してDoneが閉じられる
+	s.Disconnect()
+	select {
+	case <-c.Done():
+	case <-ctx.Done():
+		t.Fatal("disconnection is not detected")
+	}
+	if c.Err() == nil {
+		t.Errorf("error should be set")
+	}
+	if err := c.Publish(ctx, "bridge/status", 0, false, []byte("x")); err == nil {
+		t.Errorf("publish after disconnection should be error")
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if m, ok := s.Retained("bridge/status"); ok && string(m.Payload) == "offline" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("will is not published")
+}
+
+func TestConnect_Refused(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestServer(t)
+	addr := s.Addr()
+	s.Close()
+
+	if _, err := Connect(ctx, Options{Broker: addr}); err == nil {
+		t.Errorf("error expected for closed server")
+	}
+}
@@ -0,0 +1,230 @@
+// Package mqtt is MQTT 3.1.1 client and embedded broker supporting QoS 0 and 1
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// control packet types
+const (
+	typeConnect     byte = 1
+	typeConnack     byte = 2
+	typePublish     byte = 3
+	typePuback      byte = 4
+	typeSubscribe   byte = 8
+	typeSuback      byte = 9
+	typeUnsubscribe byte = 10
+	typeUnsuback    byte = 11
+	typePingreq     byte = 12
+	typePingresp    byte = 13
+	typeDisconnect  byte = 14
+)
+
+// maxRemainingLength is the maximum of remaining length which can be encoded in 4 bytes
+const maxRemainingLength = 268435455
+
+// packet is MQTT control packet
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	h, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length := 0
+	for i, shift := 0, uint(0); ; i, shift = i+1, shift+7 {
+		if i == 4 {
+			return packet{}, fmt.Errorf("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: h >> 4, flags: h & 0x0f, body: body}, nil
+}
+
+func (p packet) encode() ([]byte, error) {
+	length := len(p.body)
+	if length > maxRemainingLength {
+		return nil, fmt.Errorf("packet too large: %d", length)
+	}
+	b := []byte{p.kind<<4 | p.flags}
+	for {
+		d := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if length == 0 {
+			break
+		}
+	}
+	return append(b, p.body...), nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// reader reads fields of packet body
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uint16() uint16 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 2 {
+		r.err = fmt.Errorf("packet too short")
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 1 {
+		r.err = fmt.Errorf("packet too short")
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := int(r.uint16())
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = fmt.Errorf("packet too short")
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) string() string {
+	return string(r.bytes())
+}
+
+// Message is application message of PUBLISH
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+func (m Message) packet(id uint16) packet {
+	flags := m.QoS << 1
+	if m.Retain {
+		flags |= 0x01
+	}
+	body := appendString(nil, m.Topic)
+	if m.QoS > 0 {
+		body = appendUint16(body, id)
+	}
+	body = append(body, m.Payload...)
+	return packet{kind: typePublish, flags: flags, body: body}
+}
+
+// parsePublish returns message and packet identifier of PUBLISH
+func parsePublish(p packet) (Message, uint16, error) {
+	m := Message{QoS: (p.flags >> 1) & 0x03, Retain: p.flags&0x01 != 0}
+	if m.QoS > 1 {
+		return m, 0, fmt.Errorf("QoS %d is not supported", m.QoS)
+	}
+	r := &reader{b: p.body}
+	m.Topic = r.string()
+	var id uint16
+	if m.QoS > 0 {
+		id = r.uint16()
+	}
+	if r.err != nil {
+		return m, 0, r.err
+	}
+	m.Payload = append([]byte{}, r.b...)
+	return m, id, nil
+}
+
+// MatchTopic returns true if topic matches filter with wildcards + and #
+func MatchTopic(filter, topic string) bool {
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	// $で始まるトピックはワイルドカードにマッチしない
+	if strings.HasPrefix(topic, "$") && (fs[0] == "+" || fs[0] == "#") {
+		return false
+	}
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if f != "+" && f != ts[i] {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
+
+// validTopic returns error if topic name for PUBLISH is invalid
+func validTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("empty topic")
+	}
+	if strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("wildcard in topic name: %s", topic)
+	}
+	return nil
+}
+
+// validFilter returns error if topic filter for SUBSCRIBE is invalid
+func validFilter(filter string) error {
+	if filter == "" {
+		return fmt.Errorf("empty topic filter")
+	}
+	levels := strings.Split(filter, "/")
+	for i, l := range levels {
+		if strings.Contains(l, "#") && (l != "#" || i != len(levels)-1) {
+			return fmt.Errorf("invalid multi-level wildcard: %s", filter)
+		}
+		if strings.Contains(l, "+") && l != "+" {
+			return fmt.Errorf("invalid single-level wildcard: %s", filter)
+		}
+	}
+	return nil
+}
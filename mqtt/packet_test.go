@@ -0,0 +1,78 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPacket(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		p := packet{kind: typePublish, flags: 0x03, body: bytes.Repeat([]byte{0xab}, n)}
+		b, err := p.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+		if err != nil {
+			t.Fatalf("%d: %s", n, err)
+		}
+		if got.kind != p.kind || got.flags != p.flags || !bytes.Equal(got.body, p.body) {
+			t.Errorf("%d: packet differs", n)
+		}
+	}
+
+	// PINGREQ
+	b, _ := packet{kind: typePingreq}.encode()
+	if diff := cmp.Diff([]byte{0xc0, 0x00}, b); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestParsePublish(t *testing.T) {
+	t.Parallel()
+
+	m := Message{Topic: "a/b", Payload: []byte("on"), QoS: 1, Retain: true}
+	got, id, err := parsePublish(m.packet(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 10 {
+		t.Errorf("packet identifier differs: %d", id)
+	}
+	if diff := cmp.Diff(m, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b/c", "a/b/c", true},
+		{"a/+/c", "a/b/c", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a", true},
+		{"#", "a/b", true},
+		{"+/+", "a/b", true},
+		{"a/b/c/d", "a/b/c", false},
+		{"#", "$SYS/uptime", false},
+	}
+	for _, tc := range testcases {
+		if got := MatchTopic(tc.filter, tc.topic); got != tc.want {
+			t.Errorf("%s %s: want:%v got:%v", tc.filter, tc.topic, tc.want, got)
+		}
+	}
+
+	if validFilter("a/#/b") == nil || validFilter("a+/b") == nil || validFilter("a/+/#") != nil {
+		t.Errorf("filter validation differs")
+	}
+}
@@ -0,0 +1,320 @@
+package mqtt
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Server is embedded MQTT 3.1.1 broker for tests and small setups.
+// It supports QoS 0 and 1 (messages are delivered in QoS 0), retained messages and wills,
+// without authentication and persistent sessions.
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	retained map[string]Message
+	closed   bool
+}
+
+type session struct {
+	conn   net.Conn
+	wmu    sync.Mutex
+	id     string
+	filter map[string]struct{}
+	will   *Message
+}
+
+// NewServer listens on addr (e.g. "127.0.0.1:0") and serves in background
+func NewServer(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: l,
+		sessions: map[*session]struct{}{},
+		retained: map[string]Message{},
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns address which the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops listening and disconnects all clients
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	sessions := s.sessionList()
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	for _, ss := range sessions {
+		ss.conn.Close()
+	}
+	return err
+}
+
+// Disconnect closes connections of all clients without DISCONNECT, as if the network is lost
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	sessions := s.sessionList()
+	s.mu.Unlock()
+	for _, ss := range sessions {
+		ss.conn.Close()
+	}
+}
+
+// Retained returns retained message of topic
+func (s *Server) Retained(topic string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.retained[topic]
+	return m, ok
+}
+
+// RetainedTopics returns topics of retained messages matched to filter in sorted order
+func (s *Server) RetainedTopics(filter string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	topics := []string{}
+	for t := range s.retained {
+		if MatchTopic(filter, t) {
+			topics = append(topics, t)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Clients returns number of connected clients
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for ss := range s.sessions {
+		if ss.id != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) sessionList() []*session {
+	list := make([]*session, 0, len(s.sessions))
+	for ss := range s.sessions {
+		list = append(list, ss)
+	}
+	return list
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if !closed {
+				log.Printf("mqtt server: %s", err)
+			}
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	ss := &session{conn: conn, filter: map[string]struct{}{}}
+	s.mu.Lock()
+	s.sessions[ss] = struct{}{}
+	s.mu.Unlock()
+
+	graceful := false
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.sessions, ss)
+		s.mu.Unlock()
+		if !graceful && ss.will != nil {
+			s.publish(*ss.will)
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(defaultConnectTimeout))
+	p, err := readPacket(r)
+	if err != nil || p.kind != typeConnect {
+		return
+	}
+	keepAlive, err := s.connect(ss, p)
+	if err != nil {
+		ss.write(packet{kind: typeConnack, body: []byte{0, 1}})
+		return
+	}
+	if err := ss.write(packet{kind: typeConnack, body: []byte{0, 0}}); err != nil {
+		return
+	}
+
+	for {
+		if keepAlive > 0 {
+			conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.kind {
+		case typePublish:
+			m, id, err := parsePublish(p)
+			if err != nil {
+				return
+			}
+			// 配信と保持を済ませてからPUBACKを返す
+			s.publish(m)
+			if m.QoS == 1 {
+				ss.write(packet{kind: typePuback, body: appendUint16(nil, id)})
+			}
+		case typeSubscribe:
+			s.subscribe(ss, p)
+		case typeUnsubscribe:
+			rd := &reader{b: p.body}
+			id := rd.uint16()
+			s.mu.Lock()
+			for len(rd.b) > 0 && rd.err == nil {
+				delete(ss.filter, rd.string())
+			}
+			s.mu.Unlock()
+			ss.write(packet{kind: typeUnsuback, body: appendUint16(nil, id)})
+		case typePingreq:
+			ss.write(packet{kind: typePingresp})
+		case typeDisconnect:
+			graceful = true
+			return
+		default:
+			return
+		}
+	}
+}
+
+// connect reads CONNECT packet and returns keep alive
+func (s *Server) connect(ss *session, p packet) (time.Duration, error) {
+	r := &reader{b: p.body}
+	name := r.string()
+	level := r.byte()
+	flags := r.byte()
+	keepAlive := time.Duration(r.uint16()) * time.Second
+	id := r.string()
+	if r.err != nil {
+		return 0, r.err
+	}
+	if name != "MQTT" || level != 4 {
+		return 0, fmt.Errorf("unsupported protocol %s %d", name, level)
+	}
+	if flags&0x04 != 0 {
+		topic := r.string()
+		payload := r.bytes()
+		ss.will = &Message{Topic: topic, Payload: append([]byte{}, payload...), QoS: (flags >> 3) & 0x03, Retain: flags&0x20 != 0}
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if id == "" {
+		id = fmt.Sprintf("%p", ss)
+	}
+	s.mu.Lock()
+	ss.id = id
+	s.mu.Unlock()
+	return keepAlive, nil
+}
+
+func (s *Server) subscribe(ss *session, p packet) {
+	r := &reader{b: p.body}
+	id := r.uint16()
+	codes := []byte{}
+	filters := []string{}
+	for len(r.b) > 0 && r.err == nil {
+		filter := r.string()
+		qos := r.byte()
+		if r.err != nil {
+			break
+		}
+		if validFilter(filter) != nil || qos > 2 {
+			codes = append(codes, 0x80)
+			continue
+		}
+		if qos > 1 {
+			qos = 1
+		}
+		codes = append(codes, qos)
+		filters = append(filters, filter)
+	}
+
+	s.mu.Lock()
+	retained := []Message{}
+	for _, f := range filters {
+		ss.filter[f] = struct{}{}
+		for t, m := range s.retained {
+			if MatchTopic(f, t) {
+				retained = append(retained, m)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	body := appendUint16(nil, id)
+	ss.write(packet{kind: typeSuback, body: append(body, codes...)})
+	sort.Slice(retained, func(i, j int) bool { return retained[i].Topic < retained[j].Topic })
+	for _, m := range retained {
+		ss.write(Message{Topic: m.Topic, Payload: m.Payload, Retain: true}.packet(0))
+	}
+}
+
+// publish delivers m to subscribers in QoS 0, and keeps it if retained
+func (s *Server) publish(m Message) {
+	s.mu.Lock()
+	if m.Retain {
+		if len(m.Payload) == 0 {
+			delete(s.retained, m.Topic)
+		} else {
+			s.retained[m.Topic] = m
+		}
+	}
+	targets := []*session{}
+	for ss := range s.sessions {
+		for f := range ss.filter {
+			if MatchTopic(f, m.Topic) {
+				targets = append(targets, ss)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ss := range targets {
+		ss.write(Message{Topic: m.Topic, Payload: m.Payload}.packet(0))
+	}
+}
+
+func (ss *session) write(p packet) error {
+	b, err := p.encode()
+	if err != nil {
+		return err
+	}
+	ss.wmu.Lock()
+	defer ss.wmu.Unlock()
+	ss.conn.SetWriteDeadline(time.Now().Add(defaultConnectTimeout))
+	_, err = ss.conn.Write(b)
+	return err
+}
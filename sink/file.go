@@ -0,0 +1,194 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of file sink
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Formats is names of supported formats of file sink
+var Formats = []string{FormatCSV, FormatJSONL}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// FileOptions is options of file sink.
+// The file is rotated when it exceeds MaxSize bytes or the day is changed if Daily is set,
+// and renamed to <name>-<time opened>.<ext>.
+type FileOptions struct {
+	Path    string
+	Format  string // csv or jsonl
+	MaxSize int64  // not rotated by size if 0
+	Daily   bool
+	Keep    int // rotated files to keep, all files are kept if 0
+}
+
+// File writes points to a file in CSV with columns time,name,value,labels or JSON Lines.
+// Labels in CSV are written as "key=value" separated by ";".
+type File struct {
+	opts   FileOptions
+	now    func() time.Time
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// JSONPoint is point written in JSON Lines
+type JSONPoint struct {
+	Time   string            `json:"time"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// OpenFile opens file sink with opts, and appends points to the file if it exists
+func OpenFile(opts FileOptions) (*File, error) {
+	if opts.Format != FormatCSV && opts.Format != FormatJSONL {
+		return nil, fmt.Errorf("unknown format %q, one of %s is expected", opts.Format, strings.Join(Formats, ", "))
+	}
+	f := &File{opts: opts, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	fp, err := os.OpenFile(f.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	st, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return err
+	}
+	f.f = fp
+	f.size = st.Size()
+	f.opened = f.now()
+	// 既存のファイルは最後に書き込んだ日から続ける
+	if f.size > 0 {
+		f.opened = st.ModTime()
+	}
+	return nil
+}
+
+// Write implements Sink
+func (f *File) Write(ctx context.Context, points []Point) error {
+	if f.f == nil {
+		// ローテーションに失敗した場合は開き直す
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.rotationNeeded() {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	buf := &bytes.Buffer{}
+	if f.size == 0 && f.opts.Format == FormatCSV {
+		buf.WriteString("time,name,value,labels\n")
+	}
+	if err := f.encode(buf, points); err != nil {
+		return err
+	}
+	n, err := f.f.Write(buf.Bytes())
+	f.size += int64(n)
+	return err
+}
+
+func (f *File) encode(buf *bytes.Buffer, points []Point) error {
+	if f.opts.Format == FormatJSONL {
+		enc := json.NewEncoder(buf)
+		for _, p := range points {
+			if err := enc.Encode(JSONPoint{Time: p.Time.Format(timeFormat), Name: p.Name, Labels: p.Labels, Value: p.Value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w := csv.NewWriter(buf)
+	for _, p := range points {
+		labels := []string{}
+		for _, k := range sortedKeys(p.Labels) {
+			labels = append(labels, k+"="+p.Labels[k])
+		}
+		record := []string{p.Time.Format(timeFormat), p.Name, strconv.FormatFloat(p.Value, 'g', -1, 64), strings.Join(labels, ";")}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (f *File) rotationNeeded() bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSize > 0 && f.size >= f.opts.MaxSize {
+		return true
+	}
+	if f.opts.Daily {
+		y1, m1, d1 := f.opened.Date()
+		y2, m2, d2 := f.now().Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
+
+// rotate renames the current file and opens new one
+func (f *File) rotate() error {
+	if err := f.f.Close(); err != nil {
+		return err
+	}
+	f.f = nil
+	ext := filepath.Ext(f.opts.Path)
+	base := strings.TrimSuffix(f.opts.Path, ext)
+	if err := os.Rename(f.opts.Path, base+"-"+f.opened.Format("20060102-150405")+ext); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if f.opts.Keep <= 0 {
+		return nil
+	}
+	rotated, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return err
+	}
+	// 時刻の書式により名前順が古い順になる
+	sort.Strings(rotated)
+	for len(rotated) > f.opts.Keep {
+		if err := os.Remove(rotated[0]); err != nil {
+			logger.Printf("[Error] failed to remove %s: %s", rotated[0], err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Close implements Sink
+func (f *File) Close() error {
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ts := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Name: "home_smartmeter_instantpower", Value: 480, Time: ts},
+		{Name: "echonet_property_value", Labels: map[string]string{"name": "温度設定値", "epc": "b3"}, Value: 26, Time: ts},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatCSV,
+			want: "time,name,value,labels\n" +
+				"2021-03-01T12:00:00.000Z,home_smartmeter_instantpower,480,\n" +
+				"2021-03-01T12:00:00.000Z,echonet_property_value,26,epc=b3;name=温度設定値\n" +
+				"2021-03-01T12:00:00.000Z,home_smartmeter_instantpower,480,\n" +
+				"2021-03-01T12:00:00.000Z,echonet_property_value,26,epc=b3;name=温度設定値\n",
+		},
+		{
+			format: FormatJSONL,
+			want: `{"time":"2021-03-01T12:00:00.000Z","name":"home_smartmeter_instantpower","value":480}` + "\n" +
+				`{"time":"2021-03-01T12:00:00.000Z","name":"echonet_property_value","labels":{"epc":"b3","name":"温度設定値"},"value":26}` + "\n" +
+				`{"time":"2021-03-01T12:00:00.000Z","name":"home_smartmeter_instantpower","value":480}` + "\n" +
+				`{"time":"2021-03-01T12:00:00.000Z","name":"echonet_property_value","labels":{"epc":"b3","name":"温度設定値"},"value":26}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "metrics."+tt.format)
+		// 再度開いた場合は追記する
+		for i := 0; i < 2; i++ {
+			f, err := OpenFile(FileOptions{Path: path, Format: tt.format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Write(context.Background(), points); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if diff := cmp.Diff(tt.want, readFile(t, path)); diff != "" {
+			t.Errorf("%s (-want +got):\n%s", tt.format, diff)
+		}
+	}
+
+	if _, err := OpenFile(FileOptions{Path: filepath.Join(dir, "metrics.txt"), Format: "txt"}); err == nil {
+		t.Error("unknown format must be error")
+	}
+}
+
+func TestFile_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "metrics.csv")
+	now := time.Date(2021, 3, 1, 23, 58, 0, 0, time.Local)
+	f, err := OpenFile(FileOptions{Path: path, Format: FormatCSV, MaxSize: 100, Daily: true, Keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.now = func() time.Time { return now }
+	f.opened = now
+
+	write := func() {
+		t.Helper()
+		p := Point{Name: "home_power", Value: 1, Time: now}
+		if err := f.Write(context.Background(), []Point{p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rotated := func() []string {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(dir, "metrics-*.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range files {
+			files[i] = filepath.Base(files[i])
+		}
+		return files
+	}
+
+	// ヘッダと1行では100バイト未満で、2行目で超える
+	write()
+	write()
+	if files := rotated(); len(files) != 0 {
+		t.Errorf("rotated before exceeding max size: %v", files)
+	}
+	now = now.Add(time.Second)
+	write()
+	if diff := cmp.Diff([]string{"metrics-20210301-235800.csv"}, rotated()); diff != "" {
+		t.Errorf("rotated by size (-want +got):\n%s", diff)
+	}
+	if got := readFile(t, path); got != "time,name,value,labels\n"+now.Format(timeFormat)+",home_power,1,\n" {
+		t.Errorf("new file: %s", got)
+	}
+
+	// 日付が変わるとローテートする
+	now = time.Date(2021, 3, 2, 0, 0, 0, 0, time.Local)
+	write()
+	if diff := cmp.Diff([]string{"metrics-20210301-235800.csv", "metrics-20210301-235801.csv"}, rotated()); diff != "" {
+		t.Errorf("rotated by day (-want +got):\n%s", diff)
+	}
+
+	// 古いファイルから削除する
+	now = time.Date(2021, 3, 3, 0, 0, 0, 0, time.Local)
+	write()
+	if diff := cmp.Diff([]string{"metrics-20210301-235801.csv", "metrics-20210302-000000.csv"}, rotated()); diff != "" {
+		t.Errorf("kept files (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,120 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxBatchSize is number of lines sent in a write request
+const influxBatchSize = 5000
+
+// InfluxDBOptions is options of InfluxDB sink.
+// InfluxDB 1.8 or later accepts the same API with "<database>/<retention policy>" as Bucket and "<user>:<password>" as Token.
+type InfluxDBOptions struct {
+	URL    string // http://host:8086
+	Org    string
+	Bucket string
+	Token  string
+}
+
+// InfluxDB writes points in line protocol by HTTP API /api/v2/write.
+// Name of metric is measurement, labels are tags and value is field "value".
+type InfluxDB struct {
+	opts   InfluxDBOptions
+	client *http.Client
+}
+
+// NewInfluxDB returns InfluxDB sink with opts
+func NewInfluxDB(opts InfluxDBOptions) (*InfluxDB, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q, http(s)://host:port is expected", opts.URL)
+	}
+	return &InfluxDB{opts: opts, client: &http.Client{}}, nil
+}
+
+// Write implements Sink
+func (db *InfluxDB) Write(ctx context.Context, points []Point) error {
+	for len(points) > 0 {
+		n := len(points)
+		if n > influxBatchSize {
+			n = influxBatchSize
+		}
+		buf := &bytes.Buffer{}
+		for _, p := range points[:n] {
+			writeLine(buf, p)
+		}
+		if err := db.post(ctx, buf); err != nil {
+			return err
+		}
+		points = points[n:]
+	}
+	return nil
+}
+
+func (db *InfluxDB) post(ctx context.Context, body io.Reader) error {
+	q := url.Values{}
+	q.Set("org", db.opts.Org)
+	q.Set("bucket", db.opts.Bucket)
+	q.Set("precision", "ms")
+	u := strings.TrimRight(db.opts.URL, "/") + "/api/v2/write?" + q.Encode()
+	req, err := http.NewRequest(http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if db.opts.Token != "" {
+		req.Header.Set("Authorization", "Token "+db.opts.Token)
+	}
+	resp, err := db.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("InfluxDB returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Close implements Sink
+func (db *InfluxDB) Close() error {
+	db.client.CloseIdleConnections()
+	return nil
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// writeLine writes p in line protocol with timestamp in ms.
+// Tags with empty value are omitted because they are not allowed in line protocol.
+func writeLine(buf *bytes.Buffer, p Point) {
+	buf.WriteString(measurementEscaper.Replace(p.Name))
+	for _, k := range sortedKeys(p.Labels) {
+		v := p.Labels[k]
+		if v == "" {
+			continue
+		}
+		buf.WriteByte(',')
+		buf.WriteString(tagEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(tagEscaper.Replace(v))
+	}
+	buf.WriteString(" value=")
+	buf.WriteString(strconv.FormatFloat(p.Value, 'g', -1, 64))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(p.Time.UnixNano()/int64(time.Millisecond), 10))
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,129 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// influxStub is InfluxDB write API which records requests, and fails with status if it is set
+type influxStub struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (s *influxStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, string(body))
+	if s.status != 0 {
+		http.Error(w, `{"code":"unavailable","message":"service unavailable"}`, s.status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestInfluxDB(t *testing.T) {
+	stub := &influxStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	db, err := NewInfluxDB(InfluxDBOptions{URL: srv.URL + "/", Org: "home", Bucket: "energy", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ts := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Name: "home_smartmeter_instantpower", Value: 480, Time: ts},
+		{Name: "home_smartmeter_cumulative_energy_kwh", Labels: map[string]string{"direction": "normal", "id": ""}, Value: 1234.5, Time: ts.Add(time.Second)},
+	}
+	if err := db.Write(context.Background(), points); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.requests) != 1 {
+		t.Fatalf("requests: %d", len(stub.requests))
+	}
+	r := stub.requests[0]
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/write" {
+		t.Errorf("request: %s %s", r.Method, r.URL.Path)
+	}
+	q := r.URL.Query()
+	if q.Get("org") != "home" || q.Get("bucket") != "energy" || q.Get("precision") != "ms" {
+		t.Errorf("query: %s", r.URL.RawQuery)
+	}
+	if got := r.Header.Get("Authorization"); got != "Token secret" {
+		t.Errorf("Authorization: %q", got)
+	}
+	want := "home_smartmeter_instantpower value=480 1614600000000\n" +
+		"home_smartmeter_cumulative_energy_kwh,direction=normal value=1234.5 1614600001000\n"
+	if stub.bodies[0] != want {
+		t.Errorf("body:\n%s\nwant:\n%s", stub.bodies[0], want)
+	}
+
+	stub.status = http.StatusServiceUnavailable
+	err = db.Write(context.Background(), points)
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "service unavailable") {
+		t.Errorf("error: %v", err)
+	}
+}
+
+func TestInfluxDB_Batch(t *testing.T) {
+	stub := &influxStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	db, err := NewInfluxDB(InfluxDBOptions{URL: srv.URL, Bucket: "elexporter/autogen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := make([]Point, influxBatchSize+1)
+	for i := range points {
+		points[i] = Point{Name: "home_power", Value: float64(i), Time: time.Unix(int64(i), 0)}
+	}
+	if err := db.Write(context.Background(), points); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.requests) != 2 {
+		t.Fatalf("requests: %d", len(stub.requests))
+	}
+	if n := strings.Count(stub.bodies[1], "\n"); n != 1 {
+		t.Errorf("lines in the last batch: %d", n)
+	}
+	if got := stub.requests[0].Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization without token: %q", got)
+	}
+}
+
+func TestNewInfluxDB_InvalidURL(t *testing.T) {
+	for _, u := range []string{"", "localhost:8086", "ftp://localhost", "http://"} {
+		if _, err := NewInfluxDB(InfluxDBOptions{URL: u}); err == nil {
+			t.Errorf("%q: error is expected", u)
+		}
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeLine(buf, Point{
+		Name:   "home power,total",
+		Labels: map[string]string{"name": "温度 設定,値=1", "class": "0130"},
+		Value:  -0.5,
+		Time:   time.Unix(1, 500*int64(time.Millisecond)),
+	})
+	want := `home\ power\,total,class=0130,name=温度\ 設定\,値\=1 value=-0.5 1500` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
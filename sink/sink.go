@@ -0,0 +1,207 @@
+// Package sink writes metrics exported to Prometheus to time-series outputs such as InfluxDB and files
+package sink
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// DefaultBufferSize is number of points kept for a sink while it is unavailable
+	DefaultBufferSize = 100000
+	// writeTimeout is timeout to write points to a sink
+	writeTimeout = 10 * time.Second
+)
+
+var logger = log.New(os.Stdout, "[Sink]", log.LstdFlags)
+
+// runtimeMetrics is prefixes of metrics of the process, which are not exported unless selected explicitly
+var runtimeMetrics = []string{"go_", "process_", "promhttp_"}
+
+// Point is a sample of metric
+type Point struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+	Time   time.Time
+}
+
+// Sink is output of points
+type Sink interface {
+	Write(ctx context.Context, points []Point) error
+	Close() error
+}
+
+// Exporter gathers metrics from Prometheus registry and writes them to sinks.
+// Points which are failed to be written are kept up to buffer size of the sink, and written with the next points.
+type Exporter struct {
+	gatherer prometheus.Gatherer
+	metrics  []string
+	outputs  []*output
+	last     map[string]int64 // series -> timestamp of the last point in ms
+}
+
+type output struct {
+	name   string
+	sink   Sink
+	size   int
+	points []Point
+}
+
+// NewExporter returns Exporter of metrics in g whose names start with one of metrics.
+// Metrics other than runtime metrics of the process are exported if metrics is empty.
+func NewExporter(g prometheus.Gatherer, metrics []string) *Exporter {
+	return &Exporter{gatherer: g, metrics: metrics, last: map[string]int64{}}
+}
+
+// Add adds sink named name, which keeps at most size points while it is unavailable
+func (e *Exporter) Add(name string, s Sink, size int) {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	e.outputs = append(e.outputs, &output{name: name, sink: s, size: size})
+}
+
+// Run exports metrics every interval until ctx is done, and closes sinks
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			e.Export(ctx, now)
+		case <-ctx.Done():
+			e.Close()
+			return
+		}
+	}
+}
+
+// Export gathers metrics and writes them with buffered points to all sinks.
+// now is time of metrics without timestamp.
+func (e *Exporter) Export(ctx context.Context, now time.Time) {
+	points, err := e.gather(now)
+	if err != nil {
+		// 一部のコレクタが失敗しても取得できたメトリクスは出力する
+		logger.Printf("[Error] failed to gather metrics: %s", err)
+	}
+	for _, o := range e.outputs {
+		o.write(ctx, points)
+	}
+}
+
+// Close closes all sinks
+func (e *Exporter) Close() {
+	for _, o := range e.outputs {
+		if len(o.points) > 0 {
+			logger.Printf("[Error] %d points are not written to %s", len(o.points), o.name)
+		}
+		if err := o.sink.Close(); err != nil {
+			logger.Printf("[Error] failed to close %s: %s", o.name, err)
+		}
+	}
+}
+
+func (o *output) write(ctx context.Context, points []Point) {
+	o.points = append(o.points, points...)
+	if n := len(o.points) - o.size; n > 0 {
+		logger.Printf("[Error] buffer of %s is full, %d oldest points are dropped", o.name, n)
+		o.points = append([]Point(nil), o.points[n:]...)
+	}
+	if len(o.points) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	if err := o.sink.Write(ctx, o.points); err != nil {
+		logger.Printf("[Error] failed to write %d points to %s, retry later: %s", len(o.points), o.name, err)
+		return
+	}
+	o.points = nil
+}
+
+// gather returns points of gauges, counters and untyped metrics.
+// Metrics with timestamp are skipped if they are not updated since the last export.
+func (e *Exporter) gather(now time.Time) ([]Point, error) {
+	families, err := e.gatherer.Gather()
+	points := []Point{}
+	for _, f := range families {
+		if !e.selected(f.GetName()) {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var v float64
+			switch f.GetType() {
+			case dto.MetricType_GAUGE:
+				v = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				v = m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				v = m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			p := Point{Name: f.GetName(), Labels: labels, Value: v, Time: now}
+			if ts := m.GetTimestampMs(); ts != 0 {
+				key := seriesKey(p)
+				if e.last[key] == ts {
+					continue
+				}
+				e.last[key] = ts
+				p.Time = time.Unix(0, ts*int64(time.Millisecond))
+			}
+			points = append(points, p)
+		}
+	}
+	return points, err
+}
+
+func (e *Exporter) selected(name string) bool {
+	if len(e.metrics) == 0 {
+		return !hasPrefix(name, runtimeMetrics)
+	}
+	return hasPrefix(name, e.metrics)
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesKey returns name and labels of p in sorted order
+func seriesKey(p Point) string {
+	keys := sortedKeys(p.Labels)
+	s := p.Name
+	for _, k := range keys {
+		s += "\xff" + k + "=" + p.Labels[k]
+	}
+	return s
+}
+
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,159 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fakeSink records points written to it, and fails while fail is set
+type fakeSink struct {
+	fail    bool
+	written [][]Point
+	closed  bool
+}
+
+func (s *fakeSink) Write(ctx context.Context, points []Point) error {
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	s.written = append(s.written, append([]Point(nil), points...))
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+// timestampCollector exports power with the time when it is updated like collectors of devices
+type timestampCollector struct {
+	desc    *prometheus.Desc
+	value   float64
+	updated time.Time
+}
+
+func (c *timestampCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *timestampCollector) Collect(ch chan<- prometheus.Metric) {
+	m := prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, c.value, "0130")
+	ch <- prometheus.NewMetricWithTimestamp(c.updated, m)
+}
+
+func TestExporter(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	power := &timestampCollector{
+		desc:    prometheus.NewDesc("home_power_watts", "power", []string{"id"}, nil),
+		value:   480,
+		updated: now.Add(-10 * time.Second),
+	}
+	version := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "home_exporter_version"}, []string{"version"})
+	version.WithLabelValues("v1").Inc()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGoCollector(), power, version)
+
+	s := &fakeSink{}
+	e := NewExporter(reg, nil)
+	e.Add("fake", s, 0)
+
+	e.Export(context.Background(), now)
+	want := [][]Point{{
+		{Name: "home_exporter_version", Labels: map[string]string{"version": "v1"}, Value: 1, Time: now},
+		{Name: "home_power_watts", Labels: map[string]string{"id": "0130"}, Value: 480, Time: power.updated},
+	}}
+	if diff := cmp.Diff(want, s.written); diff != "" {
+		t.Errorf("runtime metrics must be excluded (-want +got):\n%s", diff)
+	}
+
+	// 更新されていないメトリクスは出力しない
+	s.written = nil
+	now = now.Add(time.Minute)
+	e.Export(context.Background(), now)
+	want = [][]Point{{
+		{Name: "home_exporter_version", Labels: map[string]string{"version": "v1"}, Value: 1, Time: now},
+	}}
+	if diff := cmp.Diff(want, s.written); diff != "" {
+		t.Errorf("not updated metric must be skipped (-want +got):\n%s", diff)
+	}
+
+	// 出力先が使えない間はバッファして次に出力する
+	s.written = nil
+	s.fail = true
+	power.value, power.updated = 500, now
+	e.Export(context.Background(), now.Add(time.Minute))
+	power.value, power.updated = 520, now.Add(time.Minute)
+	e.Export(context.Background(), now.Add(2*time.Minute))
+	s.fail = false
+	e.Export(context.Background(), now.Add(3*time.Minute))
+	if len(s.written) != 1 {
+		t.Fatalf("buffered points must be written at once: %v", s.written)
+	}
+	values := []float64{}
+	for _, p := range s.written[0] {
+		if p.Name == "home_power_watts" {
+			values = append(values, p.Value)
+		}
+	}
+	if diff := cmp.Diff([]float64{500, 520}, values); diff != "" {
+		t.Errorf("buffered power (-want +got):\n%s", diff)
+	}
+
+	e.Close()
+	if !s.closed {
+		t.Error("sink is not closed")
+	}
+}
+
+func TestExporter_BufferSize(t *testing.T) {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "home_temperature"}, []string{"id"})
+	g.WithLabelValues("a").Set(20)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(g)
+
+	s := &fakeSink{fail: true}
+	e := NewExporter(reg, []string{"home_"})
+	e.Add("fake", s, 2)
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		e.Export(context.Background(), start.Add(time.Duration(i)*time.Minute))
+	}
+	s.fail = false
+	e.Export(context.Background(), start.Add(3*time.Minute))
+
+	// 古い点から捨てる
+	times := []time.Time{}
+	for _, p := range s.written[0] {
+		times = append(times, p.Time)
+	}
+	want := []time.Time{start.Add(2 * time.Minute), start.Add(3 * time.Minute)}
+	if diff := cmp.Diff(want, times); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestExporter_Metrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	for _, name := range []string{"home_power", "echonet_property_value", "other_value"} {
+		g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name})
+		g.Set(1)
+		reg.MustRegister(g)
+	}
+	s := &fakeSink{}
+	e := NewExporter(reg, []string{"home_", "echonet_"})
+	e.Add("fake", s, 0)
+	e.Export(context.Background(), time.Now())
+
+	names := []string{}
+	for _, p := range s.written[0] {
+		names = append(names, p.Name)
+	}
+	if diff := cmp.Diff([]string{"echonet_property_value", "home_power"}, names); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"flag"
 	"log"
 
-	"github.com/u-one/go-el-controller/wisun"
+	"github.com/matsuu/go-el-controller/wisun"
 )
 
 var portAddr = flag.String("port", "COM4", "Serial port address (COM4)")
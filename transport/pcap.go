@@ -0,0 +1,422 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Direction is direction of captured datagram
+type Direction int
+
+// Directions
+const (
+	Inbound  Direction = 1
+	Outbound Direction = 2
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "in"
+	case Outbound:
+		return "out"
+	default:
+		return "unknown"
+	}
+}
+
+// Packet is captured datagram
+type Packet struct {
+	Timestamp time.Time
+	Direction Direction
+	Peer      string // address of remote node, "ip" or "ip:port"
+	Local     string // address of this node, empty if unknown
+	Data      []byte
+}
+
+// Recorder records datagrams
+type Recorder interface {
+	Record(p Packet) error
+}
+
+// pcapng block types and constants
+// https://www.ietf.org/archive/id/draft-tuexen-opsawg-pcapng-03.html
+const (
+	blockTypeSHB uint32 = 0x0A0D0D0A // Section Header Block
+	blockTypeIDB uint32 = 0x00000001 // Interface Description Block
+	blockTypeEPB uint32 = 0x00000006 // Enhanced Packet Block
+
+	byteOrderMagic uint32 = 0x1A2B3C4D
+
+	linkTypeRaw  uint16 = 101 // LINKTYPE_RAW
+	linkTypeIPv4 uint16 = 228 // LINKTYPE_IPV4
+
+	optEndOfOpt uint16 = 0
+	optEPBFlags uint16 = 2
+
+	echonetLitePort = 3610
+	ipv4HeaderLen   = 20
+	udpHeaderLen    = 8
+)
+
+// PcapWriter writes datagrams to pcapng file.
+// Each datagram is encapsulated in IPv4/UDP headers so that Wireshark can dissect ECHONET Lite.
+type PcapWriter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+}
+
+// NewPcapWriter writes pcapng headers to w and returns PcapWriter
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	pw := &PcapWriter{w: bufio.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		pw.closer = c
+	}
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // major version
+	binary.LittleEndian.PutUint16(shb[6:], 0) // minor version
+	binary.LittleEndian.PutUint64(shb[8:], 0xFFFFFFFFFFFFFFFF)
+	if err := pw.writeBlock(blockTypeSHB, shb); err != nil {
+		return nil, err
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], linkTypeIPv4)
+	binary.LittleEndian.PutUint32(idb[4:], 0xFFFF) // snap length
+	if err := pw.writeBlock(blockTypeIDB, idb); err != nil {
+		return nil, err
+	}
+
+	return pw, pw.w.Flush()
+}
+
+// CreatePcapFile creates pcapng file and returns PcapWriter for it
+func CreatePcapFile(path string) (*PcapWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+	pw, err := NewPcapWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write capture header: %w", err)
+	}
+	return pw, nil
+}
+
+func (pw *PcapWriter) writeBlock(blockType uint32, body []byte) error {
+	length := uint32(12 + len(body))
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header[0:], blockType)
+	binary.LittleEndian.PutUint32(header[4:], length)
+	trailer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(trailer, length)
+
+	for _, b := range [][]byte{header, body, trailer} {
+		if _, err := pw.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record writes a datagram as an Enhanced Packet Block
+func (pw *PcapWriter) Record(p Packet) error {
+	src, dst := p.Local, p.Peer
+	if p.Direction == Inbound {
+		src, dst = p.Peer, p.Local
+	}
+	pkt, err := encapsulate(src, dst, p.Data)
+	if err != nil {
+		return err
+	}
+	padded := (len(pkt) + 3) &^ 3
+
+	body := make([]byte, 20+padded+12)
+	ts := uint64(p.Timestamp.UnixNano() / int64(time.Microsecond))
+	binary.LittleEndian.PutUint32(body[0:], 0) // interface ID
+	binary.LittleEndian.PutUint32(body[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(pkt)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(len(pkt)))
+	copy(body[20:], pkt)
+	opts := body[20+padded:]
+	binary.LittleEndian.PutUint16(opts[0:], optEPBFlags)
+	binary.LittleEndian.PutUint16(opts[2:], 4)
+	binary.LittleEndian.PutUint32(opts[4:], uint32(p.Direction)&0x3)
+	binary.LittleEndian.PutUint16(opts[8:], optEndOfOpt)
+	binary.LittleEndian.PutUint16(opts[10:], 0)
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if err := pw.writeBlock(blockTypeEPB, body); err != nil {
+		return err
+	}
+	return pw.w.Flush()
+}
+
+// Close flushes and closes underlying writer
+func (pw *PcapWriter) Close() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	err := pw.w.Flush()
+	if pw.closer != nil {
+		if cerr := pw.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// splitAddress returns IPv4 address and port of "ip" or "ip:port"
+func splitAddress(addr string) (net.IP, int, error) {
+	if addr == "" {
+		return net.IPv4zero.To4(), echonetLitePort, nil
+	}
+	host, port := addr, echonetLitePort
+	if h, p, err := net.SplitHostPort(addr); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid port [%s]", addr)
+		}
+		host, port = h, n
+	}
+	ip := net.ParseIP(host).To4()
+	if ip == nil {
+		return nil, 0, fmt.Errorf("not an IPv4 address [%s]", addr)
+	}
+	return ip, port, nil
+}
+
+// encapsulate builds IPv4/UDP packet which carries data
+func encapsulate(src, dst string, data []byte) ([]byte, error) {
+	srcIP, srcPort, err := splitAddress(src)
+	if err != nil {
+		return nil, err
+	}
+	dstIP, dstPort, err := splitAddress(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	pkt := make([]byte, ipv4HeaderLen+udpHeaderLen+len(data))
+	ip := pkt[:ipv4HeaderLen]
+	ip[0] = 0x45 // version 4, header length 5
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(pkt)))
+	ip[8] = 64 // TTL
+	ip[9] = 17 // UDP
+	copy(ip[12:16], srcIP)
+	copy(ip[16:20], dstIP)
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+
+	udp := pkt[ipv4HeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:], uint16(srcPort))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dstPort))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderLen+len(data)))
+	copy(udp[udpHeaderLen:], data)
+	return pkt, nil
+}
+
+func checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xFFFF {
+		sum = (sum >> 16) + (sum & 0xFFFF)
+	}
+	return ^uint16(sum)
+}
+
+// decapsulate returns source, destination and payload of IPv4/UDP packet
+func decapsulate(pkt []byte) (string, string, []byte, error) {
+	if len(pkt) < ipv4HeaderLen || pkt[0]>>4 != 4 {
+		return "", "", nil, fmt.Errorf("not an IPv4 packet")
+	}
+	ihl := int(pkt[0]&0x0F) * 4
+	if ihl < ipv4HeaderLen || len(pkt) < ihl+udpHeaderLen {
+		return "", "", nil, fmt.Errorf("packet is too short:%d", len(pkt))
+	}
+	if pkt[9] != 17 {
+		return "", "", nil, fmt.Errorf("not an UDP packet")
+	}
+	srcIP := net.IP(pkt[12:16]).String()
+	dstIP := net.IP(pkt[16:20]).String()
+	udp := pkt[ihl:]
+	srcPort := binary.BigEndian.Uint16(udp[0:])
+	dstPort := binary.BigEndian.Uint16(udp[2:])
+	length := int(binary.BigEndian.Uint16(udp[4:]))
+	if length < udpHeaderLen || length > len(udp) {
+		return "", "", nil, fmt.Errorf("invalid UDP length:%d", length)
+	}
+	src := net.JoinHostPort(srcIP, strconv.Itoa(int(srcPort)))
+	dst := net.JoinHostPort(dstIP, strconv.Itoa(int(dstPort)))
+	return src, dst, append([]byte{}, udp[udpHeaderLen:length]...), nil
+}
+
+// PcapReader reads datagrams from pcapng file written by PcapWriter or other tools
+type PcapReader struct {
+	r         *bufio.Reader
+	order     binary.ByteOrder
+	linkTypes []uint16
+}
+
+// NewPcapReader returns PcapReader
+func NewPcapReader(r io.Reader) *PcapReader {
+	return &PcapReader{r: bufio.NewReader(r), order: binary.LittleEndian}
+}
+
+// ReadPcapFile reads all datagrams from pcapng file
+func ReadPcapFile(path string) ([]Packet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	defer f.Close()
+
+	packets := []Packet{}
+	pr := NewPcapReader(f)
+	for {
+		p, err := pr.Next()
+		if err == io.EOF {
+			return packets, nil
+		}
+		if err != nil {
+			return packets, err
+		}
+		packets = append(packets, p)
+	}
+}
+
+// Next returns next datagram. It returns io.EOF at end of file.
+func (pr *PcapReader) Next() (Packet, error) {
+	for {
+		blockType, body, err := pr.readBlock()
+		if err != nil {
+			return Packet{}, err
+		}
+
+		switch blockType {
+		case blockTypeSHB:
+			pr.linkTypes = nil
+		case blockTypeIDB:
+			if len(body) < 8 {
+				return Packet{}, fmt.Errorf("invalid interface description block")
+			}
+			pr.linkTypes = append(pr.linkTypes, pr.order.Uint16(body[0:]))
+		case blockTypeEPB:
+			return pr.parseEPB(body)
+		}
+	}
+}
+
+func (pr *PcapReader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(pr.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("truncated block header")
+		}
+		return 0, nil, err
+	}
+
+	blockType := pr.order.Uint32(header[0:])
+	if blockType == blockTypeSHB {
+		// Byte order is determined by each section header
+		magic := make([]byte, 4)
+		if _, err := io.ReadFull(pr.r, magic); err != nil {
+			return 0, nil, fmt.Errorf("truncated section header: %w", err)
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == byteOrderMagic:
+			pr.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == byteOrderMagic:
+			pr.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("invalid byte order magic %x", magic)
+		}
+		length := pr.order.Uint32(header[4:])
+		if length < 16 || length%4 != 0 {
+			return 0, nil, fmt.Errorf("invalid block length:%d", length)
+		}
+		rest := make([]byte, length-16)
+		if _, err := io.ReadFull(pr.r, rest); err != nil {
+			return 0, nil, fmt.Errorf("truncated section header: %w", err)
+		}
+		return blockTypeSHB, nil, pr.skip(4)
+	}
+
+	length := pr.order.Uint32(header[4:])
+	if length < 12 || length%4 != 0 {
+		return 0, nil, fmt.Errorf("invalid block length:%d", length)
+	}
+	body := make([]byte, length-12)
+	if _, err := io.ReadFull(pr.r, body); err != nil {
+		return 0, nil, fmt.Errorf("truncated block: %w", err)
+	}
+	return blockType, body, pr.skip(4)
+}
+
+func (pr *PcapReader) skip(n int) error {
+	if _, err := io.CopyN(ioutil.Discard, pr.r, int64(n)); err != nil {
+		return fmt.Errorf("truncated block trailer: %w", err)
+	}
+	return nil
+}
+
+func (pr *PcapReader) parseEPB(body []byte) (Packet, error) {
+	if len(body) < 20 {
+		return Packet{}, fmt.Errorf("invalid enhanced packet block")
+	}
+	ifID := int(pr.order.Uint32(body[0:]))
+	if ifID >= len(pr.linkTypes) {
+		return Packet{}, fmt.Errorf("unknown interface:%d", ifID)
+	}
+	if lt := pr.linkTypes[ifID]; lt != linkTypeIPv4 && lt != linkTypeRaw {
+		return Packet{}, fmt.Errorf("unsupported link type:%d", lt)
+	}
+	ts := uint64(pr.order.Uint32(body[4:]))<<32 | uint64(pr.order.Uint32(body[8:]))
+	capLen := int(pr.order.Uint32(body[12:]))
+	padded := (capLen + 3) &^ 3
+	if len(body) < 20+padded {
+		return Packet{}, fmt.Errorf("invalid captured length:%d", capLen)
+	}
+
+	src, dst, data, err := decapsulate(body[20 : 20+capLen])
+	if err != nil {
+		return Packet{}, err
+	}
+
+	p := Packet{
+		Timestamp: time.Unix(0, int64(ts)*int64(time.Microsecond)),
+		Direction: Inbound,
+		Peer:      src,
+		Local:     dst,
+		Data:      data,
+	}
+
+	opts := body[20+padded:]
+	for len(opts) >= 4 {
+		code := pr.order.Uint16(opts[0:])
+		length := int(pr.order.Uint16(opts[2:]))
+		if code == optEndOfOpt || len(opts) < 4+length {
+			break
+		}
+		if code == optEPBFlags && length == 4 && Direction(pr.order.Uint32(opts[4:])&0x3) == Outbound {
+			p.Direction = Outbound
+			p.Peer, p.Local = dst, src
+		}
+		opts = opts[4+(length+3)&^3:]
+	}
+	return p, nil
+}
@@ -0,0 +1,129 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPcap_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2021, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	packets := []Packet{
+		{
+			Timestamp: ts,
+			Direction: Outbound,
+			Peer:      "224.0.23.0:3610",
+			Local:     "0.0.0.0:3610",
+			Data:      []byte{0x10, 0x81, 0x00, 0x01, 0x05, 0xff, 0x01, 0x0e, 0xf0, 0x01, 0x63, 0x01, 0xd5, 0x00},
+		},
+		{
+			Timestamp: ts.Add(time.Second),
+			Direction: Inbound,
+			Peer:      "192.168.1.10:3610",
+			Local:     "224.0.23.0:3610",
+			Data:      []byte{0x10, 0x81, 0x00, 0x01, 0x0e, 0xf0, 0x01, 0x05, 0xff, 0x01, 0x73, 0x01, 0xd5, 0x04, 0x01, 0x01, 0x30, 0x01},
+		},
+		{
+			Timestamp: ts.Add(2 * time.Second),
+			Direction: Inbound,
+			Peer:      "192.168.1.11:49322",
+			Local:     "0.0.0.0:3610",
+			Data:      []byte{0x10, 0x81, 0x00, 0x02},
+		},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range packets {
+		if err := w.Record(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewPcapReader(&buf)
+	for i, want := range packets {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("packet %d: %s", i, err)
+		}
+		if !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("packet %d: timestamp differs: want:%s got:%s", i, want.Timestamp, got.Timestamp)
+		}
+		got.Timestamp = want.Timestamp
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("packet %d differs: (-want +got)\n%s", i, diff)
+		}
+	}
+	if _, err := r.Next(); err == nil {
+		t.Errorf("expected EOF")
+	}
+}
+
+func Test_encapsulate(t *testing.T) {
+	t.Parallel()
+
+	got, err := encapsulate("192.168.1.10", "224.0.23.0", []byte{0x10, 0x81})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		0x45, 0x00, 0x00, 0x1e, 0x00, 0x00, 0x00, 0x00, 0x40, 0x11, 0xc2, 0x1c,
+		0xc0, 0xa8, 0x01, 0x0a, 0xe0, 0x00, 0x17, 0x00,
+		0x0e, 0x1a, 0x0e, 0x1a, 0x00, 0x0a, 0x00, 0x00,
+		0x10, 0x81,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if checksum(got[:ipv4HeaderLen]) != 0 {
+		t.Errorf("invalid IPv4 header checksum")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	packets := []Packet{
+		{Direction: Outbound, Peer: "224.0.23.0", Data: []byte{0x01}},
+		{Direction: Inbound, Peer: "192.168.1.10:3610", Local: "224.0.23.0:3610", Data: []byte{0x02}},
+		{Direction: Inbound, Peer: "192.168.1.11:3610", Local: "0.0.0.0:3610", Data: []byte{0x03}},
+	}
+	r := NewReplay(packets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collect := func(ch <-chan ReceiveResult) []ReceiveResult {
+		results := []ReceiveResult{}
+		for res := range ch {
+			results = append(results, res)
+		}
+		return results
+	}
+
+	got := collect(r.Multicast().Start(ctx, "224.0.23.0", ":3610"))
+	want := []ReceiveResult{{Data: []byte{0x02}, Address: "192.168.1.10"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("multicast differs: (-want +got)\n%s", diff)
+	}
+
+	got = collect(r.Unicast().Start(ctx, ":3610"))
+	want = []ReceiveResult{{Data: []byte{0x03}, Address: "192.168.1.11:3610"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unicast differs: (-want +got)\n%s", diff)
+	}
+
+	r.Send([]byte{0x04})
+	if diff := cmp.Diff([][]byte{{0x04}}, r.Sent()); diff != "" {
+		t.Errorf("sent differs: (-want +got)\n%s", diff)
+	}
+}
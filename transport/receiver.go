@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxDatagramSize is size of buffers to read datagrams into
+	maxDatagramSize = 1500
+	// defaultQueueSize is default capacity of result channels
+	defaultQueueSize = 16
+	// errorBackoff is time to wait after a read error not to spin on persistent errors
+	errorBackoff = 100 * time.Millisecond
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, maxDatagramSize)
+		return &b
+	},
+}
+
+// ReceiverStats is a snapshot of receiver counters
+type ReceiverStats struct {
+	Packets uint64 // number of received datagrams
+	Bytes   uint64 // total size of received datagrams
+	Drops   uint64 // number of datagrams dropped because the consumer was slow
+}
+
+// counters counts received and dropped datagrams
+type counters struct {
+	packets uint64
+	bytes   uint64
+	drops   uint64
+}
+
+// Stats returns current counters
+func (c *counters) Stats() ReceiverStats {
+	return ReceiverStats{
+		Packets: atomic.LoadUint64(&c.packets),
+		Bytes:   atomic.LoadUint64(&c.bytes),
+		Drops:   atomic.LoadUint64(&c.drops),
+	}
+}
+
+func queueSize(size int) int {
+	if size <= 0 {
+		return defaultQueueSize
+	}
+	return size
+}
+
+// report sends an error result unless ctx is done
+func report(ctx context.Context, results chan<- ReceiveResult, err error) {
+	select {
+	case results <- ReceiveResult{Err: err}:
+	case <-ctx.Done():
+	}
+}
+
+// isClosed returns true if err is returned from closed connection.
+// net.ErrClosed is not available before Go 1.16.
+func isClosed(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// receive reads datagrams from conn until ctx is done or conn is closed.
+// Other read errors are reported and reading continues. conn is closed when it returns.
+func receive(ctx context.Context, conn *net.UDPConn, results chan<- ReceiveResult, c *counters, addrString func(*net.UDPAddr) string) {
+	// Closing conn unblocks ReadFromUDP when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Println("ctx.Done")
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	for {
+		bp := bufferPool.Get().(*[]byte)
+		length, remoteAddress, err := conn.ReadFromUDP(*bp)
+		if err != nil {
+			bufferPool.Put(bp)
+			if ctx.Err() != nil || isClosed(err) {
+				return
+			}
+			report(ctx, results, err)
+			select {
+			case <-time.After(errorBackoff):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		data := make([]byte, length)
+		copy(data, (*bp)[:length])
+		bufferPool.Put(bp)
+
+		atomic.AddUint64(&c.packets, 1)
+		atomic.AddUint64(&c.bytes, uint64(length))
+
+		select {
+		case results <- ReceiveResult{Data: data, Address: addrString(remoteAddress)}:
+		default:
+			drops := atomic.AddUint64(&c.drops, 1)
+			log.Printf("receive queue is full, dropped %d bytes from %s (total drops: %d)", length, remoteAddress, drops)
+		}
+	}
+}
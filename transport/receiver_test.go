@@ -0,0 +1,170 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func listenLoopback(t *testing.T) (*net.UDPConn, *net.UDPConn) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, sender
+}
+
+func waitStats(t *testing.T, c *counters, packets uint64) ReceiverStats {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if s := c.Stats(); s.Packets >= packets {
+			return s
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d packets", packets)
+	return ReceiverStats{}
+}
+
+func Test_receive(t *testing.T) {
+	t.Parallel()
+
+	conn, sender := listenLoopback(t)
+	defer sender.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &counters{}
+	results := make(chan ReceiveResult, 2)
+	go func() {
+		defer close(results)
+		receive(ctx, conn, results, c, func(addr *net.UDPAddr) string { return "peer" })
+	}()
+
+	inputs := [][]byte{{0x10, 0x81, 0x00, 0x01}, {0x10, 0x81}}
+	for _, in := range inputs {
+		if _, err := sender.Write(in); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range inputs {
+		got := <-results
+		if diff := cmp.Diff(ReceiveResult{Data: want, Address: "peer"}, got); diff != "" {
+			t.Errorf("ReceiveResult differs: (-want +got)\n%s", diff)
+		}
+	}
+
+	want := ReceiverStats{Packets: 2, Bytes: 6, Drops: 0}
+	if diff := cmp.Diff(want, waitStats(t, c, 2)); diff != "" {
+		t.Errorf("ReceiverStats differs: (-want +got)\n%s", diff)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Errorf("unexpected result after cancel")
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("receive did not return after cancel")
+	}
+}
+
+func Test_receive_Error(t *testing.T) {
+	t.Parallel()
+
+	conn, sender := listenLoopback(t)
+	defer sender.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 期限切れで読み出しに失敗させる
+	if err := conn.SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan ReceiveResult, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		receive(ctx, conn, results, &counters{}, func(addr *net.UDPAddr) string { return "peer" })
+	}()
+
+	select {
+	case r := <-results:
+		if r.Err == nil {
+			t.Fatalf("error expected: %+v", r)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("error is not reported")
+	}
+
+	// エラーの後も受信を続ける
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sender.Write([]byte{0x10, 0x81}); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(3 * time.Second)
+	for received := false; !received; {
+		select {
+		case r := <-results:
+			received = r.Err == nil
+		case <-done:
+			t.Fatal("receive returned after error")
+		case <-timeout:
+			t.Fatal("datagram is not received after error")
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Error("receive did not return after cancel")
+	}
+}
+
+func Test_receive_Drop(t *testing.T) {
+	t.Parallel()
+
+	conn, sender := listenLoopback(t)
+	defer sender.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &counters{}
+	results := make(chan ReceiveResult, 1)
+	go receive(ctx, conn, results, c, func(addr *net.UDPAddr) string { return addr.String() })
+
+	for i := 0; i < 3; i++ {
+		if _, err := sender.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := ReceiverStats{Packets: 3, Bytes: 3, Drops: 2}
+	if diff := cmp.Diff(want, waitStats(t, c, 3)); diff != "" {
+		t.Errorf("ReceiverStats differs: (-want +got)\n%s", diff)
+	}
+
+	got := <-results
+	if diff := cmp.Diff([]byte{0x00}, got.Data); diff != "" {
+		t.Errorf("Data differs: (-want +got)\n%s", diff)
+	}
+}
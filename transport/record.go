@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RecordingMulticastReceiver is MulticastReceiver which records received datagrams
+type RecordingMulticastReceiver struct {
+	Receiver MulticastReceiver
+	Recorder Recorder
+}
+
+// Start starts Receiver and records its results
+func (r *RecordingMulticastReceiver) Start(ctx context.Context, ip, port string) <-chan ReceiveResult {
+	return forward(ctx, r.Receiver.Start(ctx, ip, port), r.Recorder, ip)
+}
+
+// RecordingUnicastReceiver is UnicastReceiver which records received datagrams
+type RecordingUnicastReceiver struct {
+	Receiver UnicastReceiver
+	Recorder Recorder
+}
+
+// Start starts Receiver and records its results
+func (r *RecordingUnicastReceiver) Start(ctx context.Context, port string) <-chan ReceiveResult {
+	return forward(ctx, r.Receiver.Start(ctx, port), r.Recorder, "")
+}
+
+func forward(ctx context.Context, in <-chan ReceiveResult, rec Recorder, local string) <-chan ReceiveResult {
+	out := make(chan ReceiveResult, cap(in))
+	go func() {
+		defer close(out)
+		for result := range in {
+			if result.Err == nil {
+				err := rec.Record(Packet{
+					Timestamp: time.Now(),
+					Direction: Inbound,
+					Peer:      result.Address,
+					Local:     local,
+					Data:      result.Data,
+				})
+				if err != nil {
+					log.Println("failed to record:", err)
+				}
+			}
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// RecordingMulticastSender is MulticastSender which records sent datagrams
+type RecordingMulticastSender struct {
+	Sender   MulticastSender
+	Recorder Recorder
+	Address  string // destination address written to records
+}
+
+// Send records and sends data
+func (s *RecordingMulticastSender) Send(data []byte) {
+	err := s.Recorder.Record(Packet{
+		Timestamp: time.Now(),
+		Direction: Outbound,
+		Peer:      s.Address,
+		Data:      data,
+	})
+	if err != nil {
+		log.Println("failed to record:", err)
+	}
+	s.Sender.Send(data)
+}
+
+// Close closes Sender
+func (s *RecordingMulticastSender) Close() {
+	s.Sender.Close()
+}
+
+// RecordingUnicastSender is UnicastSender which records sent datagrams
+type RecordingUnicastSender struct {
+	Sender   UnicastSender
+	Recorder Recorder
+	Port     string // destination port written to records such as ":3610"
+}
+
+// SendTo records and sends data to ip
+func (s *RecordingUnicastSender) SendTo(data []byte, ip string) error {
+	err := s.Recorder.Record(Packet{
+		Timestamp: time.Now(),
+		Direction: Outbound,
+		Peer:      ip + s.Port,
+		Data:      data,
+	})
+	if err != nil {
+		log.Println("failed to record:", err)
+	}
+	return s.Sender.SendTo(data, ip)
+}
+
+// Close closes Sender
+func (s *RecordingUnicastSender) Close() {
+	s.Sender.Close()
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Replay replays captured datagrams for regression tests.
+// Inbound datagrams are delivered by receivers returned from Multicast and Unicast,
+// and Replay itself works as MulticastSender which keeps sent data.
+type Replay struct {
+	packets []Packet
+	// Realtime keeps intervals between captured datagrams if true
+	Realtime bool
+
+	mu   sync.Mutex
+	sent [][]byte
+}
+
+// NewReplay returns Replay for packets
+func NewReplay(packets []Packet) *Replay {
+	return &Replay{packets: packets}
+}
+
+// LoadReplay returns Replay for packets in pcapng file
+func LoadReplay(path string) (*Replay, error) {
+	packets, err := ReadPcapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplay(packets), nil
+}
+
+// Multicast returns MulticastReceiver which delivers datagrams sent to multicast address
+func (r *Replay) Multicast() MulticastReceiver {
+	return replayMulticastReceiver{r}
+}
+
+// Unicast returns UnicastReceiver which delivers datagrams sent to unicast address
+func (r *Replay) Unicast() UnicastReceiver {
+	return replayUnicastReceiver{r}
+}
+
+// Send keeps data
+func (r *Replay) Send(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, append([]byte{}, data...))
+}
+
+// Close does nothing
+func (r *Replay) Close() {
+}
+
+// Sent returns data passed to Send
+func (r *Replay) Sent() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte{}, r.sent...)
+}
+
+func (r *Replay) start(ctx context.Context, multicast bool) <-chan ReceiveResult {
+	results := make(chan ReceiveResult, defaultQueueSize)
+	go func() {
+		defer close(results)
+		var last time.Time
+		for _, p := range r.packets {
+			if p.Direction != Inbound || isMulticast(p.Local) != multicast {
+				continue
+			}
+			if r.Realtime && !last.IsZero() && p.Timestamp.After(last) {
+				select {
+				case <-time.After(p.Timestamp.Sub(last)):
+				case <-ctx.Done():
+					return
+				}
+			}
+			last = p.Timestamp
+
+			addr := p.Peer
+			if multicast {
+				addr = host(p.Peer)
+			}
+			select {
+			case results <- ReceiveResult{Data: p.Data, Address: addr}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
+
+func host(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return addr
+}
+
+func isMulticast(addr string) bool {
+	ip := net.ParseIP(host(addr))
+	return ip != nil && ip.IsMulticast()
+}
+
+type replayMulticastReceiver struct {
+	r *Replay
+}
+
+func (m replayMulticastReceiver) Start(ctx context.Context, ip, port string) <-chan ReceiveResult {
+	return m.r.start(ctx, true)
+}
+
+type replayUnicastReceiver struct {
+	r *Replay
+}
+
+func (u replayUnicastReceiver) Start(ctx context.Context, port string) <-chan ReceiveResult {
+	return u.r.start(ctx, false)
+}
@@ -23,11 +23,28 @@ type SerialImpl struct {
 	reader *bufio.Reader
 }
 
+// DefaultBaudRate is baud rate of serial connection if not specified
+const DefaultBaudRate = 115200
+
 // NewSerialImpl opens default serial connection and returns SerialImpl
 func NewSerialImpl(addr string) *SerialImpl {
+	s, err := OpenSerial(addr)
+	if err != nil {
+		log.Fatal("Faild to open serial:", err)
+	}
+	return s
+}
+
+// OpenSerial opens default serial connection and returns SerialImpl or error
+func OpenSerial(addr string) (*SerialImpl, error) {
+	return OpenSerialWithBaudRate(addr, DefaultBaudRate)
+}
+
+// OpenSerialWithBaudRate opens serial connection at baudRate and returns SerialImpl or error
+func OpenSerialWithBaudRate(addr string, baudRate int) (*SerialImpl, error) {
 	config := serial.Config{
 		Address:  addr,
-		BaudRate: 115200,
+		BaudRate: baudRate,
 		DataBits: 8,
 		StopBits: 1,
 		Parity:   "N",
@@ -36,12 +53,12 @@ func NewSerialImpl(addr string) *SerialImpl {
 
 	port, err := serial.Open(&config)
 	if err != nil {
-		log.Fatal("Faild to open serial:", err)
+		return nil, err
 	}
 
 	reader := bufio.NewReaderSize(port, 4096)
 
-	return &SerialImpl{port: port, reader: reader}
+	return &SerialImpl{port: port, reader: reader}, nil
 }
 
 // Send sends data
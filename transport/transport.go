@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"time"
 )
 
 // ReceiveResult is response data
@@ -28,6 +27,12 @@ type MulticastSender interface {
 	Close()
 }
 
+// UnicastSender is unicast sender
+type UnicastSender interface {
+	SendTo(data []byte, ip string) error
+	Close()
+}
+
 // UnicastReceiver is unicast receiver
 type UnicastReceiver interface {
 	Start(ctx context.Context, port string) <-chan ReceiveResult
@@ -35,11 +40,15 @@ type UnicastReceiver interface {
 
 // UDPMulticastReceiver is udp multicast receiver
 type UDPMulticastReceiver struct {
+	counters // must be first for 64-bit alignment on 32-bit platforms
+
+	// QueueSize is capacity of the result channel. Default is used if zero.
+	QueueSize int
 }
 
 // Start starts to receive
 func (r *UDPMulticastReceiver) Start(ctx context.Context, ip, port string) <-chan ReceiveResult {
-	results := make(chan ReceiveResult, 5)
+	results := make(chan ReceiveResult, queueSize(r.QueueSize))
 	log.Println("Start to listen multicast udp ", ip, port)
 
 	go func() {
@@ -47,44 +56,18 @@ func (r *UDPMulticastReceiver) Start(ctx context.Context, ip, port string) <-cha
 		address, err := net.ResolveUDPAddr("udp", ip+port)
 		log.Println("resolved:", address)
 		if err != nil {
-			results <- ReceiveResult{Err: fmt.Errorf("Error: [%s]", err)}
+			report(ctx, results, fmt.Errorf("Error: [%s]", err))
 			return
 		}
 		conn, err := net.ListenMulticastUDP("udp", nil, address)
 		if err != nil {
-			results <- ReceiveResult{Err: fmt.Errorf("Error: [%s]", err)}
+			report(ctx, results, fmt.Errorf("Error: [%s]", err))
 			return
 		}
-		defer conn.Close()
-		buffer := make([]byte, 1500)
-
-		for {
-			fmt.Printf(".")
-			conn.SetDeadline(time.Now().Add(1 * time.Second))
-			length, remoteAddress, err := conn.ReadFromUDP(buffer)
-			if err != nil {
-				err, ok := err.(net.Error)
-				if !ok || !err.Timeout() {
-					results <- ReceiveResult{Err: fmt.Errorf("Error: [%s]", err)}
-				}
-			} else if length > 0 {
-				fmt.Println()
-				// Need copy because buffer will be cleared and reuse
-				data := append([]byte{}, buffer[:length]...)
-				results <- ReceiveResult{Data: data, Address: remoteAddress.IP.String(), Err: nil}
-			}
-			select {
-			case <-ctx.Done():
-				log.Println("ctx.Done")
-				return
-			default:
-				//log.Println("recv: ", length)
-			}
-
-			for i := range buffer {
-				buffer[i] = 0
-			}
-		}
+
+		receive(ctx, conn, results, &r.counters, func(addr *net.UDPAddr) string {
+			return addr.IP.String()
+		})
 	}()
 	return results
 }
@@ -117,59 +100,73 @@ func (ums *UDPMulticastSender) Send(data []byte) {
 	//log.Println("written:", length)
 }
 
+// UDPUnicastSender is udp unicast sender
+type UDPUnicastSender struct {
+	conn *net.UDPConn
+	port string
+}
+
+// NewUDPUnicastSender creates UDPUnicastSender instance which sends to port
+func NewUDPUnicastSender(port string) (*UDPUnicastSender, error) {
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("Write conn error: [%s]", err)
+	}
+	return &UDPUnicastSender{conn: conn, port: port}, nil
+}
+
+// Close closes connection
+func (us *UDPUnicastSender) Close() {
+	us.conn.Close()
+}
+
+// SendTo sends data to ip
+func (us *UDPUnicastSender) SendTo(data []byte, ip string) error {
+	address, err := net.ResolveUDPAddr("udp", ip+us.port)
+	if err != nil {
+		return fmt.Errorf("failed to resolve [%s]: %w", ip, err)
+	}
+	_, err = us.conn.WriteToUDP(data, address)
+	return err
+}
+
 // UDPUnicastReceiver is udp unicast receiver
 type UDPUnicastReceiver struct {
+	counters // must be first for 64-bit alignment on 32-bit platforms
+
+	// QueueSize is capacity of the result channel. Default is used if zero.
+	QueueSize int
 }
 
 // Start starts to receive
 func (r *UDPUnicastReceiver) Start(ctx context.Context, port string) <-chan ReceiveResult {
-	results := make(chan ReceiveResult, 5)
+	results := make(chan ReceiveResult, queueSize(r.QueueSize))
 	log.Println("Start to listen unicast udp ", port)
 
 	go func() {
+		defer close(results)
 		address, err := net.ResolveUDPAddr("udp", port)
 		log.Println("resolved:", address)
 		if err != nil {
-			results <- ReceiveResult{Err: fmt.Errorf("Error: [%s]", err)}
+			report(ctx, results, fmt.Errorf("Error: [%s]", err))
 			return
 		}
 		conn, err := net.ListenUDP("udp", address)
 		if err != nil {
-			results <- ReceiveResult{Err: fmt.Errorf("Unicast Error: [%s]", err)}
+			report(ctx, results, fmt.Errorf("Unicast Error: [%s]", err))
 			return
 		}
-		defer conn.Close()
 
 		err = resolveSocketOption(conn)
 		if err != nil {
-			results <- ReceiveResult{Err: fmt.Errorf("unicast error: %w", err)}
+			conn.Close()
+			report(ctx, results, fmt.Errorf("unicast error: %w", err))
 			return
 		}
 
-		buffer := make([]byte, 1024)
-		for {
-			length, remoteAddress, err := conn.ReadFromUDP(buffer)
-			if err != nil {
-				log.Println("Unicast Error:", err)
-			} else if length > 0 {
-				fmt.Println()
-				// Need copy because buffer will be cleared and reuse
-				data := append([]byte{}, buffer[:length]...)
-				results <- ReceiveResult{Data: data, Address: remoteAddress.String(), Err: nil}
-			}
-
-			select {
-			case <-ctx.Done():
-				log.Println("ctx.Done")
-				return
-			default:
-			}
-
-			for i := range buffer {
-				buffer[i] = 0
-			}
-
-		}
+		receive(ctx, conn, results, &r.counters, func(addr *net.UDPAddr) string {
+			return addr.String()
+		})
 	}()
 	return results
 }
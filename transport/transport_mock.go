@@ -6,34 +6,35 @@ package transport
 
 import (
 	context "context"
-	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
 )
 
-// MockMulticastReceiver is a mock of MulticastReceiver interface
+// MockMulticastReceiver is a mock of MulticastReceiver interface.
 type MockMulticastReceiver struct {
 	ctrl     *gomock.Controller
 	recorder *MockMulticastReceiverMockRecorder
 }
 
-// MockMulticastReceiverMockRecorder is the mock recorder for MockMulticastReceiver
+// MockMulticastReceiverMockRecorder is the mock recorder for MockMulticastReceiver.
 type MockMulticastReceiverMockRecorder struct {
 	mock *MockMulticastReceiver
 }
 
-// NewMockMulticastReceiver creates a new mock instance
+// NewMockMulticastReceiver creates a new mock instance.
 func NewMockMulticastReceiver(ctrl *gomock.Controller) *MockMulticastReceiver {
 	mock := &MockMulticastReceiver{ctrl: ctrl}
 	mock.recorder = &MockMulticastReceiverMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockMulticastReceiver) EXPECT() *MockMulticastReceiverMockRecorder {
 	return m.recorder
 }
 
-// Start mocks base method
+// Start mocks base method.
 func (m *MockMulticastReceiver) Start(ctx context.Context, ip, port string) <-chan ReceiveResult {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Start", ctx, ip, port)
@@ -41,83 +42,132 @@ func (m *MockMulticastReceiver) Start(ctx context.Context, ip, port string) <-ch
 	return ret0
 }
 
-// Start indicates an expected call of Start
+// Start indicates an expected call of Start.
 func (mr *MockMulticastReceiverMockRecorder) Start(ctx, ip, port interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockMulticastReceiver)(nil).Start), ctx, ip, port)
 }
 
-// MockMulticastSender is a mock of MulticastSender interface
+// MockMulticastSender is a mock of MulticastSender interface.
 type MockMulticastSender struct {
 	ctrl     *gomock.Controller
 	recorder *MockMulticastSenderMockRecorder
 }
 
-// MockMulticastSenderMockRecorder is the mock recorder for MockMulticastSender
+// MockMulticastSenderMockRecorder is the mock recorder for MockMulticastSender.
 type MockMulticastSenderMockRecorder struct {
 	mock *MockMulticastSender
 }
 
-// NewMockMulticastSender creates a new mock instance
+// NewMockMulticastSender creates a new mock instance.
 func NewMockMulticastSender(ctrl *gomock.Controller) *MockMulticastSender {
 	mock := &MockMulticastSender{ctrl: ctrl}
 	mock.recorder = &MockMulticastSenderMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockMulticastSender) EXPECT() *MockMulticastSenderMockRecorder {
 	return m.recorder
 }
 
-// Send mocks base method
+// Close mocks base method.
+func (m *MockMulticastSender) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockMulticastSenderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockMulticastSender)(nil).Close))
+}
+
+// Send mocks base method.
 func (m *MockMulticastSender) Send(data []byte) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "Send", data)
 }
 
-// Send indicates an expected call of Send
+// Send indicates an expected call of Send.
 func (mr *MockMulticastSenderMockRecorder) Send(data interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockMulticastSender)(nil).Send), data)
 }
 
-// Close mocks base method
-func (m *MockMulticastSender) Close() {
+// MockUnicastSender is a mock of UnicastSender interface.
+type MockUnicastSender struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnicastSenderMockRecorder
+}
+
+// MockUnicastSenderMockRecorder is the mock recorder for MockUnicastSender.
+type MockUnicastSenderMockRecorder struct {
+	mock *MockUnicastSender
+}
+
+// NewMockUnicastSender creates a new mock instance.
+func NewMockUnicastSender(ctrl *gomock.Controller) *MockUnicastSender {
+	mock := &MockUnicastSender{ctrl: ctrl}
+	mock.recorder = &MockUnicastSenderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnicastSender) EXPECT() *MockUnicastSenderMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockUnicastSender) Close() {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "Close")
 }
 
-// Close indicates an expected call of Close
-func (mr *MockMulticastSenderMockRecorder) Close() *gomock.Call {
+// Close indicates an expected call of Close.
+func (mr *MockUnicastSenderMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockMulticastSender)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockUnicastSender)(nil).Close))
+}
+
+// SendTo mocks base method.
+func (m *MockUnicastSender) SendTo(data []byte, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendTo", data, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendTo indicates an expected call of SendTo.
+func (mr *MockUnicastSenderMockRecorder) SendTo(data, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendTo", reflect.TypeOf((*MockUnicastSender)(nil).SendTo), data, ip)
 }
 
-// MockUnicastReceiver is a mock of UnicastReceiver interface
+// MockUnicastReceiver is a mock of UnicastReceiver interface.
 type MockUnicastReceiver struct {
 	ctrl     *gomock.Controller
 	recorder *MockUnicastReceiverMockRecorder
 }
 
-// MockUnicastReceiverMockRecorder is the mock recorder for MockUnicastReceiver
+// MockUnicastReceiverMockRecorder is the mock recorder for MockUnicastReceiver.
 type MockUnicastReceiverMockRecorder struct {
 	mock *MockUnicastReceiver
 }
 
-// NewMockUnicastReceiver creates a new mock instance
+// NewMockUnicastReceiver creates a new mock instance.
 func NewMockUnicastReceiver(ctrl *gomock.Controller) *MockUnicastReceiver {
 	mock := &MockUnicastReceiver{ctrl: ctrl}
 	mock.recorder = &MockUnicastReceiverMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockUnicastReceiver) EXPECT() *MockUnicastReceiverMockRecorder {
 	return m.recorder
 }
 
-// Start mocks base method
+// Start mocks base method.
 func (m *MockUnicastReceiver) Start(ctx context.Context, port string) <-chan ReceiveResult {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Start", ctx, port)
@@ -125,7 +175,7 @@ func (m *MockUnicastReceiver) Start(ctx context.Context, port string) <-chan Rec
 	return ret0
 }
 
-// Start indicates an expected call of Start
+// Start indicates an expected call of Start.
 func (mr *MockUnicastReceiverMockRecorder) Start(ctx, port interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockUnicastReceiver)(nil).Start), ctx, port)
@@ -10,7 +10,7 @@ import (
 	"time"
 	"unicode"
 
-	"github.com/u-one/go-el-controller/transport"
+	"github.com/matsuu/go-el-controller/transport"
 )
 
 const (
@@ -32,6 +32,12 @@ type PanDesc struct {
 	IPV6Addr string
 	Channel  string
 	PanID    string
+	LQI      int // link quality indicator of the beacon
+}
+
+// RSSI returns received signal strength in dBm estimated from LQI
+func (d PanDesc) RSSI() float64 {
+	return 0.275*float64(d.LQI) - 104.27
 }
 
 // NewBP35C2Client returns BP35C2Client instance
@@ -41,6 +47,20 @@ func NewBP35C2Client(portaddr string) *BP35C2Client {
 	return &BP35C2Client{serial: s}
 }
 
+// OpenBP35C2Client opens serial port and returns BP35C2Client instance
+func OpenBP35C2Client(portaddr string) (*BP35C2Client, error) {
+	s, err := transport.OpenSerial(portaddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial [%s]: %w", portaddr, err)
+	}
+	return &BP35C2Client{serial: s}, nil
+}
+
+// NewBP35C2ClientWithSerial returns BP35C2Client communicating through s
+func NewBP35C2ClientWithSerial(s transport.Serial) *BP35C2Client {
+	return &BP35C2Client{serial: s}
+}
+
 // Close closees connection
 func (c *BP35C2Client) Close() {
 	if c.joined {
@@ -244,7 +264,17 @@ func (c BP35C2Client) receivePanDesc() (PanDesc, error) {
 		}
 		tokens = bytes.Split(line, []byte("Addr:"))
 		ed.Addr = string(bytes.Trim(tokens[1], "\r\n"))
-		c.recv() // LQI:CA
+		line, err = c.recv() // LQI:CA
+		if err != nil {
+			return PanDesc{}, fmt.Errorf("failed to get LQI [%s]", err)
+		}
+		tokens = bytes.Split(line, []byte("LQI:"))
+		if len(tokens) > 1 {
+			lqi, err := strconv.ParseUint(string(bytes.Trim(tokens[1], "\r\n")), 16, 8)
+			if err == nil {
+				ed.LQI = int(lqi)
+			}
+		}
 		c.recv() // Side:X
 		c.recv() // PairID:XXXXXXXX
 	}
@@ -348,6 +378,7 @@ func (c *BP35C2Client) Join(desc PanDesc) (bool, error) {
 				case 0x25:
 					log.Println("Join succeed")
 					c.joined = true
+					c.panDesc = desc
 					return true, nil
 				}
 			}
@@ -7,7 +7,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
-	"github.com/u-one/go-el-controller/transport"
+	"github.com/matsuu/go-el-controller/transport"
 )
 
 type resp struct {
@@ -371,6 +371,7 @@ func Test_receivePanDesc(t *testing.T) {
 		IPV6Addr: "",
 		Channel:  "21",
 		PanID:    "0002",
+		LQI:      0xCA,
 	}
 
 	c := &BP35C2Client{serial: m}
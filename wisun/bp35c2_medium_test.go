@@ -88,6 +88,7 @@ func Test_Medium_Scan(t *testing.T) {
 				IPV6Addr: "",
 				Channel:  "21",
 				PanID:    "8888",
+				LQI:      0xE1,
 			},
 		},
 	}
@@ -0,0 +1,140 @@
+package wisun
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/matsuu/go-el-controller/transport"
+)
+
+// Names of Wi-SUN modules.
+// ModuleAuto detects the module by DetectModule.
+const (
+	ModuleRL7023 = "rl7023"
+	ModuleBP35C2 = "bp35c2"
+	ModuleAuto   = "auto"
+)
+
+// maxResponseLines is limit of lines read until OK or FAIL in detection
+const maxResponseLines = 16
+
+// ModuleInfo is result of DetectModule
+type ModuleInfo struct {
+	Module  string // client implementation for the module, ModuleRL7023 or ModuleBP35C2
+	Version string // firmware version from SKVER
+	Addr64  string // MAC address from SKINFO
+	ASCII   bool   // true if ERXUDP data is in hex ASCII
+}
+
+// DetectModule probes the module connected to s and returns client implementation suitable for it.
+// RL7023 always shows ERXUDP data in hex ASCII and rejects ROPT,
+// while BP35C2 shows it in hex ASCII (ROPT 01) or binary (ROPT 00) according to WOPT.
+// Hex ASCII is handled by RL7023Client and binary is handled by BP35C2Client.
+func DetectModule(s transport.Serial) (ModuleInfo, error) {
+	info := ModuleInfo{}
+
+	lines, err := command(s, "SKVER")
+	if err != nil {
+		return info, fmt.Errorf("SKVER failed: %w", err)
+	}
+	for _, l := range lines {
+		if strings.HasPrefix(l, "EVER ") {
+			info.Version = strings.TrimPrefix(l, "EVER ")
+		}
+	}
+	if info.Version == "" {
+		return info, fmt.Errorf("SKVER failed: version not found in %q", lines)
+	}
+
+	// EINFO <IPADDR> <ADDR64> <CHANNEL> <PANID> <ADDR16>
+	lines, err = command(s, "SKINFO")
+	if err != nil {
+		log.Printf("SKINFO failed: %s", err)
+	}
+	for _, l := range lines {
+		if tokens := strings.Fields(l); len(tokens) >= 3 && tokens[0] == "EINFO" {
+			info.Addr64 = tokens[2]
+		}
+	}
+
+	// OK 00: binary, OK 01: hex ASCII
+	lines, err = command(s, "ROPT")
+	switch {
+	case err == nil && len(lines) > 0 && lines[len(lines)-1] == "OK 00":
+		info.Module = ModuleBP35C2
+	case err == nil && len(lines) > 0 && lines[len(lines)-1] == "OK 01":
+		info.Module = ModuleRL7023
+		info.ASCII = true
+	case err == nil || isCommandFailure(err):
+		// ROPTがなければ常にASCII表示
+		info.Module = ModuleRL7023
+		info.ASCII = true
+	default:
+		return info, fmt.Errorf("ROPT failed: %w", err)
+	}
+	log.Printf("detected module: %+v", info)
+	return info, nil
+}
+
+// commandFailure is returned by command if the module answers FAIL
+type commandFailure struct {
+	result string
+}
+
+func (e *commandFailure) Error() string {
+	return fmt.Sprintf("command failed [%s]", e.result)
+}
+
+func isCommandFailure(err error) bool {
+	_, ok := err.(*commandFailure)
+	return ok
+}
+
+// command sends cmd and returns response lines without echo back until OK, which is included as the last line
+func command(s transport.Serial, cmd string) ([]string, error) {
+	log.Printf("Send:%s", cmd)
+	if err := s.Send([]byte(cmd + "\r\n")); err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for i := 0; i < maxResponseLines; i++ {
+		r, err := s.Recv()
+		if err != nil {
+			return lines, err
+		}
+		line := string(bytes.TrimRight(r, "\r\n"))
+		log.Printf("Read:%s", line)
+		switch {
+		case line == cmd || line == "":
+			continue
+		case strings.HasPrefix(line, "FAIL"):
+			return lines, &commandFailure{result: line}
+		}
+		lines = append(lines, line)
+		if strings.HasPrefix(line, "OK") {
+			return lines, nil
+		}
+	}
+	return lines, fmt.Errorf("no result of %s", cmd)
+}
+
+// NewClient returns client of module communicating through s.
+// The module is detected by DetectModule if module is ModuleAuto.
+func NewClient(module string, s transport.Serial) (Client, error) {
+	if module == ModuleAuto {
+		info, err := DetectModule(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect module: %w", err)
+		}
+		module = info.Module
+	}
+	switch module {
+	case ModuleRL7023:
+		return NewRL7023ClientWithSerial(s), nil
+	case ModuleBP35C2:
+		return NewBP35C2ClientWithSerial(s), nil
+	}
+	return nil, fmt.Errorf("unknown module: %s", module)
+}
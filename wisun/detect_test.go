@@ -0,0 +1,118 @@
+package wisun
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/matsuu/go-el-controller/transport"
+)
+
+// mockModule makes m answer each command with echo back and responses
+func mockModule(m *transport.MockSerial, responses map[string][]string) {
+	queue := []string{}
+	m.EXPECT().Send(gomock.Any()).DoAndReturn(func(in []byte) error {
+		cmd := strings.TrimSuffix(string(in), "\r\n")
+		queue = append([]string{cmd}, responses[cmd]...)
+		return nil
+	}).AnyTimes()
+	m.EXPECT().Recv().DoAndReturn(func() ([]byte, error) {
+		if len(queue) == 0 {
+			return nil, fmt.Errorf("serial: timeout")
+		}
+		line := queue[0]
+		queue = queue[1:]
+		return []byte(line + "\r\n"), nil
+	}).AnyTimes()
+}
+
+func TestDetectModule(t *testing.T) {
+	t.Parallel()
+
+	version := []string{"EVER 1.2.10", "OK"}
+	einfo := []string{"EINFO FE80:0000:0000:0000:021D:1290:0003:C890 001D129012345678 21 8888 FFFE", "OK"}
+
+	testcases := []struct {
+		name      string
+		responses map[string][]string
+		want      ModuleInfo
+		err       bool
+	}{
+		{
+			name:      "binary",
+			responses: map[string][]string{"SKVER": version, "SKINFO": einfo, "ROPT": {"OK 00"}},
+			want:      ModuleInfo{Module: ModuleBP35C2, Version: "1.2.10", Addr64: "001D129012345678"},
+		},
+		{
+			name:      "ascii",
+			responses: map[string][]string{"SKVER": version, "SKINFO": einfo, "ROPT": {"OK 01"}},
+			want:      ModuleInfo{Module: ModuleRL7023, Version: "1.2.10", Addr64: "001D129012345678", ASCII: true},
+		},
+		{
+			name:      "no ROPT",
+			responses: map[string][]string{"SKVER": version, "SKINFO": einfo, "ROPT": {"FAIL ER04"}},
+			want:      ModuleInfo{Module: ModuleRL7023, Version: "1.2.10", Addr64: "001D129012345678", ASCII: true},
+		},
+		{
+			name:      "no response",
+			responses: map[string][]string{},
+			err:       true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := transport.NewMockSerial(ctrl)
+			mockModule(m, tc.responses)
+
+			got, err := DetectModule(m)
+			if tc.err {
+				if err == nil {
+					t.Errorf("error expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("want:%+v got:%+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := transport.NewMockSerial(ctrl)
+	mockModule(m, map[string][]string{"SKVER": {"EVER 1.0.0", "OK"}, "SKINFO": {"FAIL ER04"}, "ROPT": {"OK 00"}})
+
+	c, err := NewClient(ModuleAuto, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(*BP35C2Client); !ok {
+		t.Errorf("BP35C2Client expected: %T", c)
+	}
+
+	c, err = NewClient(ModuleRL7023, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.(*RL7023Client); !ok {
+		t.Errorf("RL7023Client expected: %T", c)
+	}
+
+	if _, err := NewClient("wsr35a", m); err == nil {
+		t.Errorf("error expected for unknown module")
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"log"
 	"strconv"
 
-	"github.com/u-one/go-el-controller/transport"
+	"github.com/matsuu/go-el-controller/transport"
 )
 
 // RL7023Client is client for TESSERA RL7023
@@ -27,6 +27,20 @@ func NewRL7023Client(portaddr string) *RL7023Client {
 	return &RL7023Client{serial: s}
 }
 
+// OpenRL7023Client opens serial port and returns RL7023Client instance
+func OpenRL7023Client(portaddr string) (*RL7023Client, error) {
+	s, err := transport.OpenSerial(portaddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial [%s]: %w", portaddr, err)
+	}
+	return &RL7023Client{serial: s}, nil
+}
+
+// NewRL7023ClientWithSerial returns RL7023Client communicating through s
+func NewRL7023ClientWithSerial(s transport.Serial) *RL7023Client {
+	return &RL7023Client{serial: s}
+}
+
 // Close closees connection
 func (c *RL7023Client) Close() {
 	if c.joined {
@@ -200,7 +214,17 @@ func (c RL7023Client) receivePanDesc() (PanDesc, error) {
 		}
 		tokens = bytes.Split(line, []byte("Addr:"))
 		ed.Addr = string(bytes.Trim(tokens[1], "\r\n"))
-		c.recv() // LQI:CA
+		line, err = c.recv() // LQI:CA
+		if err != nil {
+			return PanDesc{}, fmt.Errorf("failed to get LQI [%s]", err)
+		}
+		tokens = bytes.Split(line, []byte("LQI:"))
+		if len(tokens) > 1 {
+			lqi, err := strconv.ParseUint(string(bytes.Trim(tokens[1], "\r\n")), 16, 8)
+			if err == nil {
+				ed.LQI = int(lqi)
+			}
+		}
 		c.recv() // Side:X
 		c.recv() // PairID:XXXXXXXX
 	}
@@ -304,6 +328,7 @@ func (c *RL7023Client) Join(desc PanDesc) (bool, error) {
 				case 0x25:
 					log.Println("Join succeed")
 					c.joined = true
+					c.panDesc = desc
 					return true, nil
 				}
 			}
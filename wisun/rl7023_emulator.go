@@ -178,6 +178,15 @@ func (e *RL7023Emulator) Start() {
 
 			e.ok()
 			e.rxUDP([]byte{0x10, 0x81, 0x00, 0x01, 0x02, 0x88, 0x01, 0x05, 0xff, 0x01, 'r', 0x01, 0xe7, 0x04, 0x00, 0x00, 0x01, 0xf8})
+		} else if bytes.HasPrefix(line, []byte("SKINFO")) {
+			e.echoBack()
+			e.writer.WriteString("EINFO FE80:0000:0000:0000:021D:1290:1234:5678 001D129012345678 21 8888 FFFE\r\n")
+			e.flush()
+			e.ok()
+		} else if bytes.HasPrefix(line, []byte("ROPT")) {
+			e.echoBack()
+			e.writer.WriteString("FAIL ER04\r\n")
+			e.flush()
 		} else if bytes.HasPrefix(line, []byte("SKTERM")) {
 			e.echoBack()
 			e.ok()